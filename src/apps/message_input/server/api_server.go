@@ -7,9 +7,17 @@ import (
 	"duolingo/apps/message_input/server/handlers"
 	"duolingo/dependencies"
 	"duolingo/libraries/config_reader"
+	facade "duolingo/libraries/connection_manager/facade"
 	container "duolingo/libraries/dependencies_container"
+	ps "duolingo/libraries/message_queue/pub_sub"
+	"duolingo/libraries/restful/authenticators"
+	signature_registry "duolingo/libraries/restful/authenticators/drivers/redis"
 	restful "duolingo/libraries/restful/server"
+	ts "duolingo/libraries/task_scheduler"
 	"duolingo/libraries/telemetry/otel_wrapper/log"
+	"duolingo/models"
+	outbox_repo "duolingo/repositories/outbox_repository/external"
+	status_svc "duolingo/services/message_status_service"
 )
 
 type MessageInputApiServer struct {
@@ -25,10 +33,22 @@ func NewMessageInputApiServer(ctx context.Context) *MessageInputApiServer {
 		"connections",
 		"message_queues",
 		"pub_sub",
+		"task_scheduler",
+		"user_repo",
+		"user_service",
+		"work_distributor",
+		"message_status_repo",
+		"message_status_service",
+		"outbox_repo",
+		"idempotency",
 	})
 
 	config := container.MustResolve[config_reader.ConfigReader]()
 	server := restful.NewServer(config.Get("message_input", "server_address"))
+	server.Authenticate(
+		newAPIKeyAuthenticator(config),
+		newHMACAuthenticator(config),
+	)
 	return &MessageInputApiServer{
 		ctx:    ctx,
 		server: server,
@@ -37,21 +57,107 @@ func NewMessageInputApiServer(ctx context.Context) *MessageInputApiServer {
 	}
 }
 
+func newAPIKeyAuthenticator(config config_reader.ConfigReader) *authenticators.APIKeyAuthenticator {
+	authenticator := authenticators.NewAPIKeyAuthenticator()
+	principals := config.GetArr("message_input", "authentication.api_keys.#.principal")
+	keys := config.GetArr("message_input", "authentication.api_keys.#.key")
+	for i := range min(len(principals), len(keys)) {
+		if keys[i] == "" {
+			continue
+		}
+		authenticator.AddKey(principals[i], keys[i])
+	}
+	return authenticator
+}
+
+func newHMACAuthenticator(config config_reader.ConfigReader) *authenticators.HMACAuthenticator {
+	window := time.Duration(config.GetInt("message_input", "authentication.hmac_replay_window_seconds")) * time.Second
+	if window <= 0 {
+		window = 5 * time.Minute
+	}
+	connections := container.MustResolve[*facade.ConnectionProvider]()
+	authenticator := authenticators.NewHMACAuthenticator().
+		SetReplayWindow(window).
+		SetSignatureRegistry(signature_registry.NewRedisSignatureRegistry(
+			connections.GetRedisClient(), "message_input",
+		))
+	principals := config.GetArr("message_input", "authentication.hmac_secrets.#.principal")
+	secrets := config.GetArr("message_input", "authentication.hmac_secrets.#.secret")
+	for i := range min(len(principals), len(secrets)) {
+		if secrets[i] == "" {
+			continue
+		}
+		authenticator.AddSecret(principals[i], secrets[i])
+	}
+	return authenticator
+}
+
 func (api *MessageInputApiServer) Addr() string {
 	return api.server.Addr()
 }
 
 func (api *MessageInputApiServer) Serve() {
 	api.server.Post("/api/v1/campaigns/{campaign}/message-input",
-		handlers.NewMessageInputRequestHandler().Handle,
+		handlers.NewIdempotentRequestHandler(
+			handlers.NewMessageInputRequestHandler().Handle,
+		).Handle,
 	)
 
+	api.server.Post("/api/v1/campaigns/{campaign}/message-preview",
+		handlers.NewMessagePreviewRequestHandler().Handle,
+	)
+
+	api.server.Get("/api/v1/campaigns/{campaign}/messages/{id}",
+		handlers.NewMessageStatusRequestHandler().Handle,
+	)
+	api.server.Post("/api/v1/campaigns/{campaign}/messages/{id}/cancel",
+		handlers.NewMessageCancelRequestHandler().Handle,
+	)
+
+	scheduledMessages := handlers.NewScheduledMessageRequestHandler()
+	api.server.Get("/api/v1/campaigns/{campaign}/scheduled-messages",
+		scheduledMessages.List,
+	)
+	api.server.Put("/api/v1/campaigns/{campaign}/scheduled-messages/{id}",
+		scheduledMessages.Reschedule,
+	)
+	api.server.Delete("/api/v1/campaigns/{campaign}/scheduled-messages/{id}",
+		scheduledMessages.Cancel,
+	)
+
+	go api.releaseScheduledMessages()
+	go NewOutboxRelay().
+		AddPublisher(
+			handlers.MessageInputsTopic,
+			container.MustResolveAlias[ps.Publisher]("message_input_publisher"),
+		).
+		Start(api.ctx)
+
 	api.logger.Write(api.logger.
 		Info("serving api").Namespace("message_input.api_server"))
 
 	api.server.Serve(api.ctx)
 }
 
+// releaseScheduledMessages moves the scheduled messages to the outbox when they
+// are due, a message is kept by the scheduler and retried until it is moved.
+func (api *MessageInputApiServer) releaseScheduledMessages() {
+	outboxRepo := container.MustResolve[outbox_repo.OutboxRepository]()
+	scheduler := container.MustResolveAlias[*ts.TaskScheduler]("message_input_scheduler")
+	statusService := container.MustResolve[*status_svc.MessageStatusService]()
+	scheduler.Dispatching(api.ctx, func(ctx context.Context, task *ts.ScheduledTask) error {
+		input := models.MessageInputDecode([]byte(task.Payload))
+		if statusService.IsCanceled(ctx, input.Id) {
+			return nil
+		}
+		if err := statusService.MarkQueued(ctx, input); err != nil {
+			return err
+		}
+		outboxMessage := models.NewOutboxMessage(handlers.MessageInputsTopic, task.Payload)
+		return outboxRepo.AddOutboxMessage(ctx, outboxMessage)
+	})
+}
+
 func (api *MessageInputApiServer) Shutdown() {
 	timeout := time.Duration(api.config.GetInt("message_input", "server_shutdown_wait"))
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	container "duolingo/libraries/dependencies_container"
+	"duolingo/libraries/idempotency"
+	rest "duolingo/libraries/restful"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentRequestHandler replays the first response for the retries of a
+// request carrying the same "Idempotency-Key" header, instead of handling it again.
+type IdempotentRequestHandler struct {
+	store   idempotency.IdempotencyStore
+	handler func(*rest.Request, *rest.Response)
+}
+
+func NewIdempotentRequestHandler(handler func(*rest.Request, *rest.Response)) *IdempotentRequestHandler {
+	return &IdempotentRequestHandler{
+		store:   container.MustResolveAlias[idempotency.IdempotencyStore]("message_input_idempotency_store"),
+		handler: handler,
+	}
+}
+
+func (handler *IdempotentRequestHandler) Handle(req *rest.Request, res *rest.Response) {
+	key := req.Header().Get(IdempotencyKeyHeader)
+	if key == "" {
+		handler.handler(req, res)
+		return
+	}
+
+	record, err := handler.store.Begin(req.Context(), key, handler.fingerprint(req))
+	if err == idempotency.ErrFingerprintMismatch || err == idempotency.ErrRequestInProgress {
+		res.Conflict(err.Error())
+		return
+	}
+	if err != nil {
+		res.ServerErr("failed to check idempotency key")
+		return
+	}
+	if record != nil {
+		res.Replay(record.Status, record.Body)
+		return
+	}
+
+	handler.handler(req, res)
+
+	// the server errors are not saved, so that the request can be retried
+	if !res.Sent() || res.Status() >= http.StatusInternalServerError {
+		handler.store.Release(req.Context(), key)
+		return
+	}
+	record = idempotency.NewIdempotencyRecord(key, handler.fingerprint(req))
+	record.Complete(res.Status(), res.Body())
+	handler.store.Complete(req.Context(), record)
+}
+
+// The same key must be used for the same endpoint and body only
+func (handler *IdempotentRequestHandler) fingerprint(req *rest.Request) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method() + " " + req.URL().Path + "\n"))
+	hash.Write(req.RawBody())
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	container "duolingo/libraries/dependencies_container"
+	ps "duolingo/libraries/message_queue/pub_sub"
+	rest "duolingo/libraries/restful"
+	ts "duolingo/libraries/task_scheduler"
+	"duolingo/models"
+	status_repo "duolingo/repositories/message_status_repository/external"
+	status_svc "duolingo/services/message_status_service"
+)
+
+type MessageCancelRequestHandler struct {
+	cancelPublisher ps.Publisher
+	inputScheduler  *ts.TaskScheduler
+	statusService   *status_svc.MessageStatusService
+}
+
+func NewMessageCancelRequestHandler() *MessageCancelRequestHandler {
+	return &MessageCancelRequestHandler{
+		cancelPublisher: container.MustResolveAlias[ps.Publisher]("message_cancellation_publisher"),
+		inputScheduler:  container.MustResolveAlias[*ts.TaskScheduler]("message_input_scheduler"),
+		statusService:   container.MustResolve[*status_svc.MessageStatusService](),
+	}
+}
+
+func (handler *MessageCancelRequestHandler) Handle(req *rest.Request, res *rest.Response) {
+	messageId := req.PathArg("id").String()
+	status, err := handler.statusService.GetMessageStatus(req.Context(), messageId)
+	if err == status_repo.ErrMessageStatusNotExists ||
+		(err == nil && status.Campaign != req.PathArg("campaign").String()) {
+		res.NotFound("message not found")
+		return
+	}
+	if err != nil {
+		res.ServerErr("failed to cancel message")
+		return
+	}
+
+	if status.State == models.MessageScheduled {
+		err = handler.inputScheduler.Cancel(req.Context(), messageId)
+		if err == ts.ErrScheduledTaskClaimed {
+			res.Conflict("message is being dispatched")
+			return
+		}
+		if err != nil && err != ts.ErrScheduledTaskNotExists {
+			res.ServerErr("failed to cancel message")
+			return
+		}
+	}
+
+	status, err = handler.statusService.Cancel(req.Context(), messageId)
+	if err == status_svc.ErrMessageNotCancelable {
+		res.BadRequest("message can not be canceled", map[string]string{
+			"state": "message has already finished",
+		})
+		return
+	}
+	if err == nil {
+		// let the senders drop the notifications already queued for the message
+		err = handler.cancelPublisher.NotifyMainTopic(req.Context(), messageId)
+	}
+	if err != nil {
+		res.ServerErr("failed to cancel message")
+	} else {
+		res.Ok("message canceled", status)
+	}
+}
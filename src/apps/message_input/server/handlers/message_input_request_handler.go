@@ -1,44 +1,121 @@
 package handlers
 
 import (
+	"slices"
+	"time"
+
 	container "duolingo/libraries/dependencies_container"
-	ps "duolingo/libraries/message_queue/pub_sub"
+	push_message "duolingo/libraries/push_notification/message"
 	rest "duolingo/libraries/restful"
+	ts "duolingo/libraries/task_scheduler"
 	"duolingo/models"
+	outbox_repo "duolingo/repositories/outbox_repository/external"
+	status_svc "duolingo/services/message_status_service"
+
+	"github.com/tidwall/gjson"
 )
 
+// The messages are published to this topic by the outbox relay
+const MessageInputsTopic = "message_inputs"
+
+var supportedLanguages = []models.NativeLanguage{
+	models.LanguageEN,
+	models.LanguageVN,
+	models.LanguageJP,
+}
+
+var supportedMemberships = []models.Membership{
+	models.MembershipPremium,
+	models.MembershipFreeTier,
+	models.MembershipSubscription,
+}
+
+var supportedPlatforms = []push_message.Platform{
+	push_message.Android,
+	push_message.IOS,
+	push_message.Web,
+}
+
 type MessageInputRequestHandler struct {
-	inputPublisher ps.Publisher
+	outboxRepo     outbox_repo.OutboxRepository
+	inputScheduler *ts.TaskScheduler
+	statusService  *status_svc.MessageStatusService
 }
 
 func NewMessageInputRequestHandler() *MessageInputRequestHandler {
-	publisher := container.MustResolveAlias[ps.Publisher]("message_input_publisher")
+	scheduler := container.MustResolveAlias[*ts.TaskScheduler]("message_input_scheduler")
 	return &MessageInputRequestHandler{
-		inputPublisher: publisher,
+		outboxRepo:     container.MustResolve[outbox_repo.OutboxRepository](),
+		inputScheduler: scheduler,
+		statusService:  container.MustResolve[*status_svc.MessageStatusService](),
 	}
 }
 
 func (handler *MessageInputRequestHandler) Handle(req *rest.Request, res *rest.Response) {
-	if valid, validations := handler.validate(req); !valid {
+	if valid, validations := validateMessageInput(req); !valid {
 		res.BadRequest("invalid arguments", validations)
 		return
 	}
-	message := models.NewMessageInput(
-		req.PathArg("campaign").String(),
-		req.Input("title").String(),
-		req.Input("body").String(),
-	)
+	message := parseMessageInput(req)
+
+	// Messages with a future "send_at" are held by the scheduler,
+	// and published to the "message_inputs" topic when due.
+	sendAt, _ := parseSendAt(req)
+	if sendAt.After(time.Now()) {
+		handler.schedule(req, res, models.NewScheduledMessage(message, sendAt))
+		return
+	}
 
+	// The status is saved before the message is added to the outbox, as
+	// the builders update it as soon as they receive the message. The
+	// message is accepted once it is in the outbox, the outbox relay
+	// publishes it even if the message queue is unavailable for now.
 	reqCtx := req.Context()
-	err := handler.inputPublisher.NotifyMainTopic(reqCtx, string(message.Encode()))
+	err := handler.statusService.MarkQueued(reqCtx, message)
+	if err == nil {
+		outboxMessage := models.NewOutboxMessage(MessageInputsTopic, string(message.Encode()))
+		err = handler.outboxRepo.AddOutboxMessage(reqCtx, outboxMessage)
+		if err != nil {
+			handler.statusService.DeleteMessageStatus(reqCtx, message.Id)
+		}
+	}
 	if err != nil {
 		res.ServerErr("failed to input campaign message")
 	} else {
-		res.Ok("", message)
+		res.Accepted("message accepted", message)
+	}
+}
+
+func (handler *MessageInputRequestHandler) schedule(
+	req *rest.Request,
+	res *rest.Response,
+	scheduled *models.ScheduledMessage,
+) {
+	task, err := ts.NewScheduledTask(
+		scheduled.Id,
+		scheduled.Campaign,
+		string(scheduled.MessageInput.Encode()),
+		scheduled.SendAt,
+	)
+	// The task is scheduled before the status is saved, so that a failure
+	// does not leave a scheduled status without a task to release it.
+	if err == nil {
+		err = handler.inputScheduler.Schedule(req.Context(), task)
+	}
+	if err == nil {
+		err = handler.statusService.MarkScheduled(req.Context(), scheduled.MessageInput)
+		if err != nil {
+			handler.inputScheduler.Cancel(req.Context(), task.Id)
+		}
+	}
+	if err != nil {
+		res.ServerErr("failed to schedule campaign message")
+	} else {
+		res.Accepted("message scheduled", scheduled)
 	}
 }
 
-func (handler *MessageInputRequestHandler) validate(req *rest.Request) (bool, map[string]string) {
+func validateMessageInput(req *rest.Request) (bool, map[string]string) {
 	validations := make(map[string]string)
 	if req.PathArg("campaign").String() == "" {
 		validations["campaign"] = "campaign must not empty"
@@ -49,5 +126,85 @@ func (handler *MessageInputRequestHandler) validate(req *rest.Request) (bool, ma
 	if req.Input("body").String() == "" {
 		validations["body"] = "message body must not empty"
 	}
+	req.Input("localizations").ForEach(func(lang, variant gjson.Result) bool {
+		if !slices.Contains(supportedLanguages, models.NativeLanguage(lang.String())) {
+			validations["localizations"] = "localization language is not supported"
+		} else if variant.Get("title").String() == "" || variant.Get("body").String() == "" {
+			validations["localizations"] = "localization title and body must not empty"
+		}
+		return true
+	})
+	if _, err := parseSendAt(req); err != nil {
+		validations["send_at"] = "send_at must be a RFC3339 datetime"
+	}
+	if localSendAt := req.Input("local_send_at").String(); localSendAt != "" {
+		if _, err := time.Parse(models.LocalClockLayout, localSendAt); err != nil {
+			validations["local_send_at"] = "local_send_at must be a HH:MM local time"
+		}
+	}
+	audience := parseAudience(req)
+	for _, membership := range audience.Memberships {
+		if !slices.Contains(supportedMemberships, membership) {
+			validations["audience.memberships"] = "audience membership is not supported"
+		}
+	}
+	for _, lang := range audience.NativeLanguages {
+		if !slices.Contains(supportedLanguages, lang) {
+			validations["audience.native_languages"] = "audience language is not supported"
+		}
+	}
+	for _, platform := range audience.Platforms {
+		if !slices.Contains(supportedPlatforms, push_message.Platform(platform)) {
+			validations["audience.platforms"] = "audience platform is not supported"
+		}
+	}
 	return len(validations) == 0, validations
 }
+
+// parseMessageInput builds the message from a request passed validateMessageInput()
+func parseMessageInput(req *rest.Request) *models.MessageInput {
+	message := models.NewMessageInput(
+		req.PathArg("campaign").String(),
+		req.Input("title").String(),
+		req.Input("body").String(),
+	)
+	req.Input("localizations").ForEach(func(lang, variant gjson.Result) bool {
+		message.SetLocalization(
+			models.NativeLanguage(lang.String()),
+			variant.Get("title").String(),
+			variant.Get("body").String(),
+		)
+		return true
+	})
+	if audience := parseAudience(req); !audience.IsEmpty() {
+		message.Audience = audience
+	}
+	message.LocalSendAt = req.Input("local_send_at").String()
+	if principal := req.Principal(); principal != nil {
+		message.SubmittedBy = principal.Id
+	}
+	return message
+}
+
+// parseSendAt returns the zero time if "send_at" is not provided
+func parseSendAt(req *rest.Request) (time.Time, error) {
+	sendAt := req.Input("send_at").String()
+	if sendAt == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, sendAt)
+}
+
+func parseAudience(req *rest.Request) *models.AudienceFilter {
+	audience := new(models.AudienceFilter)
+	for _, membership := range req.Input("audience.memberships").Array() {
+		audience.Memberships = append(audience.Memberships, models.Membership(membership.String()))
+	}
+	for _, lang := range req.Input("audience.native_languages").Array() {
+		audience.NativeLanguages = append(audience.NativeLanguages, models.NativeLanguage(lang.String()))
+	}
+	for _, platform := range req.Input("audience.platforms").Array() {
+		audience.Platforms = append(audience.Platforms, platform.String())
+	}
+	return audience
+}
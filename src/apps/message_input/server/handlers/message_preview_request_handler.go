@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+
+	"duolingo/libraries/config_reader"
+	container "duolingo/libraries/dependencies_container"
+	rest "duolingo/libraries/restful"
+	"duolingo/models"
+	usr_svc "duolingo/services/user_service"
+)
+
+// MessagePreviewRequestHandler dry-runs a message input, it reports the
+// devices the message would reach and how the delivery would be batched.
+// Nothing is published, scheduled or saved.
+type MessagePreviewRequestHandler struct {
+	userService      *usr_svc.UserService
+	platforms        []string
+	sampleSize       int64
+	distributionSize int64
+}
+
+func NewMessagePreviewRequestHandler() *MessagePreviewRequestHandler {
+	config := container.MustResolve[config_reader.ConfigReader]()
+	return &MessagePreviewRequestHandler{
+		userService:      container.MustResolve[*usr_svc.UserService](),
+		platforms:        config.GetArr("message_input", "preview.platforms"),
+		sampleSize:       config.GetInt64("message_input", "preview.sample_size"),
+		distributionSize: config.GetInt64("work_distributor", "distribution_size"),
+	}
+}
+
+func (handler *MessagePreviewRequestHandler) Handle(req *rest.Request, res *rest.Response) {
+	if valid, validations := validateMessageInput(req); !valid {
+		res.BadRequest("invalid arguments", validations)
+		return
+	}
+	message := parseMessageInput(req)
+	preview := models.NewMessagePreview(message.Campaign)
+	preview.DistributionSize = handler.distributionSize
+
+	err := handler.countDevices(req.Context(), message, preview)
+	if err == nil {
+		err = handler.renderSamples(req.Context(), message, preview)
+	}
+	if err != nil {
+		res.ServerErr("failed to preview campaign message")
+	} else {
+		res.Ok("", preview)
+	}
+}
+
+func (handler *MessagePreviewRequestHandler) countDevices(
+	ctx context.Context,
+	message *models.MessageInput,
+	preview *models.MessagePreview,
+) error {
+	total, duplicates, err := handler.userService.CountDistinctDevicesForCampaign(
+		ctx,
+		message.Campaign,
+		message.Audience,
+	)
+	if err != nil {
+		return err
+	}
+	preview.TotalDevices = total
+	preview.DuplicateTokens = duplicates
+	// the batches are split by the user boundaries as the noti builders do,
+	// an empty audience has no batch
+	if total > 0 {
+		boundaries, err := handler.userService.GetDeviceBoundariesForCampaign(
+			ctx,
+			message.Campaign,
+			message.Audience,
+			handler.distributionSize,
+		)
+		if err != nil {
+			return err
+		}
+		preview.ExpectedAssignments = int64(len(boundaries) + 1)
+	}
+
+	for _, platform := range handler.platforms {
+		audience := new(models.AudienceFilter)
+		if message.Audience != nil {
+			if len(message.Audience.Platforms) > 0 && !slices.Contains(message.Audience.Platforms, platform) {
+				continue
+			}
+			*audience = *message.Audience
+		}
+		audience.Platforms = []string{platform}
+		count, _, err := handler.userService.CountDistinctDevicesForCampaign(ctx, message.Campaign, audience)
+		if err != nil {
+			return err
+		}
+		preview.DevicesByPlatform[platform] = count
+	}
+
+	return nil
+}
+
+func (handler *MessagePreviewRequestHandler) renderSamples(
+	ctx context.Context,
+	message *models.MessageInput,
+	preview *models.MessagePreview,
+) error {
+	devices, err := handler.userService.GetDevicesForCampaign(
+		ctx,
+		message.Campaign,
+		message.Audience,
+		0,
+		handler.sampleSize,
+	)
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		preview.AddSample(device.Platform, message.Render(device.Recipient))
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	container "duolingo/libraries/dependencies_container"
+	rest "duolingo/libraries/restful"
+	status_repo "duolingo/repositories/message_status_repository/external"
+	status_svc "duolingo/services/message_status_service"
+)
+
+type MessageStatusRequestHandler struct {
+	statusService *status_svc.MessageStatusService
+}
+
+func NewMessageStatusRequestHandler() *MessageStatusRequestHandler {
+	return &MessageStatusRequestHandler{
+		statusService: container.MustResolve[*status_svc.MessageStatusService](),
+	}
+}
+
+func (handler *MessageStatusRequestHandler) Handle(req *rest.Request, res *rest.Response) {
+	status, err := handler.statusService.GetDeliveryStatus(req.Context(), req.PathArg("id").String())
+	if err == status_repo.ErrMessageStatusNotExists ||
+		(err == nil && status.Campaign != req.PathArg("campaign").String()) {
+		res.NotFound("message not found")
+	} else if err != nil {
+		res.ServerErr("failed to get message status")
+	} else {
+		res.Ok("", status)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"time"
+
+	container "duolingo/libraries/dependencies_container"
+	rest "duolingo/libraries/restful"
+	ts "duolingo/libraries/task_scheduler"
+	"duolingo/models"
+	status_repo "duolingo/repositories/message_status_repository/external"
+	status_svc "duolingo/services/message_status_service"
+)
+
+type ScheduledMessageRequestHandler struct {
+	inputScheduler *ts.TaskScheduler
+	statusService  *status_svc.MessageStatusService
+}
+
+func NewScheduledMessageRequestHandler() *ScheduledMessageRequestHandler {
+	scheduler := container.MustResolveAlias[*ts.TaskScheduler]("message_input_scheduler")
+	return &ScheduledMessageRequestHandler{
+		inputScheduler: scheduler,
+		statusService:  container.MustResolve[*status_svc.MessageStatusService](),
+	}
+}
+
+func (handler *ScheduledMessageRequestHandler) List(req *rest.Request, res *rest.Response) {
+	tasks, err := handler.inputScheduler.ListTasks(req.Context(), req.PathArg("campaign").String())
+	if err != nil {
+		res.ServerErr("failed to list scheduled messages")
+		return
+	}
+	messages := []*models.ScheduledMessage{}
+	for _, task := range tasks {
+		messages = append(messages, scheduledMessageFromTask(task))
+	}
+	res.Ok("", messages)
+}
+
+func (handler *ScheduledMessageRequestHandler) Reschedule(req *rest.Request, res *rest.Response) {
+	sendAt, parseErr := parseSendAt(req)
+	if parseErr != nil || !sendAt.After(time.Now()) {
+		res.BadRequest("invalid arguments", map[string]string{
+			"send_at": "send_at must be a future RFC3339 datetime",
+		})
+		return
+	}
+	if !handler.findTask(req, res) {
+		return
+	}
+	task, err := handler.inputScheduler.Reschedule(req.Context(), req.PathArg("id").String(), sendAt)
+	if err == ts.ErrScheduledTaskNotExists {
+		res.NotFound("scheduled message not found")
+	} else if err == ts.ErrScheduledTaskClaimed {
+		res.Conflict("scheduled message is being dispatched")
+	} else if err != nil {
+		res.ServerErr("failed to reschedule message")
+	} else {
+		res.Ok("message rescheduled", scheduledMessageFromTask(task))
+	}
+}
+
+func (handler *ScheduledMessageRequestHandler) Cancel(req *rest.Request, res *rest.Response) {
+	if !handler.findTask(req, res) {
+		return
+	}
+	err := handler.inputScheduler.Cancel(req.Context(), req.PathArg("id").String())
+	if err == ts.ErrScheduledTaskNotExists {
+		res.NotFound("scheduled message not found")
+	} else if err == ts.ErrScheduledTaskClaimed {
+		res.Conflict("scheduled message is being dispatched")
+	} else if err != nil {
+		res.ServerErr("failed to cancel scheduled message")
+	} else {
+		handler.cancelStatus(req, res)
+	}
+}
+
+// cancelStatus marks the status of the canceled scheduled message, the message
+// having no status or a finished one has nothing left to cancel.
+func (handler *ScheduledMessageRequestHandler) cancelStatus(req *rest.Request, res *rest.Response) {
+	_, err := handler.statusService.Cancel(req.Context(), req.PathArg("id").String())
+	if err != nil &&
+		err != status_svc.ErrMessageNotCancelable &&
+		err != status_repo.ErrMessageStatusNotExists {
+		res.ServerErr("failed to cancel scheduled message")
+		return
+	}
+	res.NoContent()
+}
+
+// findTask responds with not found if the task does not exist, or belongs to another campaign
+func (handler *ScheduledMessageRequestHandler) findTask(req *rest.Request, res *rest.Response) bool {
+	task, err := handler.inputScheduler.GetTask(req.Context(), req.PathArg("id").String())
+	if err == ts.ErrScheduledTaskNotExists || (err == nil && task.Group != req.PathArg("campaign").String()) {
+		res.NotFound("scheduled message not found")
+		return false
+	}
+	if err != nil {
+		res.ServerErr("failed to get scheduled message")
+		return false
+	}
+	return true
+}
+
+func scheduledMessageFromTask(task *ts.ScheduledTask) *models.ScheduledMessage {
+	return models.NewScheduledMessage(
+		models.MessageInputDecode([]byte(task.Payload)),
+		task.DueAt,
+	)
+}
@@ -13,6 +13,7 @@ import (
 	"duolingo/apps/message_input/server"
 	container "duolingo/libraries/dependencies_container"
 	ps "duolingo/libraries/message_queue/pub_sub"
+	"duolingo/libraries/restful/authenticators"
 	"duolingo/models"
 
 	"github.com/stretchr/testify/suite"
@@ -105,11 +106,10 @@ func (s *MessageInputRequestTestSuite) makeRequest(
 		s.messageInputServer.Addr(),
 		campaign,
 	)
-	response, requestErr := http.Post(
-		endpoint,
-		"application/json",
-		bytes.NewBuffer(data),
-	)
+	request, _ := http.NewRequest("POST", endpoint, bytes.NewBuffer(data))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(authenticators.APIKeyHeader, "test-api-key")
+	response, requestErr := http.DefaultClient.Do(request)
 	if requestErr != nil {
 		return nil, requestErr
 	}
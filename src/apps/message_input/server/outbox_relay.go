@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"duolingo/libraries/backoff"
+	"duolingo/libraries/config_reader"
+	container "duolingo/libraries/dependencies_container"
+	ps "duolingo/libraries/message_queue/pub_sub"
+	"duolingo/libraries/telemetry/otel_wrapper/log"
+	"duolingo/models"
+	outbox_repo "duolingo/repositories/outbox_repository/external"
+)
+
+var (
+	ErrOutboxTopicNotRelayed = errors.New("no publisher is added for the outbox message topic")
+)
+
+// OutboxRelay publishes the accepted messages from the outbox to their topics,
+// so that the messages are not lost while the message queue is unavailable.
+// The failed messages are retried with an exponential backoff, except the ones
+// of a topic without publisher, which fail permanently.
+type OutboxRelay struct {
+	repo       outbox_repo.OutboxRepository
+	publishers map[string]ps.Publisher
+
+	pollInterval time.Duration
+	claimTimeout time.Duration
+	claimLimit   int
+	retryBackoff *backoff.ExponentialBackoff
+
+	logger *log.Logger
+}
+
+func NewOutboxRelay() *OutboxRelay {
+	config := container.MustResolve[config_reader.ConfigReader]()
+	return &OutboxRelay{
+		repo:         container.MustResolve[outbox_repo.OutboxRepository](),
+		publishers:   make(map[string]ps.Publisher),
+		pollInterval: time.Duration(config.GetInt("outbox", "poll_interval_ms")) * time.Millisecond,
+		claimTimeout: time.Duration(config.GetInt("outbox", "claim_timeout_ms")) * time.Millisecond,
+		claimLimit:   config.GetInt("outbox", "claim_limit"),
+		retryBackoff: backoff.NewExponentialBackoff(
+			time.Duration(config.GetInt("outbox", "retry_base_ms"))*time.Millisecond,
+			time.Duration(config.GetInt("outbox", "retry_max_ms"))*time.Millisecond,
+		),
+		logger: container.MustResolve[*log.Logger](),
+	}
+}
+
+func (relay *OutboxRelay) AddPublisher(topic string, publisher ps.Publisher) *OutboxRelay {
+	relay.publishers[topic] = publisher
+	return relay
+}
+
+func (relay *OutboxRelay) Start(ctx context.Context) {
+	ticker := time.NewTicker(relay.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			relay.relayPendingMessages(ctx)
+		}
+	}
+}
+
+func (relay *OutboxRelay) relayPendingMessages(ctx context.Context) {
+	messages, err := relay.repo.ClaimPendingMessages(ctx, relay.claimLimit, relay.claimTimeout)
+	if err != nil {
+		relay.logger.Write(relay.logger.Error("failed to claim outbox messages", err).
+			Namespace("message_input.outbox_relay"))
+		return
+	}
+	for _, message := range messages {
+		relay.publish(ctx, message)
+	}
+}
+
+func (relay *OutboxRelay) publish(ctx context.Context, message *models.OutboxMessage) {
+	var err error
+	if publisher, exists := relay.publishers[message.Topic]; !exists {
+		err = ErrOutboxTopicNotRelayed
+	} else {
+		err = publisher.NotifyMainTopic(ctx, message.Payload)
+	}
+	if err == ErrOutboxTopicNotRelayed {
+		// retrying would not add the missing publisher
+		relay.logger.Write(relay.logger.Error("outbox message can not be relayed", err).
+			Namespace("message_input.outbox_relay").
+			Data(map[string]any{"outbox_message_id": message.Id, "topic": message.Topic}))
+		relay.repo.MarkMessageFailedPermanently(ctx, message.Id, err.Error())
+		return
+	}
+	if err != nil {
+		retryAt := time.Now().Add(relay.retryBackoff.Delay(message.Attempts + 1))
+		relay.repo.MarkMessageFailed(ctx, message.Id, err.Error(), retryAt)
+		return
+	}
+	relay.repo.MarkMessageSent(ctx, message.Id)
+}
@@ -20,6 +20,9 @@ func main() {
 		"user_repo",
 		"user_service",
 		"work_distributor",
+		"message_status_repo",
+		"message_status_service",
+		"task_scheduler",
 	})
 
 	builder := server.NewNotiBuilder()
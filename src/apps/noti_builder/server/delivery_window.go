@@ -0,0 +1,52 @@
+package server
+
+import (
+	"time"
+
+	"duolingo/models"
+)
+
+/*
+DeliveryWindow times the delivery to each recipient in their own timezone:
+ 1. The message with a local send time is delivered when the recipient clock
+    reads that time, rolling out across the timezones.
+ 2. The delivery falling in the recipient quiet hours is deferred until the
+    quiet hours end. The default quiet hours are overridable per campaign.
+*/
+type DeliveryWindow struct {
+	quietHours         *models.QuietHours
+	campaignQuietHours map[string]*models.QuietHours
+}
+
+func NewDeliveryWindow(quietHours *models.QuietHours) *DeliveryWindow {
+	return &DeliveryWindow{
+		quietHours:         quietHours,
+		campaignQuietHours: make(map[string]*models.QuietHours),
+	}
+}
+
+func (window *DeliveryWindow) SetCampaignQuietHours(campaign string, quietHours *models.QuietHours) *DeliveryWindow {
+	window.campaignQuietHours[campaign] = quietHours
+	return window
+}
+
+// DeliverAt returns when the message is delivered to the recipient,
+// the message is delivered right away if it is not after "now".
+func (window *DeliveryWindow) DeliverAt(
+	input *models.MessageInput,
+	recipient *models.Recipient,
+	now time.Time,
+) time.Time {
+	local := now.In(recipient.Location())
+	if input.LocalSendAt != "" {
+		local = models.NextLocalClock(local, input.LocalSendAt)
+	}
+	quietHours := window.quietHours
+	if campaignQuietHours, exists := window.campaignQuietHours[input.Campaign]; exists {
+		quietHours = campaignQuietHours
+	}
+	if until, deferred := quietHours.DeferUntil(local); deferred {
+		local = until
+	}
+	return local.In(now.Location())
+}
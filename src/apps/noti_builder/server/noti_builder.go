@@ -3,30 +3,62 @@ package server
 import (
 	"context"
 	"sync"
+	"time"
 
 	wrkl "duolingo/apps/noti_builder/server/workloads"
+	"duolingo/libraries/config_reader"
 	container "duolingo/libraries/dependencies_container"
 	events "duolingo/libraries/events/facade"
 	ps "duolingo/libraries/message_queue/pub_sub"
 	tq "duolingo/libraries/message_queue/task_queue"
+	ts "duolingo/libraries/task_scheduler"
 	"duolingo/libraries/telemetry/otel_wrapper/log"
 	"duolingo/models"
+	usr_svc "duolingo/services/user_service"
+
+	"github.com/google/uuid"
 )
 
 type NotiBuilder struct {
-	msgInpSubscriber ps.Subscriber
-	pushNotiProducer tq.TaskProducer
-	tokenDistributor *wrkl.TokenBatchDistributor
-	logger           *log.Logger
+	msgInpSubscriber      ps.Subscriber
+	pushNotiProducer      tq.TaskProducer
+	invalidTokensConsumer tq.TaskConsumer
+	tokenDistributor      *wrkl.TokenBatchDistributor
+	userService           *usr_svc.UserService
+	logger                *log.Logger
+
+	// The push notifications of the recipients who are not to be disturbed yet
+	// are scheduled, and queued once their delivery time has come.
+	deliveryWindow    *DeliveryWindow
+	deferredScheduler *ts.TaskScheduler
 }
 
 func NewNotiBuilder() *NotiBuilder {
+	config := container.MustResolve[config_reader.ConfigReader]()
 	return &NotiBuilder{
-		msgInpSubscriber: container.MustResolveAlias[ps.Subscriber]("message_input_subscriber"),
-		pushNotiProducer: container.MustResolveAlias[tq.TaskProducer]("push_notifications_producer"),
-		tokenDistributor: wrkl.NewTokenBatchDistributor(),
-		logger:           container.MustResolve[*log.Logger](),
+		msgInpSubscriber:      container.MustResolveAlias[ps.Subscriber]("message_input_subscriber"),
+		pushNotiProducer:      container.MustResolveAlias[tq.TaskProducer]("push_notifications_producer"),
+		invalidTokensConsumer: container.MustResolveAlias[tq.TaskConsumer]("invalid_device_tokens_consumer"),
+		tokenDistributor:      wrkl.NewTokenBatchDistributor(),
+		userService:           container.MustResolve[*usr_svc.UserService](),
+		logger:                container.MustResolve[*log.Logger](),
+		deliveryWindow:        newDeliveryWindow(config),
+		deferredScheduler:     container.MustResolveAlias[*ts.TaskScheduler]("deferred_push_notifications_scheduler"),
+	}
+}
+
+func newDeliveryWindow(config config_reader.ConfigReader) *DeliveryWindow {
+	window := NewDeliveryWindow(models.NewQuietHours(
+		config.Get("quiet_hours", "default.start"),
+		config.Get("quiet_hours", "default.end"),
+	))
+	campaigns := config.GetArr("quiet_hours", "campaigns.#.campaign")
+	starts := config.GetArr("quiet_hours", "campaigns.#.start")
+	ends := config.GetArr("quiet_hours", "campaigns.#.end")
+	for i := range campaigns {
+		window.SetCampaignQuietHours(campaigns[i], models.NewQuietHours(starts[i], ends[i]))
 	}
+	return window
 }
 
 func (b *NotiBuilder) Start(buildCtx context.Context) {
@@ -34,7 +66,7 @@ func (b *NotiBuilder) Start(buildCtx context.Context) {
 	defer cancel()
 
 	wg := new(sync.WaitGroup)
-	wg.Add(2)
+	wg.Add(4)
 
 	go func() {
 		defer wg.Done()
@@ -52,6 +84,22 @@ func (b *NotiBuilder) Start(buildCtx context.Context) {
 		}
 	}()
 
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		if err := b.invalidTokensConsumer.Consuming(ctx, b.removeInvalidDevices); err != nil {
+			panic(err)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		if err := b.deferredScheduler.Dispatching(ctx, b.releaseDeferredPushNoti); err != nil {
+			panic(err)
+		}
+	}()
+
 	b.logger.Write(b.logger.
 		Info("notification builder is running").Namespace("noti_builder"))
 
@@ -87,8 +135,109 @@ func (b *NotiBuilder) producePushNotiTask(
 	defer b.logger.Write(b.logger.
 		Info("push notification batch queued").Namespace("noti_builder").Err(err))
 
-	serialized := string(models.NewPushNotiMessage(input, devices).Encode())
-	err = b.pushNotiProducer.Push(evt.Context(), serialized)
+	now := time.Now()
+	immediate := []*models.UserDevice{}
+	deferred := make(map[time.Time][]*models.UserDevice)
+	for _, device := range devices {
+		deliverAt := b.deliveryWindow.DeliverAt(input, device.Recipient, now)
+		if deliverAt.After(now) {
+			deferred[deliverAt] = append(deferred[deliverAt], device)
+		} else {
+			immediate = append(immediate, device)
+		}
+	}
+
+	for _, pushNoti := range b.renderPushNotiMessages(input, immediate) {
+		serialized := string(pushNoti.Encode())
+		if err = b.pushNotiProducer.Push(evt.Context(), serialized); err != nil {
+			return err
+		}
+	}
+	for deliverAt, group := range deferred {
+		for _, pushNoti := range b.renderPushNotiMessages(input, group) {
+			if err = b.deferPushNoti(evt.Context(), pushNoti, deliverAt); err != nil {
+				return err
+			}
+		}
+	}
+
+	return err
+}
+
+// deferPushNoti schedules the push notification, the tasks are grouped by the
+// message so that they can be listed for the message.
+func (b *NotiBuilder) deferPushNoti(
+	ctx context.Context,
+	pushNoti *models.PushNotiMessage,
+	deliverAt time.Time,
+) error {
+	task, err := ts.NewScheduledTask(uuid.NewString(), pushNoti.Id, string(pushNoti.Encode()), deliverAt)
+	if err != nil {
+		return err
+	}
+	return b.deferredScheduler.Schedule(ctx, task)
+}
+
+// releaseDeferredPushNoti queues the deferred push notification, whose delivery
+// time has come. The push notification of a canceled message is dropped by the
+// senders as usual.
+func (b *NotiBuilder) releaseDeferredPushNoti(ctx context.Context, task *ts.ScheduledTask) error {
+	var err error
+
+	evt := events.Start(ctx, "noti_builder.release_deferred_push_noti", nil)
+	defer events.End(evt, true, err, nil)
+
+	err = b.pushNotiProducer.Push(evt.Context(), task.Payload)
+
+	b.logger.Write(b.logger.
+		Info("deferred push notification queued").Namespace("noti_builder").Err(err))
 
 	return err
 }
+
+// removeInvalidDevices removes the devices of the tokens rejected permanently
+// by the push service, so that they are not targeted by the next campaigns.
+func (b *NotiBuilder) removeInvalidDevices(ctx context.Context, serialized string) error {
+	var err error
+
+	evt := events.Start(ctx, "noti_builder.remove_invalid_devices", nil)
+	defer events.End(evt, true, err, nil)
+
+	invalid := models.InvalidDeviceTokensDecode([]byte(serialized))
+	_, err = b.userService.DeleteDevicesByTokens(evt.Context(), invalid.Tokens)
+
+	b.logger.Write(b.logger.
+		Info("invalid devices removed").Namespace("noti_builder").Err(err))
+
+	return err
+}
+
+// renderPushNotiMessages personalizes the message for each recipient, the devices
+// receiving the same rendered content are grouped into the same push notification.
+func (b *NotiBuilder) renderPushNotiMessages(
+	input *models.MessageInput,
+	devices []*models.UserDevice,
+) []*models.PushNotiMessage {
+	pushNotis := []*models.PushNotiMessage{}
+	grouped := make(map[models.MessageContent]*models.PushNotiMessage)
+	for _, device := range devices {
+		rendered := input.Render(device.Recipient)
+		pushNoti, exists := grouped[rendered.Content()]
+		if !exists {
+			pushNoti = models.NewPushNotiMessage(rendered, []*models.UserDevice{})
+			grouped[rendered.Content()] = pushNoti
+			pushNotis = append(pushNotis, pushNoti)
+		}
+		// the recipient is not needed for delivery, except its id for the frequency capping
+		target := &models.UserDevice{
+			Platform: device.Platform,
+			Token:    device.Token,
+			WebPush:  device.WebPush,
+		}
+		if device.Recipient != nil {
+			target.UserId = device.Recipient.UserId
+		}
+		pushNoti.TargetDevices = append(pushNoti.TargetDevices, target)
+	}
+	return pushNotis
+}
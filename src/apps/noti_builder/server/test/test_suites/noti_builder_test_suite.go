@@ -37,19 +37,19 @@ func NewNotiBuilderTestSuite() *NotiBuilderTestSuite {
 }
 
 func (s *NotiBuilderTestSuite) SetupTest() {
-	s.usrRepo.DeleteUsersByIds(data.TestUserIds)
-	s.usrRepo.InsertManyUsers(data.TestUsers)
+	s.usrRepo.DeleteUsersByIds(context.Background(), data.TestUserIds)
+	s.usrRepo.InsertManyUsers(context.Background(), data.TestUsers)
 }
 
 func (s *NotiBuilderTestSuite) TearDownTest() {
-	s.usrRepo.DeleteUsersByIds(data.TestUserIds)
+	s.usrRepo.DeleteUsersByIds(context.Background(), data.TestUserIds)
 }
 
 func (s *NotiBuilderTestSuite) Test_NotiBuilder() {
 	input1 := models.NewMessageInput(data.TestCampaignPrimary, "title 1", "body 1")
 	input2 := models.NewMessageInput(data.TestCampaignPrimary, "title 2", "body 2")
-	s.msgInpPublisher.NotifyMainTopic(string(input1.Encode()))
-	s.msgInpPublisher.NotifyMainTopic(string(input2.Encode()))
+	s.msgInpPublisher.NotifyMainTopic(context.Background(), string(input1.Encode()))
+	s.msgInpPublisher.NotifyMainTopic(context.Background(), string(input2.Encode()))
 
 	totalDevices := 2 * len(data.TestDevices) // num of message * total test devices
 	totalBatches := totalDevices / int(s.distributor.GetDistributionSize())
@@ -78,7 +78,7 @@ func (s *NotiBuilderTestSuite) Test_NotiBuilder() {
 	}()
 	go func() {
 		defer wg.Done()
-		err := s.pushNotiConsumer.Consuming(ctx, func(ctx context.Context, str string) {
+		err := s.pushNotiConsumer.Consuming(ctx, func(ctx context.Context, str string) error {
 			s.Assert().NotPanics(func() {
 				pushNoti := models.PushNotiMessageDecode([]byte(str))
 				if pushNoti.MessageInput == nil {
@@ -89,8 +89,8 @@ func (s *NotiBuilderTestSuite) Test_NotiBuilder() {
 				countBatches++
 
 				s.Assert().True(
-					*input1 == *pushNoti.MessageInput ||
-						*input2 == *pushNoti.MessageInput,
+					input1.Content() == pushNoti.MessageInput.Content() ||
+						input2.Content() == pushNoti.MessageInput.Content(),
 				)
 				s.Assert().NotEmpty(devices)
 
@@ -98,6 +98,7 @@ func (s *NotiBuilderTestSuite) Test_NotiBuilder() {
 					done <- true
 				}
 			})
+			return nil
 		})
 		s.Assert().NoError(err)
 	}()
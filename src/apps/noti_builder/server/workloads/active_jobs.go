@@ -0,0 +1,50 @@
+package workloads
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"duolingo/libraries/events"
+)
+
+// activeJob is a batch job the builder is working on, it is finished once by
+// whichever worker finds it fulfilled, canceled or failed.
+type activeJob struct {
+	job        *TokenBatchJob
+	evt        *events.Event
+	batchCount atomic.Int64
+	finishOnce sync.Once
+}
+
+// activeJobs keeps the active jobs by their workload, which is the job id.
+type activeJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*activeJob
+}
+
+func newActiveJobs() *activeJobs {
+	return &activeJobs{jobs: make(map[string]*activeJob)}
+}
+
+// add registers the job, unless the job is already active.
+func (a *activeJobs) add(job *activeJob) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, exists := a.jobs[job.job.JobId]; exists {
+		return false
+	}
+	a.jobs[job.job.JobId] = job
+	return true
+}
+
+func (a *activeJobs) get(jobId string) *activeJob {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.jobs[jobId]
+}
+
+func (a *activeJobs) remove(jobId string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.jobs, jobId)
+}
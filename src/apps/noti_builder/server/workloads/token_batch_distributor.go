@@ -2,14 +2,18 @@ package workloads
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
 
+	"duolingo/libraries/config_reader"
 	container "duolingo/libraries/dependencies_container"
 	events "duolingo/libraries/events/facade"
 	ps "duolingo/libraries/message_queue/pub_sub"
 	"duolingo/libraries/telemetry/otel_wrapper/log"
 	dist "duolingo/libraries/work_distributor"
 	"duolingo/models"
+	status_svc "duolingo/services/message_status_service"
 	usr_svc "duolingo/services/user_service"
 )
 
@@ -19,115 +23,324 @@ type TokenBatchDistributor struct {
 	buildJobPublisher  ps.Publisher
 	buildJobSubscriber ps.Subscriber
 
-	userService *usr_svc.UserService
+	userService   *usr_svc.UserService
+	statusService *status_svc.MessageStatusService
 
 	logger *log.Logger
+
+	// The assignments of the active jobs are handled concurrently by a fixed
+	// number of workers, polling for the assignments at the interval.
+	activeJobs      *activeJobs
+	concurrency     int
+	pollingInterval time.Duration
+
+	// The turns taken by the jobs of each campaign among the active jobs
+	defaultWeight   int
+	campaignWeights map[string]int
 }
 
 func NewTokenBatchDistributor() *TokenBatchDistributor {
+	config := container.MustResolve[config_reader.ConfigReader]()
+	campaigns := config.GetArr("noti_builder", "workload_weights.campaigns.#.campaign")
+	weights := config.GetIntArr("noti_builder", "workload_weights.campaigns.#.weight")
+	campaignWeights := make(map[string]int, len(campaigns))
+	for i := range campaigns {
+		campaignWeights[campaigns[i]] = weights[i]
+	}
+	// a non-positive interval would let the workers poll without any pause
+	pollingInterval := time.Duration(config.GetInt("noti_builder", "polling_interval_ms")) * time.Millisecond
+	if pollingInterval <= 0 {
+		pollingInterval = 10 * time.Millisecond
+	}
 	return &TokenBatchDistributor{
 		WorkDistributor:    container.MustResolve[*dist.WorkDistributor](),
 		buildJobPublisher:  container.MustResolveAlias[ps.Publisher]("noti_builder_jobs_publisher"),
 		buildJobSubscriber: container.MustResolveAlias[ps.Subscriber]("noti_builder_jobs_subscriber"),
 		userService:        container.MustResolve[*usr_svc.UserService](),
+		statusService:      container.MustResolve[*status_svc.MessageStatusService](),
 		logger:             container.MustResolve[*log.Logger](),
+		activeJobs:         newActiveJobs(),
+		concurrency:        max(1, config.GetInt("noti_builder", "concurrency")),
+		pollingInterval:    pollingInterval,
+		defaultWeight:      config.GetInt("noti_builder", "workload_weights.default"),
+		campaignWeights:    campaignWeights,
 	}
 }
 
 func (d *TokenBatchDistributor) CreateBatchJob(ctx context.Context, input *models.MessageInput) error {
 	var err error
-	var count int64
+	var count, duplicates int64
 	var workload *dist.Workload
 
 	evt := events.Start(ctx, "token_batch_distributor.create_batch_job", nil)
 	defer events.End(evt, true, err, nil)
 
-	if count, err = d.userService.CountDevicesForCampaign(evt.Context(), input.Campaign); err == nil {
+	if d.statusService.IsCanceled(evt.Context(), input.Id) {
+		d.logger.Write(d.logger.Info("message canceled").Namespace("noti_builder.token_batch_distributor"))
+		return nil
+	}
+
+	// the audience is frozen for the whole job, the message id identifies its snapshot
+	snapshotId := input.Id
+	count, duplicates, err = d.userService.SnapshotDevicesForCampaign(evt.Context(), snapshotId, input.Campaign, input.Audience)
+	if err == nil {
+		evt.SetData("duplicate_tokens_total", duplicates)
 		if count == 0 {
 			d.logger.Write(d.logger.Info("workload empty").Namespace("noti_builder.token_batch_distributor"))
-			return nil
+			return d.statusService.MarkCompleted(evt.Context(), input.Id)
 		}
-		if workload, err = d.CreateWorkload(evt.Context(), count); err == nil {
+		if workload, err = d.createWorkload(evt.Context(), snapshotId, count); err == nil {
+			if err = d.statusService.MarkBuilding(evt.Context(), input.Id, workload); err != nil {
+				// the message is canceled while snapshotting, or its status is unavailable
+				d.discardWorkload(evt.Context(), workload, snapshotId)
+				if err == status_svc.ErrMessageNotBuildable {
+					d.logger.Write(d.logger.Info("message finished before building").Namespace("noti_builder.token_batch_distributor"))
+					err = nil
+				}
+				return err
+			}
 			job := NewTokenBatchJob(workload.Id, input)
+			job.SnapshotId = snapshotId
 			err = d.buildJobPublisher.NotifyMainTopic(evt.Context(), string(job.Encode()))
 			evt.SetData("devices_total", workload.TotalWorkUnits)
 			evt.SetData("batch_size", workload.TotalUnitsPerAssignment)
 			evt.SetData("expected_batches_total", workload.GetExpectTotalAssignments())
 		}
 	}
+	if err != nil {
+		d.statusService.MarkFailed(evt.Context(), input.Id, err)
+	}
 	return err
 }
 
-func (d *TokenBatchDistributor) ConsumingTokenBatches(
+// discardWorkload deletes the workload and the snapshot of the job not published
+func (d *TokenBatchDistributor) discardWorkload(ctx context.Context, workload *dist.Workload, snapshotId string) {
+	if err := d.DeleteWorkloadAndAssignments(ctx, workload.Id); err != nil {
+		d.logger.Write(d.logger.Error("workload deletion failed", err).Namespace("noti_builder.token_batch_distributor"))
+	}
+	if err := d.userService.DeleteDevicesSnapshot(ctx, snapshotId); err != nil {
+		d.logger.Write(d.logger.Error("audience snapshot deletion failed", err).Namespace("noti_builder.token_batch_distributor"))
+	}
+}
+
+// createWorkload splits the snapshot by user id boundaries, so that each batch
+// is listed by seeking its users rather than skipping the previous batches.
+func (d *TokenBatchDistributor) createWorkload(
 	ctx context.Context,
-	batchConsumer func(
-		ctx context.Context,
-		input *models.MessageInput,
-		devices []*models.UserDevice,
-	) error,
-) error {
-	return d.buildJobSubscriber.ListeningMainTopic(ctx, func(ctx context.Context, str string) error {
-		return d.startJobBatching(ctx, JobDecode([]byte(str)), batchConsumer)
-	})
+	snapshotId string,
+	count int64,
+) (*dist.Workload, error) {
+	boundaries, err := d.userService.GetDeviceBoundariesForSnapshot(ctx, snapshotId, d.GetDistributionSize())
+	if err != nil {
+		return nil, err
+	}
+	return d.CreateKeyedWorkload(ctx, count, boundaries)
 }
 
-func (d *TokenBatchDistributor) startJobBatching(
+// batchReceiver receives the devices of an assignment, the assignment is
+// rolled back if it returns an error.
+type batchReceiver func(
 	ctx context.Context,
-	job *TokenBatchJob,
-	batchReceiver func(
-		ctx context.Context,
-		input *models.MessageInput,
-		devices []*models.UserDevice,
-	) error,
-) error {
+	input *models.MessageInput,
+	devices []*models.UserDevice,
+) error
+
+// ConsumingTokenBatches works on the jobs the builder is notified of, the
+// notification returns once the job is registered, while the assignments of
+// the active jobs are handled by a bounded pool of workers.
+func (d *TokenBatchDistributor) ConsumingTokenBatches(ctx context.Context, receiver batchReceiver) error {
+	workCtx, stopWorking := context.WithCancel(ctx)
+	defer stopWorking()
+
+	wg := new(sync.WaitGroup)
+	wg.Add(d.concurrency)
+	for range d.concurrency {
+		go func() {
+			defer wg.Done()
+			d.working(workCtx, receiver)
+		}()
+	}
+
+	err := d.buildJobSubscriber.ListeningMainTopic(ctx, func(ctx context.Context, str string) error {
+		return d.startJobBatching(ctx, JobDecode([]byte(str)))
+	})
+
+	stopWorking()
+	wg.Wait()
+
+	return err
+}
+
+func (d *TokenBatchDistributor) startJobBatching(ctx context.Context, job *TokenBatchJob) error {
 	evt := events.Start(ctx, "token_batch_distributor.job_batching", nil)
 
 	if jobErr := job.Validate(); jobErr != nil {
 		events.Failed(evt, jobErr, nil)
 		return jobErr
 	}
+	if !d.activeJobs.add(&activeJob{job: job, evt: evt}) {
+		events.Succeeded(evt, nil)
+		return nil
+	}
+	d.GetRegistry().Register(job.JobId, d.workloadWeight(job.Message))
+	return nil
+}
 
-	consumeCtx, consumeCancel := context.WithCancel(ctx)
-	defer consumeCancel()
+// workloadWeight is the number of turns the job takes in a round, the jobs of
+// the campaigns without weight take one turn.
+func (d *TokenBatchDistributor) workloadWeight(input *models.MessageInput) int {
+	if weight, exists := d.campaignWeights[input.Campaign]; exists {
+		return weight
+	}
+	return d.defaultWeight
+}
 
-	var lastErr error
-	var interval = 10 * time.Millisecond
-	var jobId = job.JobId
-	var assignment *dist.Assignment
-	var batchCount int
+// working handles the assignments of the active jobs, the jobs take turns by
+// their weight, so that a small job is not held back by a large one. The worker
+// waits for the polling interval only once none of the jobs has an assignment
+// to hand out.
+func (d *TokenBatchDistributor) working(ctx context.Context, receiver batchReceiver) {
 	for {
 		select {
-		case <-consumeCtx.Done():
-			return nil
+		case <-ctx.Done():
+			return
 		default:
 		}
-		if lastErr != nil {
-			events.Failed(evt, lastErr, nil)
-			return lastErr
-		}
-		if assignment, lastErr = d.WaitForAssignment(consumeCtx, interval, jobId); lastErr != nil {
-			if lastErr == dist.ErrWorkloadHasAlreadyFulfilled {
-				events.Succeeded(evt, nil)
-				d.logger.Write(d.logger.Info("batch job fulfilled").Namespace("noti_builder.token_batch_distributor"))
-				return nil
+		assignment, err := d.AssignAny(ctx)
+		switch {
+		case err != nil:
+			d.handleWorkloadError(ctx, err)
+		case assignment != nil:
+			d.handleAssignment(ctx, assignment, receiver)
+		default:
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(d.pollingInterval):
 			}
-			continue
 		}
+	}
+}
 
-		batchCount++
-		evt.SetData("batch_count", batchCount)
-
-		lastErr = d.HandleAssignment(evt.Context(), assignment, func(assignmentCtx context.Context) error {
-			devices, queryErr := d.userService.GetDevicesForCampaign(
-				assignmentCtx,
-				job.Message.Campaign,
-				assignment.WorkStartAt()-1, // offset
-				assignment.WorkEndAt()-assignment.WorkStartAt()+1, // limit
-			)
-			if queryErr != nil {
-				return queryErr
-			}
-			return batchReceiver(assignmentCtx, job.Message, devices)
+// handleAssignment hands the devices of the assignment over to the receiver, the
+// job is failed if the assignment fails, the failed assignment is then handled
+// by the other builders.
+func (d *TokenBatchDistributor) handleAssignment(
+	ctx context.Context,
+	assignment *dist.Assignment,
+	receiver batchReceiver,
+) {
+	active := d.activeJobs.get(assignment.WorkloadId)
+	if active == nil {
+		// the job has been finished by another worker meanwhile
+		d.Rollback(ctx, assignment)
+		return
+	}
+	job := active.job
+	active.batchCount.Add(1)
+	err := d.HandleAssignment(active.evt.Context(), assignment, func(assignmentCtx context.Context) error {
+		devices, queryErr := d.getAssignedDevices(assignmentCtx, job, assignment)
+		if queryErr != nil {
+			return queryErr
+		}
+		return receiver(assignmentCtx, job.Message, devices)
+	})
+	if err != nil && ctx.Err() == nil {
+		d.failJob(ctx, active, err)
+	}
+}
+
+// handleWorkloadError finishes the job of the workload found fulfilled, or
+// failed to hand out its assignments.
+func (d *TokenBatchDistributor) handleWorkloadError(ctx context.Context, err error) {
+	var workloadErr *dist.WorkloadError
+	if !errors.As(err, &workloadErr) || ctx.Err() != nil {
+		return
+	}
+	active := d.activeJobs.get(workloadErr.WorkloadId)
+	if active == nil {
+		d.GetRegistry().Unregister(workloadErr.WorkloadId)
+		return
+	}
+	if errors.Is(err, dist.ErrWorkloadHasAlreadyFulfilled) {
+		d.finishJob(active, func() {
+			d.deleteSnapshot(ctx, active.job)
+			events.Succeeded(active.evt, nil)
+			d.logger.Write(d.logger.Info("batch job fulfilled").Namespace("noti_builder.token_batch_distributor"))
 		})
+		return
+	}
+	d.failJob(ctx, active, err)
+}
+
+func (d *TokenBatchDistributor) failJob(ctx context.Context, active *activeJob, err error) {
+	d.finishJob(active, func() {
+		// the workload is deleted when the message is canceled
+		if d.statusService.IsCanceled(ctx, active.job.Message.Id) {
+			d.deleteSnapshot(ctx, active.job)
+			events.Succeeded(active.evt, nil)
+			d.logger.Write(d.logger.Info("batch job canceled").Namespace("noti_builder.token_batch_distributor"))
+			return
+		}
+		events.Failed(active.evt, err, nil)
+		d.logger.Write(d.logger.Error("batch job failed", err).Namespace("noti_builder.token_batch_distributor"))
+	})
+}
+
+// finishJob stops working on the job, the job is finished once even though
+// several workers may find it done at the same time.
+func (d *TokenBatchDistributor) finishJob(active *activeJob, finish func()) {
+	active.finishOnce.Do(func() {
+		d.GetRegistry().Unregister(active.job.JobId)
+		d.activeJobs.remove(active.job.JobId)
+		active.evt.SetData("batch_count", active.batchCount.Load())
+		finish()
+	})
+}
+
+// getAssignedDevices lists the devices of the assignment, the assignments
+// without key range are listed by offset, and those without snapshot from
+// the live users.
+func (d *TokenBatchDistributor) getAssignedDevices(
+	ctx context.Context,
+	job *TokenBatchJob,
+	assignment *dist.Assignment,
+) ([]*models.UserDevice, error) {
+	input := job.Message
+	switch {
+	case assignment.IsKeyRanged() && job.SnapshotId != "":
+		return d.userService.GetDevicesForSnapshotInRange(
+			ctx,
+			job.SnapshotId,
+			assignment.KeyRange.After,
+			assignment.KeyRange.Until,
+		)
+	case assignment.IsKeyRanged():
+		return d.userService.GetDevicesForCampaignInRange(
+			ctx,
+			input.Campaign,
+			input.Audience,
+			assignment.KeyRange.After,
+			assignment.KeyRange.Until,
+		)
+	default:
+		return d.userService.GetDevicesForCampaign(
+			ctx,
+			input.Campaign,
+			input.Audience,
+			assignment.WorkStartAt()-1, // offset
+			assignment.WorkEndAt()-assignment.WorkStartAt()+1, // limit
+		)
+	}
+}
+
+// deleteSnapshot removes the audience snapshot once the job is done, every
+// worker of the job may remove it. The snapshot expires if it fails.
+func (d *TokenBatchDistributor) deleteSnapshot(ctx context.Context, job *TokenBatchJob) {
+	if job.SnapshotId == "" {
+		return
+	}
+	if err := d.userService.DeleteDevicesSnapshot(ctx, job.SnapshotId); err != nil {
+		d.logger.Write(d.logger.Error("audience snapshot deletion failed", err).Namespace("noti_builder.token_batch_distributor"))
 	}
 }
@@ -9,6 +9,10 @@ import (
 type TokenBatchJob struct {
 	JobId   string               `json:"job_id"`
 	Message *models.MessageInput `json:"message"`
+
+	// The audience snapshot the batches are listed from, empty for the jobs
+	// listing the live users.
+	SnapshotId string `json:"snapshot_id,omitempty"`
 }
 
 func NewTokenBatchJob(jobId string, message *models.MessageInput) *TokenBatchJob {
@@ -14,8 +14,13 @@ func main() {
 	dependencies.Bootstrap(ctx, "push_sender", "", []string{
 		"essentials",
 		"connections",
+		"message_queues",
+		"pub_sub",
 		"task_queues",
 		"push_service",
+		"message_status_repo",
+		"rate_limiter",
+		"frequency_cap",
 	})
 
 	sender := server.NewSender()
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"sync"
+
+	"duolingo/models"
+)
+
+// Delivery is a push notification task being delivered, the task is done once
+// every token of the task has been sent, or has definitively failed.
+type Delivery struct {
+	messageId string
+	pending   int
+	done      func()
+}
+
+// trackedToken links a buffered token to the delivery of its task, the device
+// is kept for the platform specific data, e.g. the web push subscription keys.
+// The content is the message rendered for the recipient of the device.
+type trackedToken struct {
+	token    string
+	device   *models.UserDevice
+	content  models.MessageContent
+	delivery *Delivery
+}
+
+/*
+DeliveryTracker links the push notification tasks to the buffer flushes,
+so that a task is acknowledged only after its tokens have been delivered.
+The task still buffered when the sender stops is redelivered to a sender.
+*/
+type DeliveryTracker struct {
+	mu         sync.Mutex
+	deliveries map[string]map[*Delivery]bool
+}
+
+func NewDeliveryTracker() *DeliveryTracker {
+	return &DeliveryTracker{
+		deliveries: make(map[string]map[*Delivery]bool),
+	}
+}
+
+// Track starts tracking the delivery of the task devices, "done" is called
+// right away if there is no device to deliver.
+func (tracker *DeliveryTracker) Track(messageId string, devices []*models.UserDevice, done func()) []*trackedToken {
+	delivery := &Delivery{
+		messageId: messageId,
+		pending:   len(devices),
+		done:      done,
+	}
+	if len(devices) == 0 {
+		done()
+		return []*trackedToken{}
+	}
+
+	tracker.mu.Lock()
+	if tracker.deliveries[messageId] == nil {
+		tracker.deliveries[messageId] = make(map[*Delivery]bool)
+	}
+	tracker.deliveries[messageId][delivery] = true
+	tracker.mu.Unlock()
+
+	tracked := make([]*trackedToken, len(devices))
+	for i := range devices {
+		tracked[i] = &trackedToken{token: devices[i].Token, device: devices[i], delivery: delivery}
+	}
+	return tracked
+}
+
+// ResolveMessage ends the deliveries of a message, e.g. the canceled message
+// whose buffered tokens are dropped.
+func (tracker *DeliveryTracker) ResolveMessage(messageId string) {
+	tracker.mu.Lock()
+	deliveries := tracker.deliveries[messageId]
+	delete(tracker.deliveries, messageId)
+	tracker.mu.Unlock()
+
+	for delivery := range deliveries {
+		delivery.done()
+	}
+}
+
+// InFlight returns the number of the deliveries not done yet
+func (tracker *DeliveryTracker) InFlight() int {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	count := 0
+	for _, deliveries := range tracker.deliveries {
+		count += len(deliveries)
+	}
+	return count
+}
+
+// Resolve marks the tokens as sent or definitively failed
+func (tracker *DeliveryTracker) Resolve(tokens []*trackedToken) {
+	counts := make(map[*Delivery]int)
+	for _, token := range tokens {
+		counts[token.delivery]++
+	}
+	for delivery, count := range counts {
+		tracker.resolve(delivery, count)
+	}
+}
+
+func (tracker *DeliveryTracker) resolve(delivery *Delivery, count int) {
+	tracker.mu.Lock()
+	if !tracker.deliveries[delivery.messageId][delivery] {
+		// the delivery has been ended by ResolveMessage()
+		tracker.mu.Unlock()
+		return
+	}
+	delivery.pending -= count
+	finished := delivery.pending <= 0
+	if finished {
+		delete(tracker.deliveries[delivery.messageId], delivery)
+		if len(tracker.deliveries[delivery.messageId]) == 0 {
+			delete(tracker.deliveries, delivery.messageId)
+		}
+	}
+	tracker.mu.Unlock()
+
+	if finished {
+		delivery.done()
+	}
+}
@@ -2,19 +2,35 @@ package server
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"sync"
 	"time"
 
+	"duolingo/libraries/backoff"
 	"duolingo/libraries/buffer"
 	"duolingo/libraries/config_reader"
 	container "duolingo/libraries/dependencies_container"
+	"duolingo/libraries/frequency_cap"
+	ps "duolingo/libraries/message_queue/pub_sub"
 	tq "duolingo/libraries/message_queue/task_queue"
 	push_noti "duolingo/libraries/push_notification"
 	"duolingo/libraries/push_notification/message"
+	"duolingo/libraries/push_notification/results"
+	"duolingo/libraries/rate_limiter"
 	"duolingo/libraries/telemetry/otel_wrapper/log"
 	"duolingo/models"
+	status_repo "duolingo/repositories/message_status_repository/external"
 )
 
+// retryBatch groups the retried tokens of a message by their attempt, the
+// group of an attempt is reused by the later retries of the message, rather
+// than declared for each retried content.
+type retryBatch struct {
+	messageId string
+	attempt   int
+}
+
 type Sender struct {
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -22,18 +38,53 @@ type Sender struct {
 	// Consumer receiving incoming push notification task
 	pushNotiConsumer tq.TaskConsumer
 
-	// Sending notifications to supported platforms (e.g., Android, IOS).
+	// Producer sending back the tokens rejected permanently by the PushService,
+	// so that their devices are removed.
+	invalidTokensProducer tq.TaskProducer
+
+	// Subscriber receiving the ids of the canceled messages
+	cancelSubscriber ps.Subscriber
+
+	// Sending notifications to supported platforms (e.g., Android, IOS, web),
+	// the PushService routes each device to the provider of its platform.
 	platforms   []string
 	pushService push_noti.PushService
 
+	// The send rate is limited across the Sender replicas, so that the project
+	// quotas of the PushService are not exceeded.
+	rateLimiter rate_limiter.RateLimiter
+
+	// The users receive a limited number of messages within a window, the
+	// devices of the users over the cap are suppressed. The transactional
+	// campaigns can be exempted from the cap.
+	frequencyCap    frequency_cap.FrequencyCap
+	exemptCampaigns []string
+
 	// The PushService may limit the number of device tokens that can be included
 	// in each send request, or the system may need to manage the sending rate.
 	// As a result, the Sender cannot submit a send request to the PushService for
 	// every incoming push notification message received by the Subscriber. Instead,
 	// each message is stored in a token buffer. When the buffer reaches its size
 	// limit, the Sender is then able to flush the tokens and submit a send request
-	// to the PushService.
-	buffer *buffer.BufferGroup[models.MessageInput, string]
+	// to the PushService. The tokens are buffered by message, rather than by the
+	// content rendered for each recipient, and grouped by content once flushed.
+	buffer *buffer.BufferGroup[string, *trackedToken]
+
+	// The push notification tasks are acknowledged once their tokens have
+	// been delivered, rather than once they have been buffered.
+	tracker *DeliveryTracker
+
+	// The tokens failed transiently are buffered again after a backoff wait,
+	// until they are delivered or the attempts limit is reached. The tokens
+	// of the last failed attempt are sent to the dead letters queue.
+	retryBuffer        *buffer.BufferGroup[retryBatch, *trackedToken]
+	retryBackoff       *backoff.ExponentialBackoff
+	retryMaxAttempts   int
+	deadLetterProducer tq.TaskProducer
+
+	// The delivery results are reported to the message status, so that the
+	// delivery progress of each message can be queried.
+	statusRepo status_repo.MessageStatusRepository
 
 	// Sender operations are executed asynchronously, any errors occur might be
 	// sent to this channel as a fallback handling.
@@ -47,19 +98,39 @@ func NewSender() *Sender {
 	platforms := config.GetArr("push_sender", "supported_platforms")
 	bufferLimit := config.GetInt("push_sender", "buffer_limit_count")
 	bufferInterval := time.Duration(config.GetInt("push_sender", "flush_duration_ms")) * time.Millisecond
-	grp := buffer.NewBufferGroup[models.MessageInput, string]()
+	grp := buffer.NewBufferGroup[string, *trackedToken]()
 	grp.SetLimit(bufferLimit).SetInterval(bufferInterval)
+	retryGrp := buffer.NewBufferGroup[retryBatch, *trackedToken]()
+	retryGrp.SetLimit(bufferLimit).SetInterval(bufferInterval)
+	retryBackoff := backoff.NewExponentialBackoff(
+		time.Duration(config.GetInt("push_sender", "retry_base_ms"))*time.Millisecond,
+		time.Duration(config.GetInt("push_sender", "retry_max_ms"))*time.Millisecond,
+	).SetJitter(true)
 
+	// The tasks stay unacknowledged while their tokens are buffered or retried,
+	// the prefetch must be large enough for the buffers to reach their limit.
 	pushNotiConsumer := container.MustResolveAlias[tq.TaskConsumer]("push_notifications_consumer")
+	pushNotiConsumer.SetPrefetch(config.GetInt("push_sender", "prefetch_count"))
 	pushService := container.MustResolve[push_noti.PushService]()
 
 	return &Sender{
-		pushNotiConsumer: pushNotiConsumer,
-		pushService:      pushService,
-		buffer:           grp,
-		platforms:        platforms,
-		errChan:          make(chan error, 100),
-		logger:           container.MustResolve[*log.Logger](),
+		pushNotiConsumer:      pushNotiConsumer,
+		invalidTokensProducer: container.MustResolveAlias[tq.TaskProducer]("invalid_device_tokens_producer"),
+		cancelSubscriber:      container.MustResolveAlias[ps.Subscriber]("message_cancellation_subscriber"),
+		pushService:           pushService,
+		rateLimiter:           container.MustResolveAlias[rate_limiter.RateLimiter]("push_notifications_rate_limiter"),
+		frequencyCap:          container.MustResolveAlias[frequency_cap.FrequencyCap]("push_notifications_frequency_cap"),
+		exemptCampaigns:       config.GetArr("push_sender", "frequency_cap_exempt_campaigns"),
+		buffer:                grp,
+		tracker:               NewDeliveryTracker(),
+		retryBuffer:           retryGrp,
+		retryBackoff:          retryBackoff,
+		retryMaxAttempts:      config.GetInt("push_sender", "retry_max_attempts"),
+		deadLetterProducer:    container.MustResolveAlias[tq.TaskProducer]("push_notifications_dead_letters_producer"),
+		statusRepo:            container.MustResolve[status_repo.MessageStatusRepository](),
+		platforms:             platforms,
+		errChan:               make(chan error, 100),
+		logger:                container.MustResolve[*log.Logger](),
 	}
 }
 
@@ -68,18 +139,28 @@ func (sender *Sender) Start(serverCtx context.Context) {
 	defer sender.cancel()
 
 	wg := new(sync.WaitGroup)
-	wg.Add(2)
+	wg.Add(3)
 
 	go sender.handleErrChannel(wg, sender.ctx)
 
+	go func() {
+		defer sender.cancel()
+		defer wg.Done()
+		err := sender.cancelSubscriber.ListeningMainTopic(sender.ctx, sender.dropCanceledMessage)
+		if err != nil {
+			panic(err)
+		}
+	}()
+
 	go func() {
 		defer sender.cancel()
 		defer wg.Done()
 		// When the buffer reaches size limit, flush the tokens and submit a send
 		// request to the PushService.
 		sender.buffer.SetConsumeFunc(false, sender.sendPushNoti)
+		sender.retryBuffer.SetConsumeFunc(false, sender.retryPushNoti)
 		// Stored incoming push notifications in a token buffer
-		err := sender.pushNotiConsumer.Consuming(sender.ctx, sender.bufferTokens)
+		err := sender.pushNotiConsumer.ConsumingAsync(sender.ctx, sender.bufferTokens)
 		if err != nil {
 			panic(err)
 		}
@@ -104,34 +185,249 @@ func (sender *Sender) handleErrChannel(wg *sync.WaitGroup, ctx context.Context)
 	}
 }
 
-func (sender *Sender) bufferTokens(ctx context.Context, serialized string) error {
+func (sender *Sender) bufferTokens(ctx context.Context, serialized string, done func()) error {
 	msg := models.PushNotiMessageDecode([]byte(serialized))
 	if err := msg.Validate(); err != nil {
 		sender.errChan <- err
 		return err
 	}
-	sender.buffer.DeclareGroup(sender.ctx, *msg.MessageInput)
-	sender.buffer.Write(*msg.MessageInput, msg.GetTargetTokens(sender.platforms)...)
+	if sender.isCanceled(ctx, msg.Id) {
+		sender.logger.Write(sender.logger.Info("push notification of canceled message dropped").Namespace("push_sender"))
+		done()
+		return nil
+	}
+	sender.dropDuplicateTokens(msg)
+	sender.applyFrequencyCap(ctx, msg)
+	devices := msg.GetTargetDevices(sender.platforms)
+	// The devices of the unsupported platforms, or without token are undeliverable
+	if skipped := len(msg.TargetDevices) - len(devices); skipped > 0 {
+		sender.reportDeliveryResults(msg.Id, 0, skipped)
+	}
+	tracked := sender.tracker.Track(msg.Id, devices, done)
+	for _, token := range tracked {
+		token.content = msg.Content()
+	}
+	sender.buffer.DeclareGroup(sender.ctx, msg.Id)
+	sender.buffer.Write(msg.Id, tracked...)
 	sender.logger.Write(sender.logger.Info("push notification tokens buffered").Namespace("push_sender"))
 	return nil
 }
 
-func (sender *Sender) sendPushNoti(
+// dropDuplicateTokens keeps a single device per token. The audience snapshots
+// are deduplicated already, the messages of the live audiences are not.
+func (sender *Sender) dropDuplicateTokens(msg *models.PushNotiMessage) {
+	if duplicates := msg.DedupeTokens(); duplicates > 0 {
+		if err := sender.statusRepo.IncreaseDuplicateCount(sender.ctx, msg.Id, int64(duplicates)); err != nil {
+			sender.errChan <- err
+		}
+		sender.logger.Write(sender.logger.Info("duplicate push notification tokens dropped").
+			Data(map[string]any{"duplicate_tokens_total": duplicates}).
+			Namespace("push_sender"))
+	}
+}
+
+// applyFrequencyCap removes the devices of the users who have reached their cap.
+// The message is sent uncapped if the cap is unavailable, rather than delayed.
+func (sender *Sender) applyFrequencyCap(ctx context.Context, msg *models.PushNotiMessage) {
+	if slices.Contains(sender.exemptCampaigns, msg.Campaign) {
+		return
+	}
+	userIds := msg.GetTargetUserIds()
+	if len(userIds) == 0 {
+		return
+	}
+	allowed, err := sender.frequencyCap.Acquire(ctx, msg.Id, userIds)
+	if err != nil {
+		sender.errChan <- err
+		return
+	}
+	if suppressed := msg.FilterUsers(allowed); suppressed > 0 {
+		if err := sender.statusRepo.IncreaseSuppressedCount(sender.ctx, msg.Id, int64(suppressed)); err != nil {
+			sender.errChan <- err
+		}
+		sender.logger.Write(sender.logger.Info("push notification suppressed by frequency cap").Namespace("push_sender"))
+	}
+}
+
+// dropCanceledMessage discards the tokens buffered for the canceled message
+func (sender *Sender) dropCanceledMessage(ctx context.Context, messageId string) error {
+	sender.buffer.RemoveGroup(messageId)
+	sender.retryBuffer.RemoveGroups(func(batch retryBatch) bool {
+		return batch.messageId == messageId
+	})
+	sender.tracker.ResolveMessage(messageId)
+	sender.logger.Write(sender.logger.Info("buffered tokens of canceled message dropped").Namespace("push_sender"))
+	return nil
+}
+
+func (sender *Sender) isCanceled(ctx context.Context, messageId string) bool {
+	status, err := sender.statusRepo.GetMessageStatus(ctx, messageId)
+	return err == nil && status.State == models.MessageCanceled
+}
+
+func (sender *Sender) sendPushNoti(ctx context.Context, messageId string, tokens []*trackedToken) {
+	sender.deliverByContent(ctx, tokens, 1)
+}
+
+func (sender *Sender) retryPushNoti(ctx context.Context, batch retryBatch, tokens []*trackedToken) {
+	sender.deliverByContent(ctx, tokens, batch.attempt)
+}
+
+// deliverByContent sends a request per content of the flushed tokens, as the
+// recipients of a message may receive the content rendered differently.
+func (sender *Sender) deliverByContent(ctx context.Context, tracked []*trackedToken, attempt int) {
+	contents := []models.MessageContent{}
+	grouped := make(map[models.MessageContent][]*trackedToken)
+	for _, token := range tracked {
+		if _, exists := grouped[token.content]; !exists {
+			contents = append(contents, token.content)
+		}
+		grouped[token.content] = append(grouped[token.content], token)
+	}
+	for _, content := range contents {
+		sender.deliver(ctx, content, grouped[content], attempt)
+	}
+}
+
+func (sender *Sender) deliver(
 	ctx context.Context,
-	input models.MessageInput,
-	tokens []string,
+	input models.MessageContent,
+	tracked []*trackedToken,
+	attempt int,
 ) {
 	noti := &message.Message{
 		Title: input.Title,
 		Body:  input.Body,
 	}
 	target := &message.MulticastTarget{
-		DeviceTokens: tokens,
-		Platforms:    message.Platforms(sender.platforms...),
+		DeviceTokens:    tokensOf(tracked),
+		Platforms:       message.Platforms(sender.platforms...),
+		DevicePlatforms: make(map[string]message.Platform, len(tracked)),
+		WebPushKeys:     make(map[string]*message.WebPushKeys),
+	}
+	for _, token := range tracked {
+		target.DevicePlatforms[token.token] = message.Platform(token.device.Platform)
+		if token.device.WebPush != nil {
+			target.WebPushKeys[token.token] = &message.WebPushKeys{
+				P256dh: token.device.WebPush.P256dh,
+				Auth:   token.device.WebPush.Auth,
+			}
+		}
+	}
+	if err := rate_limiter.Wait(ctx, sender.rateLimiter, len(tracked)); err != nil {
+		if ctx.Err() != nil {
+			// the tokens are not resolved, their tasks are redelivered
+			return
+		}
+		// the limiter is unavailable, the tokens are sent without waiting
+		sender.errChan <- err
 	}
-	if _, err := sender.pushService.SendMulticast(ctx, noti, target); err != nil {
+	result, err := sender.pushService.SendMulticast(ctx, noti, target)
+	if err != nil {
+		// the whole request failed, every token is retried
+		var retryAfter time.Duration
+		var sendErr *results.SendError
+		if errors.As(err, &sendErr) {
+			retryAfter = sendErr.RetryAfter
+		}
+		sender.retry(input, tracked, attempt, retryAfter, err.Error())
 		sender.errChan <- err
 		return
 	}
+	retryTokens, retryAfter := result.GetRetryableFailures()
+	resolved, retried := splitTrackedTokens(tracked, retryTokens)
+	// the retried tokens are reported once they are delivered, or dead lettered
+	sender.reportDeliveryResults(input.Id, result.SuccessCount, result.FailureCount-len(retryTokens))
+	sender.pruneInvalidTokens(ctx, input.Id, result.GetInvalidTokens())
+	sender.tracker.Resolve(resolved)
+	if len(retried) > 0 {
+		sender.retry(input, retried, attempt, retryAfter, string(results.FailureTransient))
+	}
 	sender.logger.Write(sender.logger.Info("push notification request sent").Namespace("push_sender"))
 }
+
+// retry buffers the tokens again after the backoff wait, or the wait requested
+// by the PushService if it is longer.
+func (sender *Sender) retry(
+	input models.MessageContent,
+	tracked []*trackedToken,
+	attempt int,
+	retryAfter time.Duration,
+	reason string,
+) {
+	if attempt >= sender.retryMaxAttempts {
+		sender.deadLetter(input, tracked, attempt, reason)
+		return
+	}
+	batch := retryBatch{messageId: input.Id, attempt: attempt + 1}
+	wait := max(sender.retryBackoff.Delay(attempt), retryAfter)
+	time.AfterFunc(wait, func() {
+		// the tasks are redelivered to the other senders if stopped
+		if sender.ctx.Err() != nil {
+			return
+		}
+		if sender.isCanceled(sender.ctx, input.Id) {
+			sender.tracker.Resolve(tracked)
+			return
+		}
+		sender.retryBuffer.DeclareGroup(sender.ctx, batch)
+		sender.retryBuffer.Write(batch, tracked...)
+	})
+}
+
+func (sender *Sender) deadLetter(
+	input models.MessageContent,
+	tracked []*trackedToken,
+	attempts int,
+	reason string,
+) {
+	defer sender.tracker.Resolve(tracked)
+	sender.reportDeliveryResults(input.Id, 0, len(tracked))
+	deadLetter := models.NewPushNotiDeadLetter(input, tokensOf(tracked), attempts, reason)
+	if err := sender.deadLetterProducer.Push(sender.ctx, string(deadLetter.Encode())); err != nil {
+		sender.errChan <- err
+	}
+}
+
+// splitTrackedTokens separates the tokens to retry from the others
+func splitTrackedTokens(tracked []*trackedToken, retryTokens []string) ([]*trackedToken, []*trackedToken) {
+	resolved, retried := []*trackedToken{}, []*trackedToken{}
+	for _, token := range tracked {
+		if slices.Contains(retryTokens, token.token) {
+			retried = append(retried, token)
+		} else {
+			resolved = append(resolved, token)
+		}
+	}
+	return resolved, retried
+}
+
+func tokensOf(tracked []*trackedToken) []string {
+	tokens := make([]string, len(tracked))
+	for i := range tracked {
+		tokens[i] = tracked[i].token
+	}
+	return tokens
+}
+
+func (sender *Sender) pruneInvalidTokens(ctx context.Context, messageId string, tokens []string) {
+	if len(tokens) == 0 {
+		return
+	}
+	invalid := models.NewInvalidDeviceTokens(messageId, tokens)
+	if err := sender.invalidTokensProducer.Push(ctx, string(invalid.Encode())); err != nil {
+		sender.errChan <- err
+	}
+}
+
+func (sender *Sender) reportDeliveryResults(messageId string, successCount int, failureCount int) {
+	err := sender.statusRepo.IncreaseDeliveryResults(
+		sender.ctx,
+		messageId,
+		int64(successCount),
+		int64(failureCount),
+	)
+	if err != nil {
+		sender.errChan <- err
+	}
+}
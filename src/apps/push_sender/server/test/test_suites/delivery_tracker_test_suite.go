@@ -0,0 +1,75 @@
+package test_suites
+
+import (
+	"duolingo/apps/push_sender/server"
+	"duolingo/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DeliveryTrackerTestSuite struct {
+	suite.Suite
+}
+
+func NewDeliveryTrackerTestSuite() *DeliveryTrackerTestSuite {
+	return &DeliveryTrackerTestSuite{}
+}
+
+func (s *DeliveryTrackerTestSuite) Test_Track_WithoutTokens() {
+	tracker := server.NewDeliveryTracker()
+
+	done := 0
+	tracked := tracker.Track("message_1", devices(), func() { done++ })
+
+	s.Assert().Empty(tracked)
+	s.Assert().Equal(1, done)
+	s.Assert().Equal(0, tracker.InFlight())
+}
+
+func (s *DeliveryTrackerTestSuite) Test_Resolve() {
+	tracker := server.NewDeliveryTracker()
+
+	firstDone, secDone := 0, 0
+	first := tracker.Track("message_1", devices("token_1", "token_2", "token_3"), func() { firstDone++ })
+	sec := tracker.Track("message_1", devices("token_4"), func() { secDone++ })
+	s.Assert().Equal(2, tracker.InFlight())
+
+	// the tokens of both tasks are flushed together
+	tracker.Resolve(append(first[:2:2], sec...))
+	s.Assert().Equal(0, firstDone)
+	s.Assert().Equal(1, secDone)
+	s.Assert().Equal(1, tracker.InFlight())
+
+	tracker.Resolve(first[2:])
+	s.Assert().Equal(1, firstDone)
+	s.Assert().Equal(0, tracker.InFlight())
+
+	// a task is done once
+	tracker.Resolve(first)
+	s.Assert().Equal(1, firstDone)
+}
+
+func (s *DeliveryTrackerTestSuite) Test_ResolveMessage() {
+	tracker := server.NewDeliveryTracker()
+
+	firstDone, secDone := 0, 0
+	first := tracker.Track("message_1", devices("token_1", "token_2"), func() { firstDone++ })
+	tracker.Track("message_2", devices("token_3"), func() { secDone++ })
+
+	tracker.ResolveMessage("message_1")
+	s.Assert().Equal(1, firstDone)
+	s.Assert().Equal(0, secDone)
+	s.Assert().Equal(1, tracker.InFlight())
+
+	// the tokens flushed after the message is canceled are ignored
+	tracker.Resolve(first)
+	s.Assert().Equal(1, firstDone)
+}
+
+func devices(tokens ...string) []*models.UserDevice {
+	devices := make([]*models.UserDevice, len(tokens))
+	for i := range tokens {
+		devices[i] = &models.UserDevice{Platform: "android", Token: tokens[i]}
+	}
+	return devices
+}
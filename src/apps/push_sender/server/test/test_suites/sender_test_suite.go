@@ -128,8 +128,66 @@ func (s *SenderTestSuite) Test_Sender_BufferLimit() {
 			models.NewMessageInput(data.TestCampaignPrimary, "title 2", "body 2"),
 			data.TestDevices,
 		)
-		s.producer.Push(string(noti1.Encode()))
-		s.producer.Push(string(noti2.Encode()))
+		s.producer.Push(ctx, string(noti1.Encode()))
+		s.producer.Push(ctx, string(noti2.Encode()))
 	}()
 	wg.Wait()
 }
+
+func (s *SenderTestSuite) Test_Sender_Groups_Rendered_Contents_Of_Message() {
+	pushService := container.MustResolve[push_notification.PushService]()
+	fakeService, ok := pushService.(*fakes.FakePushService)
+	if !ok {
+		panic("canot resolve fake push service")
+	}
+	sender := server.NewSender()
+
+	// the message is rendered for each recipient, e.g. with the firstname
+	input := models.NewMessageInput(data.TestCampaignPrimary, "Hi {{firstname}}", "body")
+	forAnn, forBob := *input, *input
+	forAnn.Title, forBob.Title = "Hi Ann", "Hi Bob"
+	annDevices, bobDevices := data.TestDevices[:5], data.TestDevices[5:]
+	tokensOf := func(devices []*models.UserDevice) []string {
+		tokens := []string{}
+		for _, device := range devices {
+			tokens = append(tokens, device.Token)
+		}
+		return tokens
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	flushTokenCount := 0
+	wg := new(sync.WaitGroup)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		sender.Start(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		s.producer.Push(ctx, string(models.NewPushNotiMessage(&forAnn, annDevices).Encode()))
+		s.producer.Push(ctx, string(models.NewPushNotiMessage(&forBob, bobDevices).Encode()))
+	}()
+	msgChan := fakeService.GetMesgChan()
+	for flushTokenCount < len(data.TestDevices) {
+		select {
+		case <-ctx.Done():
+			s.Fail("the tokens of the rendered contents are not delivered")
+			return
+		case msg := <-msgChan:
+			// each request carries the tokens of its own content only
+			expected := tokensOf(bobDevices)
+			if msg.Title == "Hi Ann" {
+				expected = tokensOf(annDevices)
+			}
+			for _, token := range msg.Tokens {
+				s.Assert().Contains(expected, token)
+			}
+			flushTokenCount += len(msg.Tokens)
+		}
+	}
+	cancel()
+	wg.Wait()
+}
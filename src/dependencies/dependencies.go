@@ -31,7 +31,14 @@ func Bootstrap(ctx context.Context, app string, scope string, grps []string) {
 		dependencies_provider.AddProvider(&providers.TaskQueueProvider{}, "message_queues", "task_queues")
 		dependencies_provider.AddProvider(&providers.UserRepoProvider{}, "user_repo")
 		dependencies_provider.AddProvider(&providers.UserServiceProvider{}, "user_service")
+		dependencies_provider.AddProvider(&providers.MessageStatusRepoProvider{}, "message_status_repo")
+		dependencies_provider.AddProvider(&providers.MessageStatusServiceProvider{}, "message_status_service")
+		dependencies_provider.AddProvider(&providers.OutboxRepoProvider{}, "outbox_repo")
 		dependencies_provider.AddProvider(&providers.WorkDistributorProvider{}, "work_distributor")
+		dependencies_provider.AddProvider(&providers.TaskSchedulerProvider{}, "task_scheduler")
+		dependencies_provider.AddProvider(&providers.IdempotencyProvider{}, "idempotency")
+		dependencies_provider.AddProvider(&providers.RateLimiterProvider{}, "rate_limiter")
+		dependencies_provider.AddProvider(&providers.FrequencyCapProvider{}, "frequency_cap")
 		dependencies_provider.AddProvider(&providers.PushServiceProvider{}, "push_service")
 
 		dependencies_provider.BootstrapGroups(ctx, scope, grps)
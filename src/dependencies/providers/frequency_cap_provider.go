@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"duolingo/libraries/config_reader"
+	facade "duolingo/libraries/connection_manager/facade"
+	"duolingo/libraries/frequency_cap/drivers/redis"
+	"duolingo/libraries/telemetry/otel_wrapper/log"
+	"duolingo/libraries/telemetry/otel_wrapper/trace"
+
+	container "duolingo/libraries/dependencies_container"
+	event "duolingo/libraries/events"
+	events "duolingo/libraries/events/facade"
+
+	"go.opentelemetry.io/otel/attribute"
+	otlptrace "go.opentelemetry.io/otel/trace"
+)
+
+type FrequencyCapProvider struct {
+}
+
+func (provider *FrequencyCapProvider) Bootstrap(bootstrapCtx context.Context, scope string) {
+	tracer := container.MustResolve[*trace.TraceManager]()
+	logger := container.MustResolve[*log.Logger]()
+
+	/* Declare Frequency Caps */
+
+	provider.declareCap("push_notifications", "push_notifications_frequency_cap")
+
+	/* Tracing Instrumentation */
+
+	tracer.Decorate("frequency_cap.*", func(
+		span otlptrace.Span,
+		data trace.DataBag,
+	) {
+		span.SetAttributes(
+			attribute.String("frequency_cap.operation.name", data.Get("operation_name")),
+			attribute.String("frequency_cap.scope", data.Get("scope")),
+		)
+	})
+
+	/* Logs Instrumentation */
+
+	events.SubscribeFunc("frequency_cap.*", func(e *event.Event) {
+		logger.Write(logger.
+			UnlessError(
+				e.Error(), "operation failure",
+				log.LevelInfo, "operation success",
+			).
+			Data(map[string]any{
+				"frequency_cap.operation.name": e.GetData("operation_name"),
+				"frequency_cap.scope":          e.GetData("scope"),
+			}),
+		)
+	})
+}
+
+func (provider *FrequencyCapProvider) Shutdown(shutdownCtx context.Context) {
+}
+
+// declareCap binds a cap configured by the "scope" section of the config
+func (provider *FrequencyCapProvider) declareCap(scope string, alias string) {
+	container.BindSingletonAlias(alias, func(ctx context.Context) any {
+		config := container.MustResolve[config_reader.ConfigReader]()
+		connections := container.MustResolve[*facade.ConnectionProvider]()
+		limit := config.GetInt("frequency_cap", scope+".limit")
+		window := config.GetInt("frequency_cap", scope+".window_hours")
+		return redis.NewRedisFrequencyCap(
+			connections.GetRedisClient(),
+			scope,
+			limit,
+			time.Duration(window)*time.Hour,
+		)
+	})
+}
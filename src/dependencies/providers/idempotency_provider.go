@@ -0,0 +1,71 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"duolingo/libraries/config_reader"
+	facade "duolingo/libraries/connection_manager/facade"
+	"duolingo/libraries/idempotency/drivers/redis"
+	"duolingo/libraries/telemetry/otel_wrapper/log"
+	"duolingo/libraries/telemetry/otel_wrapper/trace"
+
+	container "duolingo/libraries/dependencies_container"
+	event "duolingo/libraries/events"
+	events "duolingo/libraries/events/facade"
+
+	"go.opentelemetry.io/otel/attribute"
+	otlptrace "go.opentelemetry.io/otel/trace"
+)
+
+type IdempotencyProvider struct {
+}
+
+func (provider *IdempotencyProvider) Bootstrap(bootstrapCtx context.Context, scope string) {
+	tracer := container.MustResolve[*trace.TraceManager]()
+	logger := container.MustResolve[*log.Logger]()
+
+	/* Declare Idempotency Stores */
+
+	provider.declareStore("message_input", "message_input_idempotency_store")
+
+	/* Tracing Instrumentation */
+
+	tracer.Decorate("idempotency.*", func(
+		span otlptrace.Span,
+		data trace.DataBag,
+	) {
+		span.SetAttributes(
+			attribute.String("idempotency.operation.name", data.Get("operation_name")),
+		)
+	})
+
+	/* Logs Instrumentation */
+
+	events.SubscribeFunc("idempotency.*", func(e *event.Event) {
+		logger.Write(logger.
+			UnlessError(
+				e.Error(), "operation failure",
+				log.LevelInfo, "operation success",
+			).
+			Data(map[string]any{
+				"idempotency.operation.name": e.GetData("operation_name"),
+			}),
+		)
+	})
+}
+
+func (provider *IdempotencyProvider) Shutdown(shutdownCtx context.Context) {
+}
+
+func (provider *IdempotencyProvider) declareStore(scope string, alias string) {
+	container.BindSingletonAlias(alias, func(ctx context.Context) any {
+		config := container.MustResolve[config_reader.ConfigReader]()
+		connections := container.MustResolve[*facade.ConnectionProvider]()
+		reservationTTL := config.GetInt("idempotency", "reservation_ttl_ms")
+		recordTTL := config.GetInt("idempotency", "record_ttl_hours")
+		return redis.NewRedisIdempotencyStore(connections.GetRedisClient(), scope).
+			SetReservationTTL(time.Duration(reservationTTL) * time.Millisecond).
+			SetRecordTTL(time.Duration(recordTTL) * time.Hour)
+	})
+}
@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"duolingo/libraries/config_reader"
+	"duolingo/libraries/connection_manager/facade"
+	"duolingo/repositories/message_status_repository/drivers/redis"
+	status_repo "duolingo/repositories/message_status_repository/external"
+
+	"duolingo/libraries/telemetry/otel_wrapper/log"
+	"duolingo/libraries/telemetry/otel_wrapper/trace"
+
+	container "duolingo/libraries/dependencies_container"
+	event "duolingo/libraries/events"
+	events "duolingo/libraries/events/facade"
+
+	"go.opentelemetry.io/otel/attribute"
+	otlptrace "go.opentelemetry.io/otel/trace"
+)
+
+type MessageStatusRepoProvider struct {
+}
+
+func (provider *MessageStatusRepoProvider) Shutdown(shutdownCtx context.Context) {
+}
+
+func (provider *MessageStatusRepoProvider) Bootstrap(bootstrapCtx context.Context, scope string) {
+
+	tracer := container.MustResolve[*trace.TraceManager]()
+	logger := container.MustResolve[*log.Logger]()
+
+	/* Register Repository */
+
+	provider.registerRedisMessageStatusRepo()
+
+	/* Tracing Instrumentation */
+
+	tracer.Decorate("message_status_repo.*", func(
+		span otlptrace.Span,
+		data trace.DataBag,
+	) {
+		span.SetAttributes(
+			attribute.String("database.system.name", "redis"),
+			attribute.String("db.operation.name", data.Get("db_operation")),
+			attribute.String("message_status_repo.operation.name", data.Get("operation_name")),
+		)
+	})
+
+	/* Logs Instrumentation */
+
+	events.SubscribeFunc("message_status_repo.*", func(e *event.Event) {
+		logger.Write(logger.
+			UnlessError(
+				e.Error(), "operation failure",
+				log.LevelInfo, "operation success",
+			).
+			Data(map[string]any{
+				"database.system.name":               "redis",
+				"db.operation.name":                  e.GetData("db_operation"),
+				"message_status_repo.operation.name": e.GetData("operation_name"),
+			}),
+		)
+	})
+}
+
+func (provider *MessageStatusRepoProvider) registerRedisMessageStatusRepo() {
+	container.BindSingleton[status_repo.MessageStatusRepository](func(ctx context.Context) any {
+		config := container.MustResolve[config_reader.ConfigReader]()
+		connections := container.MustResolve[*facade.ConnectionProvider]()
+		retention := time.Duration(config.GetInt("message_status", "retention_hours")) * time.Hour
+		return redis.NewMessageStatusRepo(connections.GetRedisClient(), retention)
+	})
+}
@@ -0,0 +1,67 @@
+package providers
+
+import (
+	"context"
+
+	container "duolingo/libraries/dependencies_container"
+	event "duolingo/libraries/events"
+	events "duolingo/libraries/events/facade"
+	"duolingo/libraries/telemetry/otel_wrapper/log"
+	"duolingo/libraries/telemetry/otel_wrapper/trace"
+	dist "duolingo/libraries/work_distributor"
+	status_repo "duolingo/repositories/message_status_repository/external"
+	"duolingo/services/message_status_service"
+
+	"go.opentelemetry.io/otel/attribute"
+	otlptrace "go.opentelemetry.io/otel/trace"
+)
+
+type MessageStatusServiceProvider struct {
+}
+
+func (provider *MessageStatusServiceProvider) Shutdown(shutdownCtx context.Context) {
+}
+
+func (provider *MessageStatusServiceProvider) Bootstrap(bootstrapCtx context.Context, scope string) {
+
+	tracer := container.MustResolve[*trace.TraceManager]()
+	logger := container.MustResolve[*log.Logger]()
+
+	/* Register Message Status Service */
+
+	provider.registerMessageStatusService()
+
+	/* Tracing Instrumentation */
+
+	tracer.Decorate("message_status_service.*", func(
+		span otlptrace.Span,
+		data trace.DataBag,
+	) {
+		span.SetAttributes(
+			attribute.String("message_status_service.operation.name", data.Get("operation_name")),
+		)
+	})
+
+	/* Logs Instrumentation */
+
+	events.SubscribeFunc("message_status_service.*", func(e *event.Event) {
+		logger.Write(logger.
+			UnlessError(
+				e.Error(), "operation failure",
+				log.LevelInfo, "operation success",
+			).
+			Data(map[string]any{
+				"message_status_service.operation.name": e.GetData("operation_name"),
+			}),
+		)
+	})
+}
+
+func (provider *MessageStatusServiceProvider) registerMessageStatusService() {
+	container.BindSingleton[*message_status_service.MessageStatusService](func(ctx context.Context) any {
+		return message_status_service.NewMessageStatusService(
+			container.MustResolve[status_repo.MessageStatusRepository](),
+			container.MustResolve[*dist.WorkDistributor](),
+		)
+	})
+}
@@ -0,0 +1,81 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"duolingo/libraries/config_reader"
+	"duolingo/libraries/connection_manager/facade"
+	"duolingo/repositories/outbox_repository/drivers/mongodb"
+	outbox_repo "duolingo/repositories/outbox_repository/external"
+
+	"duolingo/libraries/telemetry/otel_wrapper/log"
+	"duolingo/libraries/telemetry/otel_wrapper/trace"
+
+	container "duolingo/libraries/dependencies_container"
+	event "duolingo/libraries/events"
+	events "duolingo/libraries/events/facade"
+
+	"go.opentelemetry.io/otel/attribute"
+	otlptrace "go.opentelemetry.io/otel/trace"
+)
+
+type OutboxRepoProvider struct {
+}
+
+func (provider *OutboxRepoProvider) Shutdown(shutdownCtx context.Context) {
+}
+
+func (provider *OutboxRepoProvider) Bootstrap(bootstrapCtx context.Context, scope string) {
+
+	tracer := container.MustResolve[*trace.TraceManager]()
+	logger := container.MustResolve[*log.Logger]()
+
+	/* Register Repository */
+
+	provider.registerMongoDBOutboxRepo()
+
+	/* Tracing Instrumentation */
+
+	tracer.Decorate("outbox_repo.*", func(
+		span otlptrace.Span,
+		data trace.DataBag,
+	) {
+		span.SetAttributes(
+			attribute.String("database.system.name", "mongodb"),
+			attribute.String("database.collection.name", "message_outbox"),
+			attribute.String("db.operation.name", data.Get("db_operation")),
+			attribute.String("outbox_repo.operation.name", data.Get("operation_name")),
+		)
+	})
+
+	/* Logs Instrumentation */
+
+	events.SubscribeFunc("outbox_repo.*", func(e *event.Event) {
+		logger.Write(logger.
+			UnlessError(
+				e.Error(), "operation failure",
+				log.LevelInfo, "operation success",
+			).
+			Data(map[string]any{
+				"database.system.name":       "mongodb",
+				"database.collection.name":   "message_outbox",
+				"db.operation.name":          e.GetData("db_operation"),
+				"outbox_repo.operation.name": e.GetData("operation_name"),
+			}),
+		)
+	})
+}
+
+func (provider *OutboxRepoProvider) registerMongoDBOutboxRepo() {
+	container.BindSingleton[outbox_repo.OutboxRepository](func(ctx context.Context) any {
+		config := container.MustResolve[config_reader.ConfigReader]()
+		connections := container.MustResolve[*facade.ConnectionProvider]()
+		retention := time.Duration(config.GetInt("outbox", "retention_hours")) * time.Hour
+		repo := mongodb.NewOutboxRepo(connections.GetMongoClient(), "duolingo", "message_outbox", retention)
+		// the failure is reported by the repository events, the
+		// outbox still works without the indexes, only slower
+		repo.CreateIndexes(ctx)
+		return repo
+	})
+}
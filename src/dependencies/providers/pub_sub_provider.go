@@ -25,15 +25,26 @@ func (provider *PubSubProvider) Bootstrap(bootstrapCtx context.Context, scope st
 
 	/* Declare publishers and subscribers */
 
+	// Only one of the notification builders creates the batch job for each
+	// message input, while every builder is notified of the jobs to work on.
 	provider.declareTopic(
 		"message_inputs",
 		"message_input_publisher",
 		"message_input_subscriber",
+		"noti_builders",
 	)
 	provider.declareTopic(
 		"noti_builder_jobs",
 		"noti_builder_jobs_publisher",
 		"noti_builder_jobs_subscriber",
+		"",
+	)
+	// Every sender drops the buffered notifications of the canceled messages
+	provider.declareTopic(
+		"message_cancellations",
+		"message_cancellation_publisher",
+		"message_cancellation_subscriber",
+		"",
 	)
 
 	/* Tracing Instrumentation */
@@ -92,10 +103,13 @@ func (provider *PubSubProvider) Bootstrap(bootstrapCtx context.Context, scope st
 func (provider *PubSubProvider) Shutdown(shutdownCtx context.Context) {
 }
 
+// Subscribers with an empty "subscriberGroup" receive every message of the topic,
+// otherwise they compete for the messages with the other members of the group.
 func (provider *PubSubProvider) declareTopic(
 	topicName string,
 	publisherName string,
 	subscriberName string,
+	subscriberGroup string,
 ) {
 	connections := container.MustResolve[*facade.ConnectionProvider]()
 
@@ -110,6 +124,7 @@ func (provider *PubSubProvider) declareTopic(
 
 	container.BindSingletonAlias(subscriberName, func(ctx context.Context) any {
 		subscriber := pub_sub.NewSubscriber(connections.GetRabbitMQClient())
+		subscriber.SetGroup(subscriberGroup)
 		subscriber.SetMainTopic(topicName)
 		if subscribeErr := subscriber.SubscribeMainTopic(ctx); subscribeErr != nil {
 			panic(fmt.Errorf("failed to subscribe topic %v with error: %v", topicName, subscribeErr))
@@ -3,6 +3,7 @@ package providers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"duolingo/apps/push_sender/server/test/fakes"
 	"duolingo/libraries/config_reader"
@@ -10,7 +11,11 @@ import (
 	event "duolingo/libraries/events"
 	events "duolingo/libraries/events/facade"
 	push_noti "duolingo/libraries/push_notification"
+	apns "duolingo/libraries/push_notification/drivers/apns"
 	driver "duolingo/libraries/push_notification/drivers/firebase"
+	"duolingo/libraries/push_notification/drivers/routing"
+	"duolingo/libraries/push_notification/drivers/webpush"
+	"duolingo/libraries/push_notification/message"
 	"duolingo/libraries/telemetry/otel_wrapper/log"
 	"duolingo/libraries/telemetry/otel_wrapper/trace"
 
@@ -31,7 +36,7 @@ func (provider *PushServiceProvider) Bootstrap(bootstrapCtx context.Context, sco
 	if scope == "test" {
 		provider.registerFakePushServiceProvider()
 	} else {
-		provider.registerFirebasePushServiceProvider()
+		provider.registerRoutingPushServiceProvider()
 	}
 
 	/* Tracing Instrumentation */
@@ -41,7 +46,7 @@ func (provider *PushServiceProvider) Bootstrap(bootstrapCtx context.Context, sco
 		data trace.DataBag,
 	) {
 		span.SetAttributes(
-			attribute.String("push_noti.push_service.driver", "firebase"),
+			attribute.String("push_noti.push_service.driver", data.Get("driver")),
 			attribute.String("push_noti.push_service.operation", "send_multicast"),
 			attribute.String("push_noti.multicast.platforms", data.Get("platforms")),
 			attribute.String("push_noti.multicast.response.devices_total", data.Get("devices_total")),
@@ -59,7 +64,7 @@ func (provider *PushServiceProvider) Bootstrap(bootstrapCtx context.Context, sco
 				log.LevelInfo, "push notifications sent",
 			).
 			Data(map[string]any{
-				"driver":        "firebase",
+				"driver":        e.GetData("driver"),
 				"operation":     "send_multicast",
 				"platforms":     e.GetData("platforms"),
 				"devices_total": e.GetData("devices_total"),
@@ -68,6 +73,18 @@ func (provider *PushServiceProvider) Bootstrap(bootstrapCtx context.Context, sco
 			}),
 		)
 	})
+
+	events.SubscribeFunc("push_noti.push_service.failover", func(e *event.Event) {
+		logger.Write(logger.
+			Info("push service provider skipped").
+			Data(map[string]any{
+				"provider":      e.GetData("provider"),
+				"reason":        e.GetData("reason"),
+				"platforms":     e.GetData("platforms"),
+				"devices_total": e.GetData("devices_total"),
+			}),
+		)
+	})
 }
 
 func (provider *PushServiceProvider) Shutdown(shutdownCtx context.Context) {
@@ -79,21 +96,109 @@ func (provider *PushServiceProvider) registerFakePushServiceProvider() {
 	})
 }
 
-func (provider *PushServiceProvider) registerFirebasePushServiceProvider() {
+// registerRoutingPushServiceProvider routes each platform to its providers,
+// only the providers used by the routes are created.
+func (provider *PushServiceProvider) registerRoutingPushServiceProvider() {
 	container.BindSingleton[push_noti.PushService](func(ctx context.Context) any {
 		config := container.MustResolve[config_reader.ConfigReader]()
-		cred := config.Get("firebase", "credentials")
-
-		factory, factoryErr := driver.NewFirebasePushNotiFactory(ctx, cred)
-		if factoryErr != nil {
-			panic(fmt.Errorf("failed to setup push service with error: %v ", factoryErr))
+		platforms := config.GetArr("push_service", "routes.#.platform")
+		primaries := config.GetArr("push_service", "routes.#.primary")
+		secondaries := config.GetArr("push_service", "routes.#.secondary")
+		if len(primaries) != len(platforms) || len(secondaries) != len(platforms) {
+			panic(fmt.Errorf("push service routes must specify platform, primary and secondary"))
 		}
 
-		service, serviceErr := factory.CreatePushService()
-		if serviceErr != nil {
-			panic(fmt.Errorf("failed to setup push service with error: %v ", serviceErr))
+		service := routing.NewRoutingPushService(
+			config.GetInt("push_service", "circuit_breaker.failure_threshold"),
+			time.Duration(config.GetInt("push_service", "circuit_breaker.open_seconds"))*time.Second,
+		)
+		// a provider failing to set up (e.g. its credentials are not configured yet)
+		// is left out of the routes, rather than failing the whole sender
+		logger := container.MustResolve[*log.Logger]()
+		attempted := map[string]bool{}
+		available := map[string]bool{}
+		for _, name := range append(primaries, secondaries...) {
+			if name == "" || attempted[name] {
+				continue
+			}
+			attempted[name] = true
+			pushService, err := provider.createPushService(ctx, name)
+			if err != nil {
+				logger.Write(logger.Error("push service provider unavailable", err).
+					Data(map[string]any{"provider": name}))
+				continue
+			}
+			service.AddProvider(name, pushService)
+			available[name] = true
+		}
+		for i := range platforms {
+			primary, secondary := primaries[i], secondaries[i]
+			if !available[secondary] {
+				secondary = ""
+			}
+			if !available[primary] {
+				primary, secondary = secondary, ""
+			}
+			if primary == "" {
+				logger.Write(logger.Info("push service route skipped as no provider is available").
+					Data(map[string]any{"platform": platforms[i]}))
+				continue
+			}
+			err := service.AddRoute(message.Platform(platforms[i]), primary, secondary)
+			if err != nil {
+				panic(fmt.Errorf("failed to setup push service route with error: %v ", err))
+			}
 		}
 
 		return service
 	})
 }
+
+// createPushService creates the provider by its driver name, an unknown driver
+// is a misconfiguration, it panics.
+func (provider *PushServiceProvider) createPushService(ctx context.Context, name string) (push_noti.PushService, error) {
+	var factory push_noti.PushNotiFactory
+	var err error
+	switch name {
+	case "firebase":
+		factory, err = provider.createFirebaseFactory(ctx)
+	case "apns":
+		factory, err = provider.createAPNsFactory()
+	case "webpush":
+		factory, err = provider.createWebPushFactory()
+	default:
+		panic(fmt.Errorf("unknown push service driver %v", name))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return factory.CreatePushService()
+}
+
+func (provider *PushServiceProvider) createFirebaseFactory(ctx context.Context) (push_noti.PushNotiFactory, error) {
+	config := container.MustResolve[config_reader.ConfigReader]()
+	return driver.NewFirebasePushNotiFactory(ctx, config.Get("firebase", "credentials"))
+}
+
+func (provider *PushServiceProvider) createAPNsFactory() (push_noti.PushNotiFactory, error) {
+	config := container.MustResolve[config_reader.ConfigReader]()
+	factory, err := apns.NewAPNsPushNotiFactory(
+		config.Get("apns", "key"),
+		config.Get("apns", "key_id"),
+		config.Get("apns", "team_id"),
+		config.Get("apns", "topic"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return factory.SetEndpoint(config.Get("apns", "endpoint")), nil
+}
+
+func (provider *PushServiceProvider) createWebPushFactory() (push_noti.PushNotiFactory, error) {
+	config := container.MustResolve[config_reader.ConfigReader]()
+	return webpush.NewWebPushNotiFactory(
+		config.Get("webpush", "vapid_public_key"),
+		config.Get("webpush", "vapid_private_key"),
+		config.Get("webpush", "subject"),
+	)
+}
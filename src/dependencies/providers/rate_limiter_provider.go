@@ -0,0 +1,72 @@
+package providers
+
+import (
+	"context"
+
+	"duolingo/libraries/config_reader"
+	facade "duolingo/libraries/connection_manager/facade"
+	"duolingo/libraries/rate_limiter/drivers/redis"
+	"duolingo/libraries/telemetry/otel_wrapper/log"
+	"duolingo/libraries/telemetry/otel_wrapper/trace"
+
+	container "duolingo/libraries/dependencies_container"
+	event "duolingo/libraries/events"
+	events "duolingo/libraries/events/facade"
+
+	"go.opentelemetry.io/otel/attribute"
+	otlptrace "go.opentelemetry.io/otel/trace"
+)
+
+type RateLimiterProvider struct {
+}
+
+func (provider *RateLimiterProvider) Bootstrap(bootstrapCtx context.Context, scope string) {
+	tracer := container.MustResolve[*trace.TraceManager]()
+	logger := container.MustResolve[*log.Logger]()
+
+	/* Declare Rate Limiters */
+
+	provider.declareLimiter("push_notifications", "push_notifications_rate_limiter")
+
+	/* Tracing Instrumentation */
+
+	tracer.Decorate("rate_limiter.*", func(
+		span otlptrace.Span,
+		data trace.DataBag,
+	) {
+		span.SetAttributes(
+			attribute.String("rate_limiter.operation.name", data.Get("operation_name")),
+			attribute.String("rate_limiter.scope", data.Get("scope")),
+		)
+	})
+
+	/* Logs Instrumentation */
+
+	events.SubscribeFunc("rate_limiter.*", func(e *event.Event) {
+		logger.Write(logger.
+			UnlessError(
+				e.Error(), "operation failure",
+				log.LevelInfo, "operation success",
+			).
+			Data(map[string]any{
+				"rate_limiter.operation.name": e.GetData("operation_name"),
+				"rate_limiter.scope":          e.GetData("scope"),
+			}),
+		)
+	})
+}
+
+func (provider *RateLimiterProvider) Shutdown(shutdownCtx context.Context) {
+}
+
+// declareLimiter binds a limiter configured by the "scope" section of the config
+func (provider *RateLimiterProvider) declareLimiter(scope string, alias string) {
+	container.BindSingletonAlias(alias, func(ctx context.Context) any {
+		config := container.MustResolve[config_reader.ConfigReader]()
+		connections := container.MustResolve[*facade.ConnectionProvider]()
+		rate := config.GetInt("rate_limiter", scope+".rate_per_second")
+		burst := config.GetInt("rate_limiter", scope+".burst")
+		return redis.NewRedisRateLimiter(connections.GetRedisClient(), scope, rate).
+			SetBurst(burst)
+	})
+}
@@ -32,6 +32,21 @@ func (provider *TaskQueueProvider) Bootstrap(bootstrapCtx context.Context, scope
 		"push_notifications_producer",
 		"push_notifications_consumer",
 	)
+	// The tokens rejected permanently by the push service, the builders
+	// remove their devices so that they are not targeted anymore.
+	provider.declareTaskQueue(
+		bootstrapCtx,
+		"invalid_device_tokens",
+		"invalid_device_tokens_producer",
+		"invalid_device_tokens_consumer",
+	)
+	// The tokens not delivered after the last retry, kept for inspection
+	provider.declareTaskQueue(
+		bootstrapCtx,
+		"push_notifications_dead_letters",
+		"push_notifications_dead_letters_producer",
+		"push_notifications_dead_letters_consumer",
+	)
 
 	/* Tracing Instrumentation */
 
@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"duolingo/libraries/config_reader"
+	facade "duolingo/libraries/connection_manager/facade"
+	"duolingo/libraries/task_scheduler/drivers/redis"
+	"duolingo/libraries/telemetry/otel_wrapper/log"
+	"duolingo/libraries/telemetry/otel_wrapper/trace"
+
+	container "duolingo/libraries/dependencies_container"
+	event "duolingo/libraries/events"
+	events "duolingo/libraries/events/facade"
+
+	"go.opentelemetry.io/otel/attribute"
+	otlptrace "go.opentelemetry.io/otel/trace"
+)
+
+type TaskSchedulerProvider struct {
+}
+
+func (provider *TaskSchedulerProvider) Bootstrap(bootstrapCtx context.Context, scope string) {
+	tracer := container.MustResolve[*trace.TraceManager]()
+	logger := container.MustResolve[*log.Logger]()
+
+	/* Declare Task Schedulers */
+
+	provider.declareScheduler("scheduled_message_inputs", "message_input_scheduler")
+	provider.declareScheduler("deferred_push_notifications", "deferred_push_notifications_scheduler")
+
+	/* Tracing Instrumentation */
+
+	tracer.Decorate("task_scheduler.*", func(
+		span otlptrace.Span,
+		data trace.DataBag,
+	) {
+		span.SetAttributes(
+			attribute.String("task_scheduler.operation.name", data.Get("operation_name")),
+		)
+	})
+
+	/* Logs Instrumentation */
+
+	events.SubscribeFunc("task_scheduler.*", func(e *event.Event) {
+		logger.Write(logger.
+			UnlessError(
+				e.Error(), "operation failure",
+				log.LevelInfo, "operation success",
+			).
+			Data(map[string]any{
+				"task_scheduler.operation.name": e.GetData("operation_name"),
+			}),
+		)
+	})
+}
+
+func (provider *TaskSchedulerProvider) Shutdown(shutdownCtx context.Context) {
+}
+
+func (provider *TaskSchedulerProvider) declareScheduler(schedulerName string, alias string) {
+	container.BindSingletonAlias(alias, func(ctx context.Context) any {
+		config := container.MustResolve[config_reader.ConfigReader]()
+		connections := container.MustResolve[*facade.ConnectionProvider]()
+		pollInterval := config.GetInt("task_scheduler", "poll_interval_ms")
+		claimTimeout := config.GetInt("task_scheduler", "claim_timeout_ms")
+		return redis.NewRedisTaskScheduler(connections.GetRedisClient(), schedulerName).
+			SetPollInterval(time.Duration(pollInterval) * time.Millisecond).
+			SetClaimTimeout(time.Duration(claimTimeout) * time.Millisecond).
+			SetClaimLimit(config.GetInt64("task_scheduler", "claim_limit"))
+	})
+}
@@ -37,6 +37,7 @@ func (provider *TraceManagerProvider) Bootstrap(bootstrapCtx context.Context, sc
 				attribute.String("url.full", data.Get("full_url")),
 				attribute.String("http.response.status_code", data.Get("status_code")),
 				attribute.String("user_agent.original", data.Get("user_agent")),
+				attribute.String("enduser.id", data.Get("principal")),
 			)
 		})
 
@@ -2,6 +2,9 @@ package providers
 
 import (
 	"context"
+	"time"
+
+	"duolingo/libraries/config_reader"
 	"duolingo/libraries/connection_manager/facade"
 	"duolingo/repositories/user_repository/drivers/mongodb"
 	user_repo "duolingo/repositories/user_repository/external"
@@ -72,6 +75,15 @@ func (provider *UserRepoProvider) registerMongoDBUserRepo() {
 
 	container.BindSingleton[user_repo.UserRepository](func(ctx context.Context) any {
 		factory := container.MustResolve[user_repo.UserRepoFactory]()
-		return factory.MakeUserRepo()
+		repo := factory.MakeUserRepo()
+		if mongoRepo, ok := repo.(*mongodb.UserRepo); ok {
+			config := container.MustResolve[config_reader.ConfigReader]()
+			snapshotTimeout := config.GetInt("noti_builder", "audience_snapshot_timeout_ms")
+			mongoRepo.SetSnapshotTimeout(time.Duration(snapshotTimeout) * time.Millisecond)
+			// the failure is reported by the repository events, the
+			// devices are still listed without the indexes, only slower
+			mongoRepo.CreateIndexes(ctx)
+		}
+		return repo
 	})
 }
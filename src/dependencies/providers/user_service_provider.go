@@ -41,6 +41,16 @@ func (provider *UserServiceProvider) Bootstrap(bootstrapCtx context.Context, sco
 		)
 	})
 
+	tracer.Decorate("user_service.snapshot_devices_for_campaign", func(
+		span otlptrace.Span,
+		data trace.DataBag,
+	) {
+		span.SetAttributes(
+			attribute.String("user_service.snapshot.devices_total", data.Get("devices_total")),
+			attribute.String("user_service.snapshot.duplicate_tokens_total", data.Get("duplicate_tokens_total")),
+		)
+	})
+
 	/* Logs Instrumentation */
 
 	events.SubscribeFunc("user_service.*", func(e *event.Event) {
@@ -54,6 +64,19 @@ func (provider *UserServiceProvider) Bootstrap(bootstrapCtx context.Context, sco
 			}),
 		)
 	})
+
+	events.SubscribeFunc("user_service.snapshot_devices_for_campaign", func(e *event.Event) {
+		if e.Error() != nil || e.GetData("duplicate_tokens_total") == int64(0) {
+			return
+		}
+		logger.Write(logger.
+			Info("duplicate device tokens left out of the audience").
+			Data(map[string]any{
+				"devices_total":          e.GetData("devices_total"),
+				"duplicate_tokens_total": e.GetData("duplicate_tokens_total"),
+			}),
+		)
+	})
 }
 
 func (provider *UserServiceProvider) registerMongoDBUserService() {
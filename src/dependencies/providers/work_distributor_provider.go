@@ -2,6 +2,7 @@ package providers
 
 import (
 	"context"
+	"time"
 
 	"duolingo/libraries/config_reader"
 	facade "duolingo/libraries/connection_manager/facade"
@@ -62,9 +63,10 @@ func (provider *WorkDistributorProvider) registerRedisWorkDistributor() {
 	container.BindSingleton[*dist.WorkDistributor](func(ctx context.Context) any {
 		config := container.MustResolve[config_reader.ConfigReader]()
 		connections := container.MustResolve[*facade.ConnectionProvider]()
+		leaseDuration := config.GetInt("work_distributor", "assignment_lease_ms")
 		return redis.NewRedisWorkDistributor(
 			connections.GetRedisClient(),
 			config.GetInt64("work_distributor", "distribution_size"),
-		)
+		).SetLeaseDuration(time.Duration(leaseDuration) * time.Millisecond)
 	})
 }
@@ -0,0 +1,41 @@
+package backoff
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// ExponentialBackoff doubles the delay on each attempt, up to the max delay.
+// The jitter spreads the retries of the operations failed at the same time,
+// the delay is randomized between its half and itself.
+type ExponentialBackoff struct {
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	jitter    bool
+}
+
+func NewExponentialBackoff(baseDelay time.Duration, maxDelay time.Duration) *ExponentialBackoff {
+	return &ExponentialBackoff{
+		baseDelay: baseDelay,
+		maxDelay:  max(baseDelay, maxDelay),
+	}
+}
+
+func (backoff *ExponentialBackoff) SetJitter(jitter bool) *ExponentialBackoff {
+	backoff.jitter = jitter
+	return backoff
+}
+
+// Delay returns the wait before the retry of the failed attempt,
+// the attempts are counted from 1.
+func (backoff *ExponentialBackoff) Delay(attempt int) time.Duration {
+	delay := backoff.baseDelay
+	for i := 1; i < attempt && delay < backoff.maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, backoff.maxDelay)
+	if backoff.jitter && delay > 1 {
+		delay = delay/2 + rand.N(delay/2)
+	}
+	return delay
+}
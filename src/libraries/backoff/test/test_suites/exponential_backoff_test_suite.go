@@ -0,0 +1,38 @@
+package test_suites
+
+import (
+	"time"
+
+	"duolingo/libraries/backoff"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ExponentialBackoffTestSuite struct {
+	suite.Suite
+}
+
+func NewExponentialBackoffTestSuite() *ExponentialBackoffTestSuite {
+	return &ExponentialBackoffTestSuite{}
+}
+
+func (s *ExponentialBackoffTestSuite) Test_Delay() {
+	policy := backoff.NewExponentialBackoff(100*time.Millisecond, time.Second)
+
+	s.Assert().Equal(100*time.Millisecond, policy.Delay(0))
+	s.Assert().Equal(100*time.Millisecond, policy.Delay(1))
+	s.Assert().Equal(200*time.Millisecond, policy.Delay(2))
+	s.Assert().Equal(800*time.Millisecond, policy.Delay(4))
+	s.Assert().Equal(time.Second, policy.Delay(5))
+	s.Assert().Equal(time.Second, policy.Delay(100))
+}
+
+func (s *ExponentialBackoffTestSuite) Test_Delay_Jitter() {
+	policy := backoff.NewExponentialBackoff(100*time.Millisecond, time.Second).SetJitter(true)
+
+	for range 100 {
+		delay := policy.Delay(3)
+		s.Assert().GreaterOrEqual(delay, 200*time.Millisecond)
+		s.Assert().Less(delay, 400*time.Millisecond)
+	}
+}
@@ -71,6 +71,24 @@ func (gb *BufferGroup[K, T]) RemoveGroup(key K) {
 	grp.Stop()
 }
 
+// RemoveGroups removes the groups of the matched keys,
+// the items have not been flushed are discarded.
+func (gb *BufferGroup[K, T]) RemoveGroups(match func(key K) bool) {
+	gb.groupMu.Lock()
+	removed := []*Buffer[T]{}
+	for key := range gb.groups {
+		if match(key) {
+			removed = append(removed, gb.groups[key])
+			delete(gb.groups, key)
+		}
+	}
+	gb.groupMu.Unlock()
+
+	for i := range removed {
+		removed[i].Stop()
+	}
+}
+
 func (gb *BufferGroup[K, T]) Stop() {
 	gb.groupMu.Lock()
 	defer gb.groupMu.Unlock()
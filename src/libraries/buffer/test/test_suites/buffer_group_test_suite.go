@@ -112,3 +112,34 @@ func (s *BufferGroupTestSuite) Test_BufferGroup_Flush_Interval() {
 
 	wg.Wait()
 }
+
+func (s *BufferGroupTestSuite) Test_BufferGroup_RemoveGroups() {
+	grp := buffer.NewBufferGroup[string, string]()
+	defer grp.Stop()
+
+	var mu sync.Mutex
+	flushed := []string{}
+	grp.
+		SetInterval(10*time.Millisecond).
+		SetLimit(1000). // this amount ensure the flush trigger by interval
+		DeclareGroup(context.Background(), "grp_1").
+		DeclareGroup(context.Background(), "grp_2").
+		SetConsumeFunc(true, func(ctx context.Context, name string, items []string) {
+			mu.Lock()
+			defer mu.Unlock()
+			flushed = append(flushed, name)
+		})
+
+	grp.Write("grp_1", "test_item_1")
+	grp.Write("grp_2", "test_item_1")
+	grp.RemoveGroups(func(name string) bool {
+		return name == "grp_1"
+	})
+	grp.Write("grp_1", "test_item_2") // the group was removed, should be ignored
+
+	time.Sleep(30 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	s.Assert().Equal([]string{"grp_2"}, flushed)
+}
@@ -0,0 +1,107 @@
+package circuit_breaker
+
+import (
+	"sync"
+	"time"
+)
+
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half_open"
+)
+
+/*
+CircuitBreaker stops calling a failing dependency for a while.
+
+### Notions:
+ 1. The circuit opens after "failureThreshold" consecutive failures, the calls
+    are then rejected until "openDuration" has elapsed.
+ 2. Once elapsed, the circuit is half open: a single trial call is allowed,
+    its success closes the circuit and its failure opens it again.
+*/
+type CircuitBreaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	openDuration     time.Duration
+
+	state    State
+	failures int
+	openedAt time.Time
+	trialing bool
+}
+
+func NewCircuitBreaker(failureThreshold int, openDuration time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		failureThreshold: max(1, failureThreshold),
+		openDuration:     openDuration,
+		state:            StateClosed,
+	}
+}
+
+// Allow tells whether a call can be made, the caller must then report the
+// outcome of the call by RecordSuccess() or RecordFailure().
+func (breaker *CircuitBreaker) Allow() bool {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	switch breaker.state {
+	case StateOpen:
+		if time.Since(breaker.openedAt) < breaker.openDuration {
+			return false
+		}
+		breaker.state = StateHalfOpen
+		breaker.trialing = true
+		return true
+	case StateHalfOpen:
+		if breaker.trialing {
+			return false
+		}
+		breaker.trialing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (breaker *CircuitBreaker) RecordSuccess() {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	breaker.state = StateClosed
+	breaker.failures = 0
+	breaker.trialing = false
+}
+
+func (breaker *CircuitBreaker) RecordFailure() {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	breaker.failures++
+	if breaker.state == StateHalfOpen || breaker.failures >= breaker.failureThreshold {
+		breaker.state = StateOpen
+		breaker.openedAt = time.Now()
+		breaker.trialing = false
+	}
+}
+
+// State returns the state of the circuit, the open circuit whose duration has
+// elapsed is reported half open.
+func (breaker *CircuitBreaker) State() State {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	if breaker.state == StateOpen && time.Since(breaker.openedAt) >= breaker.openDuration {
+		return StateHalfOpen
+	}
+	return breaker.state
+}
+
+// RetryAfter returns the remaining time before the open circuit allows a
+// trial call, zero if the circuit is not open.
+func (breaker *CircuitBreaker) RetryAfter() time.Duration {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	if breaker.state != StateOpen {
+		return 0
+	}
+	return max(breaker.openDuration-time.Since(breaker.openedAt), 0)
+}
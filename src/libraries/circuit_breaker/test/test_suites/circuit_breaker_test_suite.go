@@ -0,0 +1,59 @@
+package test_suites
+
+import (
+	"time"
+
+	"duolingo/libraries/circuit_breaker"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CircuitBreakerTestSuite struct {
+	suite.Suite
+}
+
+func NewCircuitBreakerTestSuite() *CircuitBreakerTestSuite {
+	return &CircuitBreakerTestSuite{}
+}
+
+func (s *CircuitBreakerTestSuite) Test_Opens_After_Consecutive_Failures() {
+	breaker := circuit_breaker.NewCircuitBreaker(3, time.Minute)
+
+	breaker.RecordFailure()
+	breaker.RecordFailure()
+	breaker.RecordSuccess() // the success resets the failures
+	breaker.RecordFailure()
+	breaker.RecordFailure()
+	s.Assert().Equal(circuit_breaker.StateClosed, breaker.State())
+	s.Assert().True(breaker.Allow())
+
+	breaker.RecordFailure()
+	s.Assert().Equal(circuit_breaker.StateOpen, breaker.State())
+	s.Assert().False(breaker.Allow())
+	s.Assert().Greater(breaker.RetryAfter(), 59*time.Second)
+}
+
+func (s *CircuitBreakerTestSuite) Test_HalfOpen_Trial() {
+	breaker := circuit_breaker.NewCircuitBreaker(1, 10*time.Millisecond)
+
+	breaker.RecordFailure()
+	s.Assert().False(breaker.Allow())
+
+	time.Sleep(15 * time.Millisecond)
+	s.Assert().Equal(circuit_breaker.StateHalfOpen, breaker.State())
+	s.Assert().True(breaker.Allow())
+	s.Assert().False(breaker.Allow(), "a single trial call should be allowed")
+
+	// the failed trial opens the circuit again
+	breaker.RecordFailure()
+	s.Assert().Equal(circuit_breaker.StateOpen, breaker.State())
+	s.Assert().False(breaker.Allow())
+
+	time.Sleep(15 * time.Millisecond)
+	s.Assert().True(breaker.Allow())
+	breaker.RecordSuccess()
+	s.Assert().Equal(circuit_breaker.StateClosed, breaker.State())
+	s.Assert().True(breaker.Allow())
+	s.Assert().True(breaker.Allow())
+	s.Assert().Equal(time.Duration(0), breaker.RetryAfter())
+}
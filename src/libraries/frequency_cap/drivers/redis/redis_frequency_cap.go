@@ -0,0 +1,117 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	connection "duolingo/libraries/connection_manager/drivers/redis"
+	events "duolingo/libraries/events/facade"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+Each user has a sorted set of the messages counted towards the cap, scored by
+the time they were counted. The messages older than the window are removed
+before counting, so that the window slides rather than resets. The script
+returns the (1-based) indexes of the users still under their cap.
+*/
+var acquireScript = redis.NewScript(`
+	local now = redis.call("TIME")
+	local now_ms = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+	local limit = tonumber(ARGV[2])
+	local window = tonumber(ARGV[3])
+
+	local allowed = {}
+	for i, key in ipairs(KEYS) do
+		redis.call("ZREMRANGEBYSCORE", key, "-inf", now_ms - window)
+		if redis.call("ZSCORE", key, ARGV[1]) then
+			table.insert(allowed, i)
+		elseif redis.call("ZCARD", key) < limit then
+			redis.call("ZADD", key, now_ms, ARGV[1])
+			redis.call("PEXPIRE", key, window)
+			table.insert(allowed, i)
+		end
+	end
+	return allowed
+`)
+
+/*
+### Notions:
+ 1. A user receives at most "limit" messages of the scope within the
+    "window", the messages over the cap are suppressed.
+ 2. A message is counted as soon as it is acquired, even if its delivery
+    fails afterwards.
+*/
+type RedisFrequencyCap struct {
+	connection.RedisClient
+
+	scope  string
+	limit  int
+	window time.Duration
+}
+
+func NewRedisFrequencyCap(
+	client *connection.RedisClient,
+	scope string,
+	limit int,
+	window time.Duration,
+) *RedisFrequencyCap {
+	return &RedisFrequencyCap{
+		RedisClient: *client,
+		scope:       scope,
+		limit:       limit,
+		window:      window,
+	}
+}
+
+func (fc *RedisFrequencyCap) Acquire(
+	ctx context.Context,
+	messageId string,
+	userIds []string,
+) ([]string, error) {
+	var indexes []int64
+	var err error
+
+	evt := events.Start(ctx, "frequency_cap.redis.acquire", map[string]any{
+		"operation_name": "acquire",
+		"scope":          fc.scope,
+	})
+	defer events.End(evt, true, err, nil)
+
+	if len(userIds) == 0 {
+		return []string{}, nil
+	}
+	keys := make([]string, len(userIds))
+	for i := range userIds {
+		keys[i] = fc.userKey(userIds[i])
+	}
+	err = fc.ExecuteClosure(evt.Context(), fc.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		result, scriptErr := acquireScript.Run(
+			timeoutCtx,
+			rdb,
+			keys,
+			messageId,
+			fc.limit,
+			fc.window.Milliseconds(),
+		).Int64Slice()
+		indexes = result
+		return scriptErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make([]string, len(indexes))
+	for i := range indexes {
+		allowed[i] = userIds[indexes[i]-1]
+	}
+	return allowed, nil
+}
+
+func (fc *RedisFrequencyCap) userKey(userId string) string {
+	return "frequency_cap:" + fc.scope + ":" + userId
+}
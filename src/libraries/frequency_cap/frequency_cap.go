@@ -0,0 +1,12 @@
+package frequency_cap
+
+import (
+	"context"
+)
+
+type FrequencyCap interface {
+	// Acquire counts the message towards the cap of each user, it returns the
+	// users still under their cap. A message already counted for a user is
+	// allowed again, so that a redelivered message is not counted twice.
+	Acquire(ctx context.Context, messageId string, userIds []string) ([]string, error)
+}
@@ -0,0 +1,55 @@
+package test_suites
+
+import (
+	"context"
+
+	"duolingo/libraries/frequency_cap"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type FrequencyCapTestSuite struct {
+	suite.Suite
+	freqCap frequency_cap.FrequencyCap
+	limit   int
+}
+
+// NewFrequencyCapTestSuite expects a freqCap whose window is longer than the tests
+func NewFrequencyCapTestSuite(freqCap frequency_cap.FrequencyCap, limit int) *FrequencyCapTestSuite {
+	return &FrequencyCapTestSuite{
+		freqCap: freqCap,
+		limit:   limit,
+	}
+}
+
+func (s *FrequencyCapTestSuite) Test_Acquire_Limit() {
+	capped, other := uuid.NewString(), uuid.NewString()
+
+	for range s.limit {
+		allowed, err := s.freqCap.Acquire(context.Background(), uuid.NewString(), []string{capped})
+		s.Assert().NoError(err)
+		s.Assert().Equal([]string{capped}, allowed)
+	}
+	allowed, err := s.freqCap.Acquire(context.Background(), uuid.NewString(), []string{capped, other})
+
+	s.Assert().NoError(err)
+	s.Assert().Equal([]string{other}, allowed)
+}
+
+func (s *FrequencyCapTestSuite) Test_Acquire_Same_Message() {
+	userId, messageId := uuid.NewString(), uuid.NewString()
+
+	for range s.limit + 1 {
+		allowed, err := s.freqCap.Acquire(context.Background(), messageId, []string{userId})
+		s.Assert().NoError(err)
+		s.Assert().Equal([]string{userId}, allowed)
+	}
+}
+
+func (s *FrequencyCapTestSuite) Test_Acquire_Empty() {
+	allowed, err := s.freqCap.Acquire(context.Background(), uuid.NewString(), []string{})
+
+	s.Assert().NoError(err)
+	s.Assert().Empty(allowed)
+}
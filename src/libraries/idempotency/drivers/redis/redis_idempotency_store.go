@@ -0,0 +1,156 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	connection "duolingo/libraries/connection_manager/drivers/redis"
+	events "duolingo/libraries/events/facade"
+	"duolingo/libraries/idempotency"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var reserveKeyScript = redis.NewScript(`
+	local record = redis.call("GET", KEYS[1])
+	if record then
+		return record
+	end
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return false
+`)
+
+/*
+### Notions:
+ 1. The reservation expires after "reservationTTL", so that the key is not
+    blocked forever if the request has never completed (e.g. crashed).
+ 2. The completed records expire after "recordTTL", the retries arrived after
+    the expiration are treated as new requests.
+*/
+type RedisIdempotencyStore struct {
+	connection.RedisClient
+
+	scope          string
+	reservationTTL time.Duration
+	recordTTL      time.Duration
+}
+
+func NewRedisIdempotencyStore(client *connection.RedisClient, scope string) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{
+		RedisClient:    *client,
+		scope:          scope,
+		reservationTTL: 30 * time.Second,
+		recordTTL:      24 * time.Hour,
+	}
+}
+
+func (store *RedisIdempotencyStore) SetReservationTTL(ttl time.Duration) *RedisIdempotencyStore {
+	store.reservationTTL = ttl
+	return store
+}
+
+func (store *RedisIdempotencyStore) SetRecordTTL(ttl time.Duration) *RedisIdempotencyStore {
+	store.recordTTL = ttl
+	return store
+}
+
+func (store *RedisIdempotencyStore) Begin(
+	ctx context.Context,
+	key string,
+	fingerprint string,
+) (*idempotency.IdempotencyRecord, error) {
+	var existing string
+	var err error
+
+	evt := events.Start(ctx, "idempotency.redis.begin", map[string]any{
+		"operation_name": "begin",
+	})
+	defer events.End(evt, true, err, nil)
+
+	reservation, _ := json.Marshal(idempotency.NewIdempotencyRecord(key, fingerprint))
+	err = store.ExecuteClosure(evt.Context(), store.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		result, scriptErr := reserveKeyScript.Run(
+			timeoutCtx,
+			rdb,
+			[]string{store.recordKey(key)},
+			string(reservation),
+			store.reservationTTL.Milliseconds(),
+		).Text()
+		existing = result
+		return scriptErr
+	})
+	if err == redis.Nil {
+		// the key is reserved
+		err = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record := new(idempotency.IdempotencyRecord)
+	if err = json.Unmarshal([]byte(existing), record); err != nil {
+		return nil, err
+	}
+	if record.Fingerprint != fingerprint {
+		err = idempotency.ErrFingerprintMismatch
+		return nil, err
+	}
+	if !record.Completed {
+		err = idempotency.ErrRequestInProgress
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (store *RedisIdempotencyStore) Complete(
+	ctx context.Context,
+	record *idempotency.IdempotencyRecord,
+) error {
+	var err error
+
+	evt := events.Start(ctx, "idempotency.redis.complete", map[string]any{
+		"operation_name": "complete",
+	})
+	defer events.End(evt, true, err, nil)
+
+	marshaled, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	err = store.ExecuteClosure(evt.Context(), store.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		return rdb.Set(timeoutCtx, store.recordKey(record.Key), string(marshaled), store.recordTTL).Err()
+	})
+
+	return err
+}
+
+func (store *RedisIdempotencyStore) Release(ctx context.Context, key string) error {
+	var err error
+
+	evt := events.Start(ctx, "idempotency.redis.release", map[string]any{
+		"operation_name": "release",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = store.ExecuteClosure(evt.Context(), store.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		return rdb.Del(timeoutCtx, store.recordKey(key)).Err()
+	})
+
+	return err
+}
+
+func (store *RedisIdempotencyStore) recordKey(key string) string {
+	return "idempotency:" + store.scope + ":" + key
+}
@@ -0,0 +1,33 @@
+package idempotency
+
+import (
+	"errors"
+)
+
+var (
+	ErrRequestInProgress   = errors.New("a request with the same idempotency key is in progress")
+	ErrFingerprintMismatch = errors.New("idempotency key has been used with a different request")
+)
+
+// IdempotencyRecord is the first result of the requests sharing the same key,
+// the fingerprint identifies the request content (e.g. a hash of the body).
+type IdempotencyRecord struct {
+	Key         string `json:"key"`
+	Fingerprint string `json:"fingerprint"`
+	Completed   bool   `json:"completed"`
+	Status      int    `json:"status"`
+	Body        []byte `json:"body"`
+}
+
+func NewIdempotencyRecord(key string, fingerprint string) *IdempotencyRecord {
+	return &IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+	}
+}
+
+func (record *IdempotencyRecord) Complete(status int, body []byte) {
+	record.Completed = true
+	record.Status = status
+	record.Body = body
+}
@@ -0,0 +1,19 @@
+package idempotency
+
+import (
+	"context"
+)
+
+type IdempotencyStore interface {
+	// Begin reserves the key for the request. It returns no record if the key is
+	// reserved, otherwise returns the completed record of the first request.
+	// ErrRequestInProgress is returned if the first request has not completed,
+	// and ErrFingerprintMismatch if the key is used by a different request.
+	Begin(ctx context.Context, key string, fingerprint string) (*IdempotencyRecord, error)
+
+	// Complete saves the result of the request which has reserved the key
+	Complete(ctx context.Context, record *IdempotencyRecord) error
+
+	// Release removes the reservation, so that the request can be retried
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,72 @@
+package test_suites
+
+import (
+	"context"
+	"duolingo/libraries/idempotency"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type IdempotencyStoreTestSuite struct {
+	suite.Suite
+	store idempotency.IdempotencyStore
+}
+
+func NewIdempotencyStoreTestSuite(store idempotency.IdempotencyStore) *IdempotencyStoreTestSuite {
+	return &IdempotencyStoreTestSuite{
+		store: store,
+	}
+}
+
+func (s *IdempotencyStoreTestSuite) Test_Begin_Reserve() {
+	key := uuid.NewString()
+	defer s.store.Release(context.Background(), key)
+
+	record1, err1 := s.store.Begin(context.Background(), key, "F1")
+	record2, err2 := s.store.Begin(context.Background(), key, "F1")
+	record3, err3 := s.store.Begin(context.Background(), key, "F2")
+
+	s.Assert().NoError(err1)
+	s.Assert().Nil(record1)
+	s.Assert().Equal(idempotency.ErrRequestInProgress, err2)
+	s.Assert().Nil(record2)
+	s.Assert().Equal(idempotency.ErrFingerprintMismatch, err3)
+	s.Assert().Nil(record3)
+}
+
+func (s *IdempotencyStoreTestSuite) Test_Complete_Replay() {
+	key := uuid.NewString()
+	defer s.store.Release(context.Background(), key)
+
+	s.store.Begin(context.Background(), key, "F1")
+	record := idempotency.NewIdempotencyRecord(key, "F1")
+	record.Complete(http.StatusCreated, []byte(`{"success":true}`))
+	completeErr := s.store.Complete(context.Background(), record)
+
+	replay, replayErr := s.store.Begin(context.Background(), key, "F1")
+	_, mismatchErr := s.store.Begin(context.Background(), key, "F2")
+
+	s.Assert().NoError(completeErr)
+	s.Assert().NoError(replayErr)
+	if s.Assert().NotNil(replay) {
+		s.Assert().True(replay.Completed)
+		s.Assert().Equal(http.StatusCreated, replay.Status)
+		s.Assert().Equal(`{"success":true}`, string(replay.Body))
+	}
+	s.Assert().Equal(idempotency.ErrFingerprintMismatch, mismatchErr)
+}
+
+func (s *IdempotencyStoreTestSuite) Test_Release() {
+	key := uuid.NewString()
+	defer s.store.Release(context.Background(), key)
+
+	s.store.Begin(context.Background(), key, "F1")
+	releaseErr := s.store.Release(context.Background(), key)
+	record, beginErr := s.store.Begin(context.Background(), key, "F2")
+
+	s.Assert().NoError(releaseErr)
+	s.Assert().NoError(beginErr)
+	s.Assert().Nil(record)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	events "duolingo/libraries/events/facade"
@@ -13,6 +14,14 @@ import (
 
 type QueueConsumer struct {
 	*Topology
+
+	// The max number of the unconfirmed messages delivered to the consumer,
+	// zero means no limit.
+	prefetch int
+}
+
+func (c *QueueConsumer) SetPrefetch(count int) {
+	c.prefetch = count
 }
 
 func (c *QueueConsumer) Consuming(
@@ -99,6 +108,82 @@ func (c *QueueConsumer) Consuming(
 	}
 }
 
+// ConsumingAsync hands the messages over to the "processFunc" without waiting for
+// them to be processed, a message is confirmed once its "confirm" function is
+// called. The messages never confirmed are redelivered when the channel closes,
+// the number of the unconfirmed messages is limited by the prefetch count.
+func (c *QueueConsumer) ConsumingAsync(
+	ctx context.Context,
+	queue string,
+	processFunc func(ctx context.Context, msg string, confirm func(ConsumeAction)),
+) error {
+	var deliveries <-chan amqp.Delivery
+	var channel *amqp.Channel
+	var fatalErr error
+
+	deliveries, channel, fatalErr = c.waitForDeliveriesChanReady(ctx, queue)
+	if fatalErr != nil {
+		return fatalErr
+	}
+	defer func() {
+		channel.Close()
+		c.RenewConnection()
+	}()
+
+	// The messages are confirmed from the other goroutines
+	var confirmationMu sync.Mutex
+	var confirmationFailures = make(map[string]ConsumeAction)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case delivery, connectionAlive := <-deliveries:
+			if !connectionAlive {
+				deliveries, channel, fatalErr = c.waitForDeliveriesChanReady(ctx, queue)
+				if fatalErr != nil {
+					return fatalErr
+				}
+				continue
+			}
+			// Same as Consuming(), the message of a failed confirmation
+			// is confirmed again rather than processed again.
+			id, _ := delivery.Headers["message_id"].(string)
+			confirmationMu.Lock()
+			prevFailedAction, found := confirmationFailures[id]
+			confirmationMu.Unlock()
+			if found {
+				if retryErr := c.handleConsumeAction(ctx, queue, delivery, prevFailedAction); retryErr == nil {
+					confirmationMu.Lock()
+					delete(confirmationFailures, id)
+					confirmationMu.Unlock()
+				}
+				continue
+			}
+
+			evt := events.Start(
+				ctx,
+				fmt.Sprintf("mq.consumer.receive(%v)", queue),
+				map[string]any{
+					"message_headers": delivery.Headers,
+					"queue":           queue,
+				},
+			)
+			var once sync.Once
+			processFunc(evt.Context(), string(delivery.Body), func(action ConsumeAction) {
+				once.Do(func() {
+					ackErr := c.handleConsumeAction(evt.Context(), queue, delivery, action)
+					if ackErr != nil {
+						confirmationMu.Lock()
+						confirmationFailures[id] = action
+						confirmationMu.Unlock()
+					}
+					events.End(evt, true, ackErr, nil)
+				})
+			})
+		}
+	}
+}
+
 func (c *QueueConsumer) waitForDeliveriesChanReady(
 	ctx context.Context,
 	queue string,
@@ -114,6 +199,11 @@ func (c *QueueConsumer) waitForDeliveriesChanReady(
 		default:
 		}
 		if ch := c.GetConnection(); ch != nil {
+			if c.prefetch > 0 {
+				if err := ch.Qos(c.prefetch, 0, false); err != nil && !c.IsNetworkErr(err) {
+					return nil, nil, err
+				}
+			}
 			deliveries, err := ch.Consume(
 				queue,
 				"",    // consumer tag (empty string for auto-generated)
@@ -12,11 +12,19 @@ import (
 	"github.com/google/uuid"
 )
 
+/*
+### Notions:
+ 1. Without a group, the subscriber creates an exclusive, non-persistent queue
+    for each topic subscribed. Every subscriber receives all messages.
+ 2. Subscribers joining a same group share a persistent queue for each topic,
+    and compete for the messages. Different groups still receive all messages.
+*/
 type Subscriber struct {
 	*driver.QueueConsumer
 
 	id        string
-	queues    map[string]string // unique queues created for each topic subscribed
+	group     string
+	queues    map[string]string // queues created for each topic subscribed
 	mainTopic string
 }
 
@@ -30,6 +38,10 @@ func NewSubscriber(client *connection.RabbitMQClient) *Subscriber {
 	}
 }
 
+func (sub *Subscriber) SetGroup(group string) {
+	sub.group = group
+}
+
 func (sub *Subscriber) SetMainTopic(topic string) {
 	sub.mainTopic = topic
 }
@@ -62,7 +74,7 @@ func (sub *Subscriber) ListeningMainTopic(
 
 func (sub *Subscriber) Subscribe(ctx context.Context, topic string) error {
 	if _, exist := sub.queues[topic]; !exist {
-		sub.queues[topic] = fmt.Sprintf("%v_%v", topic, sub.id)
+		sub.queues[topic] = sub.queueName(topic)
 	}
 	return sub.bindQueue(ctx, topic)
 }
@@ -116,11 +128,15 @@ func (sub *Subscriber) bindQueue(ctx context.Context, topic string) error {
 			IsPersistent(),
 	)
 	if declareErr == nil {
+		queueOpts := driver.DefaultQueueOpts(sub.queues[topic])
+		if sub.group != "" {
+			queueOpts.IsPersistent()
+		} else {
+			queueOpts.IsNonPersistent().IsExclusive()
+		}
 		declareErr = sub.DeclareQueue(
 			ctx,
-			driver.DefaultQueueOpts(sub.queues[topic]).
-				IsNonPersistent().
-				IsExclusive(),
+			queueOpts,
 			driver.NewQueueBinding(sub.queues[topic]).
 				Add(topic, topic),
 		)
@@ -128,3 +144,10 @@ func (sub *Subscriber) bindQueue(ctx context.Context, topic string) error {
 
 	return declareErr
 }
+
+func (sub *Subscriber) queueName(topic string) string {
+	if sub.group != "" {
+		return fmt.Sprintf("%v_group_%v", topic, sub.group)
+	}
+	return fmt.Sprintf("%v_%v", topic, sub.id)
+}
@@ -52,3 +52,30 @@ func (c *TaskConsumer) Consuming(
 		return driver.ActionAccept, err
 	})
 }
+
+func (c *TaskConsumer) ConsumingAsync(
+	ctx context.Context,
+	handleFunc func(ctx context.Context, task string, done func()) error,
+) error {
+	if c.queue == "" {
+		return tq.ErrInvalidQueueName
+	}
+	return c.QueueConsumer.ConsumingAsync(ctx, c.queue, func(
+		receiveCtx context.Context,
+		receiveMsg string,
+		confirm func(driver.ConsumeAction),
+	) {
+		var err error
+
+		evt := events.Start(receiveCtx, fmt.Sprintf("task_queue.consumer.consume(%v)", c.queue), map[string]any{
+			"task_queue": c.queue,
+		})
+		defer events.End(evt, true, err, nil)
+
+		done := func() { confirm(driver.ActionAccept) }
+		// the task failed to be handled is done, same as Consuming()
+		if err = handleFunc(evt.Context(), receiveMsg, done); err != nil {
+			done()
+		}
+	})
+}
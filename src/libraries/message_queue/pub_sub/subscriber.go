@@ -15,6 +15,10 @@ type Subscriber interface {
 	UnSubscribe(ctx context.Context, topic string) error
 	Listening(ctx context.Context, topic string, closure func(context.Context, string) error) error
 
+	// Subscribers of a same group compete for the messages of a topic,
+	// instead of every subscriber receiving all of them.
+	SetGroup(group string)
+
 	SetMainTopic(topic string)
 	SubscribeMainTopic(ctx context.Context) error
 	UnSubscribeMainTopic(ctx context.Context) error
@@ -56,15 +56,15 @@ func (s *PubSubTestSuite) Test_Notify_And_Listening() {
 	}()
 
 	// subscribe and listening
-	subErr1 := s.firstSubscriber.Subscribe(tp1)
-	subErr2 := s.secondSubscriber.Subscribe(tp2)
+	subErr1 := s.firstSubscriber.Subscribe(context.Background(), tp1)
+	subErr2 := s.secondSubscriber.Subscribe(context.Background(), tp2)
 	if !s.Assert().NoError(subErr1) || !s.Assert().NoError(subErr2) {
 		return
 	}
 	go func() {
 		defer wg.Done()
 		expectMsg := "s1_m1"
-		err := s.firstSubscriber.Listening(ctx, tp1, func(ctx context.Context, msg string) {
+		err := s.firstSubscriber.Listening(ctx, tp1, func(ctx context.Context, msg string) error {
 			if s.Assert().Equal(expectMsg, msg) {
 				msgCount1++
 				msgTotal++
@@ -75,13 +75,14 @@ func (s *PubSubTestSuite) Test_Notify_And_Listening() {
 			if msgTotal == 4 {
 				cancel()
 			}
+			return nil
 		})
 		s.Assert().NoError(err)
 	}()
 	go func() {
 		defer wg.Done()
 		expectMsg := "s2_m1"
-		err := s.secondSubscriber.Listening(ctx, tp2, func(ctx context.Context, msg string) {
+		err := s.secondSubscriber.Listening(ctx, tp2, func(ctx context.Context, msg string) error {
 			if s.Assert().Equal(expectMsg, msg) {
 				msgCount2++
 				msgTotal++
@@ -92,17 +93,18 @@ func (s *PubSubTestSuite) Test_Notify_And_Listening() {
 			if msgTotal == 4 {
 				cancel()
 			}
+			return nil
 		})
 		s.Assert().NoError(err)
 	}()
 
 	// after subscribed, publish messages
-	declareErr1 := s.publisher.DeclareTopic(tp1)
-	declareErr2 := s.publisher.DeclareTopic(tp2)
-	pubErr1 := s.publisher.Notify(tp1, "s1_m1")
-	pubErr2 := s.publisher.Notify(tp1, "s1_m2")
-	pubErr3 := s.publisher.Notify(tp2, "s2_m1")
-	pubErr4 := s.publisher.Notify(tp2, "s2_m2")
+	declareErr1 := s.publisher.DeclareTopic(context.Background(), tp1)
+	declareErr2 := s.publisher.DeclareTopic(context.Background(), tp2)
+	pubErr1 := s.publisher.Notify(context.Background(), tp1, "s1_m1")
+	pubErr2 := s.publisher.Notify(context.Background(), tp1, "s1_m2")
+	pubErr3 := s.publisher.Notify(context.Background(), tp2, "s2_m1")
+	pubErr4 := s.publisher.Notify(context.Background(), tp2, "s2_m2")
 	if !s.Assert().NoError(declareErr1) ||
 		!s.Assert().NoError(declareErr2) ||
 		!s.Assert().NoError(pubErr1) ||
@@ -112,10 +114,10 @@ func (s *PubSubTestSuite) Test_Notify_And_Listening() {
 		return
 	}
 
-	rmErr1 := s.publisher.RemoveTopic(tp1)
-	rmErr2 := s.publisher.RemoveTopic(tp2)
-	unSubErr1 := s.firstSubscriber.UnSubscribe(tp1)
-	unSubErr2 := s.secondSubscriber.UnSubscribe(tp2)
+	rmErr1 := s.publisher.RemoveTopic(context.Background(), tp1)
+	rmErr2 := s.publisher.RemoveTopic(context.Background(), tp2)
+	unSubErr1 := s.firstSubscriber.UnSubscribe(context.Background(), tp1)
+	unSubErr2 := s.secondSubscriber.UnSubscribe(context.Background(), tp2)
 	s.Assert().NoError(rmErr1)
 	s.Assert().NoError(rmErr2)
 	s.Assert().NoError(unSubErr1)
@@ -125,9 +127,9 @@ func (s *PubSubTestSuite) Test_Notify_And_Listening() {
 }
 
 func (s *PubSubTestSuite) Test_MainTopic_NotSet() {
-	declareErr := s.publisher.DeclareMainTopic()
-	subErr1 := s.firstSubscriber.SubscribeMainTopic()
-	subErr2 := s.secondSubscriber.SubscribeMainTopic()
+	declareErr := s.publisher.DeclareMainTopic(context.Background())
+	subErr1 := s.firstSubscriber.SubscribeMainTopic(context.Background())
+	subErr2 := s.secondSubscriber.SubscribeMainTopic(context.Background())
 	s.Assert().Equal(ps.ErrPublisherMainTopicNotSet, declareErr)
 	s.Assert().Equal(ps.ErrSubscriberMainTopicNotSet, subErr1)
 	s.Assert().Equal(ps.ErrSubscriberMainTopicNotSet, subErr2)
@@ -159,9 +161,9 @@ func (s *PubSubTestSuite) Test_MainTopic_Notify_And_Listening() {
 	s.publisher.SetMainTopic(mainTopic)
 	s.firstSubscriber.SetMainTopic(mainTopic)
 	s.secondSubscriber.SetMainTopic(mainTopic)
-	declareErr := s.publisher.DeclareMainTopic()
-	subErr1 := s.firstSubscriber.SubscribeMainTopic()
-	subErr2 := s.secondSubscriber.SubscribeMainTopic()
+	declareErr := s.publisher.DeclareMainTopic(context.Background())
+	subErr1 := s.firstSubscriber.SubscribeMainTopic(context.Background())
+	subErr2 := s.secondSubscriber.SubscribeMainTopic(context.Background())
 	if !s.Assert().NoError(declareErr) ||
 		!s.Assert().NoError(subErr1) ||
 		!s.Assert().NoError(subErr2) {
@@ -169,7 +171,7 @@ func (s *PubSubTestSuite) Test_MainTopic_Notify_And_Listening() {
 	}
 	go func() {
 		defer wg.Done()
-		err := s.firstSubscriber.ListeningMainTopic(ctx, func(ctx context.Context, msg string) {
+		err := s.firstSubscriber.ListeningMainTopic(ctx, func(ctx context.Context, msg string) error {
 			if s.Assert().Equal("test message", msg) {
 				firstSubReceived = true
 			}
@@ -177,12 +179,13 @@ func (s *PubSubTestSuite) Test_MainTopic_Notify_And_Listening() {
 			if totalMsg == 2 {
 				cancel()
 			}
+			return nil
 		})
 		s.Assert().NoError(err)
 	}()
 	go func() {
 		defer wg.Done()
-		err := s.secondSubscriber.ListeningMainTopic(ctx, func(ctx context.Context, msg string) {
+		err := s.secondSubscriber.ListeningMainTopic(ctx, func(ctx context.Context, msg string) error {
 			if s.Assert().Equal("test message", msg) {
 				secondSubReceived = true
 			}
@@ -190,22 +193,81 @@ func (s *PubSubTestSuite) Test_MainTopic_Notify_And_Listening() {
 			if totalMsg == 2 {
 				cancel()
 			}
+			return nil
 		})
 		s.Assert().NoError(err)
 	}()
 
 	// after subscribe main topic, publish message
-	publishErr := s.publisher.NotifyMainTopic("test message")
+	publishErr := s.publisher.NotifyMainTopic(context.Background(), "test message")
 	if !s.Assert().NoError(publishErr) {
 		return
 	}
 
 	wg.Wait()
 
-	removeErr := s.publisher.RemoveMainTopic()
-	unSubErr1 := s.firstSubscriber.UnSubscribeMainTopic()
-	unSubErr2 := s.secondSubscriber.UnSubscribeMainTopic()
+	removeErr := s.publisher.RemoveMainTopic(context.Background())
+	unSubErr1 := s.firstSubscriber.UnSubscribeMainTopic(context.Background())
+	unSubErr2 := s.secondSubscriber.UnSubscribeMainTopic(context.Background())
 	s.Assert().NoError(removeErr)
 	s.Assert().NoError(unSubErr1)
 	s.Assert().NoError(unSubErr2)
 }
+
+func (s *PubSubTestSuite) Test_Group_Subscribers_Compete_For_Messages() {
+	topic := "group_topic"
+	msgTotal := 4
+	received := make(chan string, 2*msgTotal)
+
+	s.firstSubscriber.SetGroup("test_group")
+	s.secondSubscriber.SetGroup("test_group")
+	defer s.firstSubscriber.SetGroup("")
+	defer s.secondSubscriber.SetGroup("")
+
+	// start timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	// subscribe and listening
+	subErr1 := s.firstSubscriber.Subscribe(context.Background(), topic)
+	subErr2 := s.secondSubscriber.Subscribe(context.Background(), topic)
+	if !s.Assert().NoError(subErr1) || !s.Assert().NoError(subErr2) {
+		return
+	}
+	wg := new(sync.WaitGroup)
+	wg.Add(2)
+	for _, subscriber := range s.subscribers {
+		go func() {
+			defer wg.Done()
+			err := subscriber.Listening(ctx, topic, func(ctx context.Context, msg string) error {
+				received <- msg
+				return nil
+			})
+			s.Assert().NoError(err)
+		}()
+	}
+
+	// after subscribed, publish messages
+	for i := range msgTotal {
+		pubErr := s.publisher.Notify(context.Background(), topic, fmt.Sprintf("m%v", i))
+		if !s.Assert().NoError(pubErr) {
+			return
+		}
+	}
+
+	// each message is delivered to only one member of the group
+	wg.Wait()
+	close(received)
+	delivered := make(map[string]int)
+	for msg := range received {
+		delivered[msg]++
+	}
+	s.Assert().Len(delivered, msgTotal)
+	for msg := range delivered {
+		s.Assert().Equal(1, delivered[msg])
+	}
+
+	s.Assert().NoError(s.publisher.RemoveTopic(context.Background(), topic))
+	s.Assert().NoError(s.firstSubscriber.UnSubscribe(context.Background(), topic))
+	s.Assert().NoError(s.secondSubscriber.UnSubscribe(context.Background(), topic))
+}
@@ -23,5 +23,11 @@ type TaskProducer interface {
 
 type TaskConsumer interface {
 	SetQueue(queue string)
+	// SetPrefetch limits the number of the tasks being handled at a time
+	SetPrefetch(count int)
 	Consuming(ctx context.Context, handleFunc func(context.Context, string) error) error
+	// ConsumingAsync does not wait for the tasks to be handled, a task is acknowledged once
+	// its "done" function is called, or once the "handleFunc" returns an error. The tasks
+	// not acknowledged are redelivered if the consumer stops.
+	ConsumingAsync(ctx context.Context, handleFunc func(ctx context.Context, task string, done func()) error) error
 }
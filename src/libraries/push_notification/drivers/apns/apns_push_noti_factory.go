@@ -0,0 +1,64 @@
+package apns
+
+import (
+	"net/http"
+
+	push_noti "duolingo/libraries/push_notification"
+	"duolingo/libraries/push_notification/message"
+
+	driver "duolingo/libraries/push_notification/drivers/apns/message"
+)
+
+type APNsPushNotiFactory struct {
+	client   *http.Client
+	endpoint string
+	topic    string
+	signer   *TokenSigner
+}
+
+// NewAPNsPushNotiFactory authenticates with the .p8 signing key of the team,
+// "topic" is the bundle id of the app.
+func NewAPNsPushNotiFactory(
+	keyPEM string,
+	keyId string,
+	teamId string,
+	topic string,
+) (*APNsPushNotiFactory, error) {
+	signer, err := NewTokenSigner(keyPEM, keyId, teamId)
+	if err != nil {
+		return nil, err
+	}
+	factory := &APNsPushNotiFactory{
+		client:   &http.Client{Transport: &http.Transport{ForceAttemptHTTP2: true}},
+		endpoint: ProductionEndpoint,
+		topic:    topic,
+		signer:   signer,
+	}
+	return factory, nil
+}
+
+func (factory *APNsPushNotiFactory) SetEndpoint(endpoint string) *APNsPushNotiFactory {
+	factory.endpoint = endpoint
+	return factory
+}
+
+// SetHTTPClient replaces the HTTP/2 client, e.g. with the client trusting a stub server
+func (factory *APNsPushNotiFactory) SetHTTPClient(client *http.Client) *APNsPushNotiFactory {
+	factory.client = client
+	return factory
+}
+
+func (factory *APNsPushNotiFactory) CreatePushService() (push_noti.PushService, error) {
+	service := NewAPNsPushService(
+		factory.client,
+		factory.endpoint,
+		factory.topic,
+		factory.signer,
+		factory.CreateMessageBuilder(),
+	)
+	return service, nil
+}
+
+func (factory *APNsPushNotiFactory) CreateMessageBuilder() message.MessageBuilder {
+	return driver.NewAPNsMessageBuilder()
+}
@@ -0,0 +1,219 @@
+package apns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	events "duolingo/libraries/events/facade"
+	"duolingo/libraries/push_notification/message"
+	"duolingo/libraries/push_notification/results"
+
+	driver "duolingo/libraries/push_notification/drivers/apns/message"
+)
+
+const (
+	ProductionEndpoint  = "https://api.push.apple.com"
+	DevelopmentEndpoint = "https://api.sandbox.push.apple.com"
+)
+
+var (
+	ErrMessageDriverDriverMismatch = errors.New("message_builder.MessageBuilder driver mismatched")
+)
+
+/*
+### Notions:
+ 1. APNs accepts a single device token per request, the requests of a multicast
+    are sent concurrently over the HTTP/2 connection, up to "concurrency" at once.
+ 2. The failed requests are mapped to the same failure reasons as FCM, so that
+    the senders handle both the same way.
+*/
+type APNsPushService struct {
+	client      *http.Client
+	endpoint    string
+	topic       string
+	signer      *TokenSigner
+	builder     message.MessageBuilder
+	concurrency int
+}
+
+func NewAPNsPushService(
+	client *http.Client,
+	endpoint string,
+	topic string,
+	signer *TokenSigner,
+	builder message.MessageBuilder,
+) *APNsPushService {
+	return &APNsPushService{
+		client:      client,
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		topic:       topic,
+		signer:      signer,
+		builder:     builder,
+		concurrency: 20,
+	}
+}
+
+func (service *APNsPushService) SetConcurrency(concurrency int) *APNsPushService {
+	service.concurrency = max(1, concurrency)
+	return service
+}
+
+func (service *APNsPushService) SendMulticast(
+	ctx context.Context,
+	noti *message.Message,
+	target *message.MulticastTarget,
+) (
+	*results.MulticastResult,
+	error,
+) {
+	var multicast any
+	var result *results.MulticastResult
+	var err error
+
+	evt := events.Start(ctx, "push_noti.push_service.send_multicast", map[string]any{
+		"driver":        "apns",
+		"devices_total": len(target.DeviceTokens),
+		"platforms":     strings.Join(message.StrPlatforms(target.Platforms...), ", "),
+	})
+	defer func() {
+		if err == nil {
+			evt.SetData("success_total", result.SuccessCount)
+			evt.SetData("failure_total", result.FailureCount)
+		}
+		events.End(evt, true, err, nil)
+	}()
+
+	multicast, err = service.builder.BuildMulticast(noti, target)
+	if err != nil {
+		return nil, err
+	}
+	apnsMulticast, ok := multicast.(*driver.APNsMulticast)
+	if !ok || apnsMulticast == nil {
+		panic(ErrMessageDriverDriverMismatch)
+	}
+	authToken, err := service.signer.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	failures := make([]*results.TokenFailure, len(apnsMulticast.Tokens))
+	slots := make(chan struct{}, service.concurrency)
+	wg := new(sync.WaitGroup)
+	for i, token := range apnsMulticast.Tokens {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			failures[i] = service.send(ctx, authToken, token, apnsMulticast)
+		}()
+	}
+	wg.Wait()
+
+	result, err = newMulticastResult(failures, apnsMulticast.Tokens), nil
+
+	return result, err
+}
+
+// send delivers the notification to a device token, it returns nil if the
+// notification has been accepted.
+func (service *APNsPushService) send(
+	ctx context.Context,
+	authToken string,
+	deviceToken string,
+	multicast *driver.APNsMulticast,
+) *results.TokenFailure {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		service.endpoint+"/3/device/"+deviceToken,
+		bytes.NewReader(multicast.Payload),
+	)
+	if err != nil {
+		return &results.TokenFailure{Token: deviceToken, Reason: results.FailureUnknown}
+	}
+	for name, value := range multicast.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("apns-topic", service.topic)
+	req.Header.Set("authorization", "bearer "+authToken)
+
+	res, err := service.client.Do(req)
+	if err != nil {
+		// the connection failures are retried
+		return &results.TokenFailure{Token: deviceToken, Reason: results.FailureTransient}
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(res.Body).Decode(&body)
+	if body.Reason == "ExpiredProviderToken" {
+		service.signer.Invalidate(authToken)
+	}
+	return &results.TokenFailure{
+		Token:      deviceToken,
+		Reason:     classifyFailure(res.StatusCode, body.Reason),
+		RetryAfter: retryAfter(res.Header.Get("Retry-After")),
+	}
+}
+
+func newMulticastResult(failures []*results.TokenFailure, tokens []string) *results.MulticastResult {
+	result := &results.MulticastResult{}
+	for i := range failures {
+		if failures[i] == nil {
+			result.SuccessCount++
+			continue
+		}
+		result.FailureCount++
+		result.FailureTokens = append(result.FailureTokens, tokens[i])
+		result.Failures = append(result.Failures, failures[i])
+	}
+	return result
+}
+
+// classifyFailure maps the APNs error reasons, the throttled, unavailable and
+// expired provider token errors are transient.
+func classifyFailure(status int, reason string) results.FailureReason {
+	switch {
+	case status == http.StatusGone || reason == "Unregistered":
+		return results.FailureUnregistered
+	case reason == "BadDeviceToken":
+		return results.FailureInvalidToken
+	case reason == "DeviceTokenNotForTopic":
+		// the token belongs to another app, same as the FCM sender id mismatch
+		return results.FailureSenderIdMismatch
+	case status == http.StatusTooManyRequests,
+		status == http.StatusInternalServerError,
+		status == http.StatusServiceUnavailable,
+		reason == "ExpiredProviderToken":
+		return results.FailureTransient
+	default:
+		return results.FailureUnknown
+	}
+}
+
+// retryAfter reads the "Retry-After" header, which is either a number of
+// seconds or a HTTP date.
+func retryAfter(header string) time.Duration {
+	if seconds, parseErr := strconv.Atoi(header); parseErr == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	if date, parseErr := http.ParseTime(header); parseErr == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	msg "duolingo/libraries/push_notification/message"
+)
+
+var (
+	ErrPlatformNotSupported = errors.New("apns only delivers to the ios platform")
+)
+
+// APNsMulticast is a notification sent to each device token by its own
+// request, as the APNs provider API has no multicast endpoint.
+type APNsMulticast struct {
+	Tokens  []string
+	Headers map[string]string
+	Payload []byte
+}
+
+type apsAlert struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type aps struct {
+	Alert apsAlert `json:"alert"`
+	Sound string   `json:"sound,omitempty"`
+}
+
+type APNsMessageBuilder struct {
+}
+
+func NewAPNsMessageBuilder() *APNsMessageBuilder {
+	return &APNsMessageBuilder{}
+}
+
+func (builder *APNsMessageBuilder) BuildMulticast(
+	message *msg.Message,
+	target *msg.MulticastTarget,
+) (any, error) {
+	if err := message.Validate(); err != nil {
+		return nil, err
+	}
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(target.Platforms, msg.IOS) {
+		return nil, ErrPlatformNotSupported
+	}
+	payload, err := json.Marshal(map[string]any{
+		"aps": aps{
+			Alert: apsAlert{Title: message.Title, Body: message.Body},
+			Sound: message.Sound,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{
+		"apns-push-type": "alert",
+		"apns-priority":  getPriority(message.Priority),
+	}
+	if message.Expiration != time.Duration(0) {
+		headers["apns-expiration"] = fmt.Sprint(time.Now().Add(message.Expiration).Unix())
+	}
+	if message.CollapseKey != "" {
+		headers["apns-collapse-id"] = message.CollapseKey
+	}
+	return &APNsMulticast{
+		Tokens:  target.DeviceTokens,
+		Headers: headers,
+		Payload: payload,
+	}, nil
+}
+
+func getPriority(priority msg.Priority) string {
+	priorities := map[msg.Priority]string{
+		msg.PriorityHigh:   "10",
+		msg.PriorityNormal: "5",
+	}
+	if found, ok := priorities[priority]; ok {
+		return found
+	}
+	return "5"
+}
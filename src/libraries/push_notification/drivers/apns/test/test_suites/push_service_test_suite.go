@@ -0,0 +1,183 @@
+package test_suites
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"duolingo/libraries/push_notification/drivers/apns"
+	"duolingo/libraries/push_notification/message"
+	"duolingo/libraries/push_notification/results"
+
+	"github.com/stretchr/testify/suite"
+)
+
+/*
+PushServiceTestSuite sends to a local HTTP/2 stub of the APNs provider API,
+the stub responds by the device token:
+  - "unregistered_*" is rejected with 410 Unregistered
+  - "bad_*" is rejected with 400 BadDeviceToken
+  - "throttled_*" is rejected with 429 TooManyRequests, retry after 3 seconds
+  - the others are accepted
+*/
+type PushServiceTestSuite struct {
+	suite.Suite
+	stub    *httptest.Server
+	service *apns.APNsPushService
+
+	mu       sync.Mutex
+	requests []*http.Request
+	payloads []string
+}
+
+func NewPushServiceTestSuite() *PushServiceTestSuite {
+	return &PushServiceTestSuite{}
+}
+
+func (s *PushServiceTestSuite) SetupSuite() {
+	s.stub = httptest.NewUnstartedServer(http.HandlerFunc(s.respond))
+	s.stub.EnableHTTP2 = true
+	s.stub.StartTLS()
+
+	factory, err := apns.NewAPNsPushNotiFactory(generateKey(), "KEY123", "TEAM123", "com.example.app")
+	if err != nil {
+		panic(err)
+	}
+	factory.SetEndpoint(s.stub.URL).SetHTTPClient(s.stub.Client())
+	service, _ := factory.CreatePushService()
+	s.service = service.(*apns.APNsPushService)
+}
+
+func (s *PushServiceTestSuite) TearDownSuite() {
+	s.stub.Close()
+}
+
+func (s *PushServiceTestSuite) SetupTest() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests, s.payloads = nil, nil
+}
+
+func (s *PushServiceTestSuite) Test_SendMulticast() {
+	msg := &message.Message{
+		Title:       "title",
+		Body:        "body",
+		Sound:       "default",
+		CollapseKey: "collapse",
+		Priority:    message.PriorityHigh,
+	}
+	target := &message.MulticastTarget{
+		DeviceTokens: []string{"valid_1", "valid_2"},
+		Platforms:    []message.Platform{message.IOS},
+	}
+	result, err := s.service.SendMulticast(context.Background(), msg, target)
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(2, result.SuccessCount)
+	s.Assert().Equal(0, result.FailureCount)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Assert().Len(s.requests, 2) {
+		req := s.requests[0]
+		s.Assert().Equal("HTTP/2.0", req.Proto)
+		s.Assert().Equal(http.MethodPost, req.Method)
+		s.Assert().True(strings.HasPrefix(req.URL.Path, "/3/device/valid_"))
+		s.Assert().Equal("com.example.app", req.Header.Get("apns-topic"))
+		s.Assert().Equal("alert", req.Header.Get("apns-push-type"))
+		s.Assert().Equal("10", req.Header.Get("apns-priority"))
+		s.Assert().Equal("collapse", req.Header.Get("apns-collapse-id"))
+		s.Assert().True(strings.HasPrefix(req.Header.Get("authorization"), "bearer "))
+		s.Assert().Equal(
+			req.Header.Get("authorization"),
+			s.requests[1].Header.Get("authorization"),
+			"the provider token should be reused",
+		)
+		s.Assert().JSONEq(`{"aps":{"alert":{"title":"title","body":"body"},"sound":"default"}}`, s.payloads[0])
+	}
+}
+
+func (s *PushServiceTestSuite) Test_SendMulticast_Failures() {
+	msg := &message.Message{
+		Title: "title",
+		Body:  "body",
+	}
+	target := &message.MulticastTarget{
+		DeviceTokens: []string{"valid_1", "unregistered_1", "bad_1", "throttled_1"},
+		Platforms:    []message.Platform{message.IOS},
+	}
+	result, err := s.service.SendMulticast(context.Background(), msg, target)
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(1, result.SuccessCount)
+	s.Assert().Equal(3, result.FailureCount)
+	s.Assert().ElementsMatch([]string{"unregistered_1", "bad_1", "throttled_1"}, result.FailureTokens)
+	s.Assert().ElementsMatch([]string{"unregistered_1", "bad_1"}, result.GetInvalidTokens())
+
+	retryTokens, retryAfter := result.GetRetryableFailures()
+	s.Assert().Equal([]string{"throttled_1"}, retryTokens)
+	s.Assert().Equal(3*time.Second, retryAfter)
+
+	reasons := map[string]results.FailureReason{}
+	for _, failure := range result.Failures {
+		reasons[failure.Token] = failure.Reason
+	}
+	s.Assert().Equal(results.FailureUnregistered, reasons["unregistered_1"])
+	s.Assert().Equal(results.FailureInvalidToken, reasons["bad_1"])
+	s.Assert().Equal(results.FailureTransient, reasons["throttled_1"])
+}
+
+func (s *PushServiceTestSuite) Test_SendMulticast_Android_Not_Supported() {
+	msg := &message.Message{
+		Title: "title",
+		Body:  "body",
+	}
+	target := &message.MulticastTarget{
+		DeviceTokens: []string{"valid_1"},
+		Platforms:    []message.Platform{message.Android},
+	}
+	_, err := s.service.SendMulticast(context.Background(), msg, target)
+
+	s.Assert().Error(err)
+}
+
+func (s *PushServiceTestSuite) respond(w http.ResponseWriter, r *http.Request) {
+	payload, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	s.payloads = append(s.payloads, string(payload))
+	s.mu.Unlock()
+
+	token := strings.TrimPrefix(r.URL.Path, "/3/device/")
+	switch {
+	case strings.HasPrefix(token, "unregistered_"):
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(`{"reason":"Unregistered","timestamp":1700000000000}`))
+	case strings.HasPrefix(token, "bad_"):
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+	case strings.HasPrefix(token, "throttled_"):
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"reason":"TooManyRequests"}`))
+	default:
+		w.Header().Set("apns-id", "stub-apns-id")
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// generateKey returns a .p8 like signing key
+func generateKey() string {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
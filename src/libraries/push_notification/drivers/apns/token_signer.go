@@ -0,0 +1,101 @@
+package apns
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidSigningKey = errors.New("apns signing key must be a PEM encoded .p8 (ECDSA P-256) key")
+)
+
+/*
+TokenSigner issues the provider authentication tokens (JWT signed with ES256)
+of the token-based APNs authentication. APNs rejects the tokens older than an
+hour, and the tokens refreshed more often than every 20 minutes, so a token is
+reused until "refreshInterval" has passed.
+*/
+type TokenSigner struct {
+	key    *ecdsa.PrivateKey
+	keyId  string
+	teamId string
+
+	mu              sync.Mutex
+	token           string
+	issuedAt        time.Time
+	refreshInterval time.Duration
+}
+
+func NewTokenSigner(keyPEM string, keyId string, teamId string) (*TokenSigner, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, ErrInvalidSigningKey
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidSigningKey
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidSigningKey
+	}
+	return &TokenSigner{
+		key:             key,
+		keyId:           keyId,
+		teamId:          teamId,
+		refreshInterval: 50 * time.Minute,
+	}, nil
+}
+
+// Token returns the current provider token, a new token is issued if the
+// current one is about to expire.
+func (signer *TokenSigner) Token() (string, error) {
+	signer.mu.Lock()
+	defer signer.mu.Unlock()
+	if signer.token != "" && time.Since(signer.issuedAt) < signer.refreshInterval {
+		return signer.token, nil
+	}
+	now := time.Now()
+	token, err := signer.sign(now)
+	if err != nil {
+		return "", err
+	}
+	signer.token, signer.issuedAt = token, now
+	return token, nil
+}
+
+// Invalidate drops the current token, e.g. the token rejected as expired
+func (signer *TokenSigner) Invalidate(token string) {
+	signer.mu.Lock()
+	defer signer.mu.Unlock()
+	if signer.token == token {
+		signer.token = ""
+	}
+}
+
+func (signer *TokenSigner) sign(issuedAt time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": signer.keyId})
+	claims, _ := json.Marshal(map[string]any{"iss": signer.teamId, "iat": issuedAt.Unix()})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, signer.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// the ES256 signature is the 32 bytes r and s concatenated
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
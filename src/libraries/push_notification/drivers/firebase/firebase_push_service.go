@@ -3,12 +3,16 @@ package firebase
 import (
 	"context"
 	"errors"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	events "duolingo/libraries/events/facade"
 	"duolingo/libraries/push_notification/message"
 	"duolingo/libraries/push_notification/results"
 
+	"firebase.google.com/go/v4/errorutils"
 	fcm "firebase.google.com/go/v4/messaging"
 )
 
@@ -45,6 +49,7 @@ func (service *FirebasePushService) SendMulticast(
 	var err error
 
 	evt := events.Start(ctx, "push_noti.push_service.send_multicast", map[string]any{
+		"driver":        "firebase",
 		"devices_total": len(target.DeviceTokens),
 		"platforms":     strings.Join(message.StrPlatforms(target.Platforms...), ", "),
 	})
@@ -67,7 +72,7 @@ func (service *FirebasePushService) SendMulticast(
 
 	sendResponse, err = service.client.SendEachForMulticast(ctx, firebaseMulticast)
 	if err != nil {
-		return nil, err
+		return nil, &results.SendError{Err: err, RetryAfter: retryAfter(err)}
 	}
 	result, err = service.parseMulticastResponse(sendResponse, target), nil
 
@@ -79,14 +84,61 @@ func (service *FirebasePushService) parseMulticastResponse(
 	target *message.MulticastTarget,
 ) *results.MulticastResult {
 	var failedTokens []string
+	var failures []*results.TokenFailure
 	for i, resp := range res.Responses {
 		if !resp.Success {
 			failedTokens = append(failedTokens, target.DeviceTokens[i])
+			failures = append(failures, &results.TokenFailure{
+				Token:      target.DeviceTokens[i],
+				Reason:     classifyFailure(resp.Error),
+				RetryAfter: retryAfter(resp.Error),
+			})
 		}
 	}
 	return &results.MulticastResult{
 		SuccessCount:  res.SuccessCount,
 		FailureCount:  res.FailureCount,
 		FailureTokens: failedTokens,
+		Failures:      failures,
+	}
+}
+
+// classifyFailure maps the FCM per-token errors, the unavailable, internal
+// and quota exceeded errors are transient. The invalid argument is reported
+// for the malformed payloads as well (e.g, an oversized body), it is only an
+// invalid token when the error points at the registration token.
+func classifyFailure(err error) results.FailureReason {
+	switch {
+	case fcm.IsUnregistered(err):
+		return results.FailureUnregistered
+	case fcm.IsInvalidArgument(err) && isRegistrationTokenError(err):
+		return results.FailureInvalidToken
+	case fcm.IsSenderIDMismatch(err):
+		return results.FailureSenderIdMismatch
+	case fcm.IsUnavailable(err), fcm.IsInternal(err), fcm.IsQuotaExceeded(err):
+		return results.FailureTransient
+	default:
+		return results.FailureUnknown
+	}
+}
+
+func isRegistrationTokenError(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "registration token")
+}
+
+// retryAfter reads the "Retry-After" header of the FCM error response,
+// which is either a number of seconds or a HTTP date.
+func retryAfter(err error) time.Duration {
+	response := errorutils.HTTPResponse(err)
+	if response == nil {
+		return 0
+	}
+	header := response.Header.Get("Retry-After")
+	if seconds, parseErr := strconv.Atoi(header); parseErr == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	if date, parseErr := http.ParseTime(header); parseErr == nil {
+		return max(time.Until(date), 0)
 	}
+	return 0
 }
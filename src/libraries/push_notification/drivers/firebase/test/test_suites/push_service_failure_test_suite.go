@@ -0,0 +1,116 @@
+package test_suites
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"duolingo/libraries/push_notification/drivers/firebase"
+	driver "duolingo/libraries/push_notification/drivers/firebase/message"
+	"duolingo/libraries/push_notification/message"
+	"duolingo/libraries/push_notification/results"
+
+	firebase_sdk "firebase.google.com/go/v4"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/api/option"
+)
+
+// PushServiceFailureTestSuite sends to a stub of the FCM API, responding with
+// the error configured for each token.
+type PushServiceFailureTestSuite struct {
+	suite.Suite
+	server  *httptest.Server
+	service *firebase.FirebasePushService
+
+	// the FCM error responded for each token
+	errors map[string]fcmError
+}
+
+type fcmError struct {
+	status  string
+	code    string
+	message string
+}
+
+func NewPushServiceFailureTestSuite() *PushServiceFailureTestSuite {
+	return &PushServiceFailureTestSuite{}
+}
+
+func (s *PushServiceFailureTestSuite) SetupTest() {
+	s.errors = make(map[string]fcmError)
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Message struct {
+				Token string `json:"token"`
+			} `json:"message"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		failure, failed := s.errors[body.Message.Token]
+		if !failed {
+			fmt.Fprint(w, `{"name":"projects/test/messages/1"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"error":{"status":%q,"message":%q,"details":[{
+			"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError",
+			"errorCode":%q
+		}]}}`, failure.status, failure.message, failure.code)
+	}))
+
+	ctx := context.Background()
+	app, err := firebase_sdk.NewApp(ctx, &firebase_sdk.Config{ProjectID: "test"},
+		option.WithEndpoint(s.server.URL),
+		option.WithoutAuthentication(),
+	)
+	s.Require().NoError(err)
+	client, err := app.Messaging(ctx)
+	s.Require().NoError(err)
+	s.service = firebase.NewFirebasePushService(client, driver.NewFirebaseMessagebuilder())
+}
+
+func (s *PushServiceFailureTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *PushServiceFailureTestSuite) send(tokens ...string) *results.MulticastResult {
+	result, err := s.service.SendMulticast(
+		context.Background(),
+		&message.Message{Title: "test", Body: "test"},
+		&message.MulticastTarget{DeviceTokens: tokens, Platforms: []message.Platform{message.Android}},
+	)
+	s.Require().NoError(err)
+	return result
+}
+
+func (s *PushServiceFailureTestSuite) Test_Payload_Error_Is_Not_Invalid_Token() {
+	for _, token := range []string{"token_1", "token_2"} {
+		s.errors[token] = fcmError{"INVALID_ARGUMENT", "INVALID_ARGUMENT", "Message exceeded maximum allowed size"}
+	}
+
+	result := s.send("token_1", "token_2")
+
+	s.Assert().Equal(2, result.FailureCount)
+	s.Assert().Empty(result.GetInvalidTokens())
+	for _, failure := range result.Failures {
+		s.Assert().Equal(results.FailureUnknown, failure.Reason)
+	}
+}
+
+func (s *PushServiceFailureTestSuite) Test_Token_Errors_Are_Permanent() {
+	s.errors["malformed"] = fcmError{"INVALID_ARGUMENT", "INVALID_ARGUMENT", "The registration token is not a valid FCM registration token"}
+	s.errors["gone"] = fcmError{"NOT_FOUND", "UNREGISTERED", "Requested entity was not found."}
+
+	result := s.send("malformed", "gone", "valid")
+
+	s.Assert().Equal(1, result.SuccessCount)
+	s.Assert().ElementsMatch([]string{"malformed", "gone"}, result.GetInvalidTokens())
+	reasons := map[string]results.FailureReason{}
+	for _, failure := range result.Failures {
+		reasons[failure.Token] = failure.Reason
+	}
+	s.Assert().Equal(results.FailureInvalidToken, reasons["malformed"])
+	s.Assert().Equal(results.FailureUnregistered, reasons["gone"])
+}
@@ -0,0 +1,291 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"duolingo/libraries/circuit_breaker"
+	events "duolingo/libraries/events/facade"
+	push_noti "duolingo/libraries/push_notification"
+	"duolingo/libraries/push_notification/message"
+	"duolingo/libraries/push_notification/results"
+)
+
+var (
+	ErrProviderNotDeclared  = errors.New("push service provider is not declared")
+	ErrProvidersUnavailable = errors.New("push service providers are unavailable")
+)
+
+// provider is a push service along with the circuit guarding it
+type provider struct {
+	name    string
+	service push_noti.PushService
+	breaker *circuit_breaker.CircuitBreaker
+}
+
+// route is the providers delivering a platform, the secondary is optional
+type route struct {
+	primary   *provider
+	secondary *provider
+}
+
+/*
+RoutingPushService sends the device tokens to the push service of their
+platform, e.g. APNs for iOS, FCM for Android and Web Push for web.
+
+### Notions:
+ 1. The tokens of the platforms sharing the same route are sent by a single
+    request, the requests of the different routes are sent concurrently.
+ 2. The provider is failing when the whole request failed (results.SendError),
+    or when every token failed transiently. The tokens are then sent to the
+    secondary provider of the route, if any.
+ 3. The circuit of a provider opens after consecutive failures, the provider
+    is skipped until its circuit allows a trial request again.
+ 4. The secondary provider must accept the tokens of the primary one, otherwise
+    the valid tokens would be reported invalid and removed.
+ 5. The results of the routes are merged, the tokens of a failed route are
+    reported as transient failures, so that only them are retried.
+*/
+type RoutingPushService struct {
+	failureThreshold int
+	openDuration     time.Duration
+	providers        map[string]*provider
+	routes           map[message.Platform]*route
+}
+
+func NewRoutingPushService(failureThreshold int, openDuration time.Duration) *RoutingPushService {
+	return &RoutingPushService{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		providers:        make(map[string]*provider),
+		routes:           make(map[message.Platform]*route),
+	}
+}
+
+// AddProvider declares a push service, which can then be used by the routes
+func (service *RoutingPushService) AddProvider(name string, pushService push_noti.PushService) *RoutingPushService {
+	service.providers[name] = &provider{
+		name:    name,
+		service: pushService,
+		breaker: circuit_breaker.NewCircuitBreaker(service.failureThreshold, service.openDuration),
+	}
+	return service
+}
+
+// AddRoute sends the tokens of the platform to the primary provider, and to the
+// secondary one when the primary is failing. The secondary can be empty.
+func (service *RoutingPushService) AddRoute(platform message.Platform, primary string, secondary string) error {
+	r := &route{primary: service.providers[primary]}
+	if r.primary == nil {
+		return fmt.Errorf("%w: %v", ErrProviderNotDeclared, primary)
+	}
+	if secondary != "" {
+		if r.secondary = service.providers[secondary]; r.secondary == nil {
+			return fmt.Errorf("%w: %v", ErrProviderNotDeclared, secondary)
+		}
+	}
+	service.routes[platform] = r
+	return nil
+}
+
+func (service *RoutingPushService) SendMulticast(
+	ctx context.Context,
+	noti *message.Message,
+	target *message.MulticastTarget,
+) (
+	*results.MulticastResult,
+	error,
+) {
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+	routed, unrouted := service.routeTokens(target)
+	if len(routed) == 1 && len(unrouted) == 0 {
+		for r, routeTarget := range routed {
+			return service.sendRoute(ctx, r, noti, routeTarget)
+		}
+	}
+
+	mu := new(sync.Mutex)
+	merged := &results.MulticastResult{}
+	errs := []error{}
+	for _, token := range unrouted {
+		mergeFailures(merged, []string{token}, results.FailureUnknown, 0)
+	}
+	wg := new(sync.WaitGroup)
+	for r, routeTarget := range routed {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := service.sendRoute(ctx, r, noti, routeTarget)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				mergeError(merged, routeTarget.DeviceTokens, err)
+				return
+			}
+			mergeResult(merged, result)
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) == len(routed) && len(unrouted) == 0 {
+		return nil, errs[0]
+	}
+	return merged, nil
+}
+
+// routeTokens groups the tokens by route, the tokens of an unknown platform
+// are returned apart.
+func (service *RoutingPushService) routeTokens(target *message.MulticastTarget) (
+	map[*route]*message.MulticastTarget,
+	[]string,
+) {
+	routed := make(map[*route]*message.MulticastTarget)
+	unrouted := []string{}
+	for _, token := range target.DeviceTokens {
+		platform, ok := target.DevicePlatforms[token]
+		if !ok && len(target.Platforms) == 1 {
+			platform, ok = target.Platforms[0], true
+		}
+		r := service.routes[platform]
+		if !ok || r == nil {
+			unrouted = append(unrouted, token)
+			continue
+		}
+		if routed[r] == nil {
+			routed[r] = &message.MulticastTarget{
+				DevicePlatforms: target.DevicePlatforms,
+				WebPushKeys:     target.WebPushKeys,
+			}
+		}
+		routeTarget := routed[r]
+		routeTarget.DeviceTokens = append(routeTarget.DeviceTokens, token)
+		if !slices.Contains(routeTarget.Platforms, platform) {
+			routeTarget.Platforms = append(routeTarget.Platforms, platform)
+		}
+	}
+	return routed, unrouted
+}
+
+// sendRoute sends the tokens to the primary provider, or to the secondary one
+// if the primary is failing or its circuit is open.
+func (service *RoutingPushService) sendRoute(
+	ctx context.Context,
+	r *route,
+	noti *message.Message,
+	target *message.MulticastTarget,
+) (
+	*results.MulticastResult,
+	error,
+) {
+	var result *results.MulticastResult
+	var err error
+	var retryAfter time.Duration
+	attempted := false
+
+	for _, p := range []*provider{r.primary, r.secondary} {
+		if p == nil {
+			continue
+		}
+		if !p.breaker.Allow() {
+			retryAfter = minRetryAfter(retryAfter, p.breaker.RetryAfter())
+			service.notifyFailover(ctx, p, target, string(circuit_breaker.StateOpen))
+			continue
+		}
+		attempted = true
+		result, err = p.service.SendMulticast(ctx, noti, target)
+		if !isFailing(result, err) {
+			p.breaker.RecordSuccess()
+			return result, err
+		}
+		p.breaker.RecordFailure()
+		service.notifyFailover(ctx, p, target, "failing")
+	}
+	if !attempted {
+		return nil, &results.SendError{Err: ErrProvidersUnavailable, RetryAfter: retryAfter}
+	}
+	// the last failure is reported, its tokens are retried by the caller
+	return result, err
+}
+
+func (service *RoutingPushService) notifyFailover(
+	ctx context.Context,
+	p *provider,
+	target *message.MulticastTarget,
+	reason string,
+) {
+	evt := events.Start(ctx, "push_noti.push_service.failover", map[string]any{
+		"provider":      p.name,
+		"reason":        reason,
+		"devices_total": len(target.DeviceTokens),
+		"platforms":     strings.Join(message.StrPlatforms(target.Platforms...), ", "),
+	})
+	events.End(evt, true, nil, nil)
+}
+
+// isFailing tells whether the provider failed the request as a whole, rather
+// than rejected some of its tokens. Any error counts, not only the send errors,
+// e.g. the provider failing to sign or to build its requests.
+func isFailing(result *results.MulticastResult, err error) bool {
+	if err != nil {
+		return true
+	}
+	if result == nil || result.SuccessCount > 0 || len(result.Failures) == 0 {
+		return false
+	}
+	for _, failure := range result.Failures {
+		if !failure.Reason.IsRetryable() {
+			return false
+		}
+	}
+	return true
+}
+
+func mergeResult(merged *results.MulticastResult, result *results.MulticastResult) {
+	merged.SuccessCount += result.SuccessCount
+	merged.FailureCount += result.FailureCount
+	merged.FailureTokens = append(merged.FailureTokens, result.FailureTokens...)
+	merged.Failures = append(merged.Failures, result.Failures...)
+}
+
+// mergeError reports the tokens of the failed request, only the tokens of the
+// failed provider are retried.
+func mergeError(merged *results.MulticastResult, tokens []string, err error) {
+	var sendErr *results.SendError
+	if errors.As(err, &sendErr) {
+		mergeFailures(merged, tokens, results.FailureTransient, sendErr.RetryAfter)
+	} else {
+		mergeFailures(merged, tokens, results.FailureUnknown, 0)
+	}
+}
+
+func mergeFailures(
+	merged *results.MulticastResult,
+	tokens []string,
+	reason results.FailureReason,
+	retryAfter time.Duration,
+) {
+	for _, token := range tokens {
+		merged.FailureCount++
+		merged.FailureTokens = append(merged.FailureTokens, token)
+		merged.Failures = append(merged.Failures, &results.TokenFailure{
+			Token:      token,
+			Reason:     reason,
+			RetryAfter: retryAfter,
+		})
+	}
+}
+
+func minRetryAfter(current time.Duration, next time.Duration) time.Duration {
+	if current == 0 {
+		return next
+	}
+	return min(current, next)
+}
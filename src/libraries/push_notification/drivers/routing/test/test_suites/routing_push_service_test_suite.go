@@ -0,0 +1,194 @@
+package test_suites
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"duolingo/libraries/push_notification/drivers/routing"
+	"duolingo/libraries/push_notification/message"
+	"duolingo/libraries/push_notification/results"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// stubPushService records the requests, and fails them while "failing" is set,
+// with "err" if set, otherwise with a send error.
+type stubPushService struct {
+	mu       sync.Mutex
+	failing  bool
+	err      error
+	requests []*message.MulticastTarget
+}
+
+func (stub *stubPushService) SendMulticast(
+	ctx context.Context,
+	noti *message.Message,
+	target *message.MulticastTarget,
+) (
+	*results.MulticastResult,
+	error,
+) {
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	stub.requests = append(stub.requests, target)
+	if stub.failing && stub.err != nil {
+		return nil, stub.err
+	}
+	if stub.failing {
+		return nil, &results.SendError{Err: errors.New("unavailable"), RetryAfter: time.Second}
+	}
+	return &results.MulticastResult{SuccessCount: len(target.DeviceTokens)}, nil
+}
+
+func (stub *stubPushService) Requests() []*message.MulticastTarget {
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	return stub.requests
+}
+
+type RoutingPushServiceTestSuite struct {
+	suite.Suite
+	firebase *stubPushService
+	apns     *stubPushService
+	webpush  *stubPushService
+	service  *routing.RoutingPushService
+}
+
+func NewRoutingPushServiceTestSuite() *RoutingPushServiceTestSuite {
+	return &RoutingPushServiceTestSuite{}
+}
+
+func (s *RoutingPushServiceTestSuite) SetupTest() {
+	s.firebase, s.apns, s.webpush = &stubPushService{}, &stubPushService{}, &stubPushService{}
+	s.service = routing.NewRoutingPushService(2, time.Minute).
+		AddProvider("firebase", s.firebase).
+		AddProvider("apns", s.apns).
+		AddProvider("webpush", s.webpush)
+	s.Require().NoError(s.service.AddRoute(message.IOS, "apns", "firebase"))
+	s.Require().NoError(s.service.AddRoute(message.Android, "firebase", ""))
+	s.Require().NoError(s.service.AddRoute(message.Web, "webpush", ""))
+}
+
+func (s *RoutingPushServiceTestSuite) Test_AddRoute_Provider_Not_Declared() {
+	err := s.service.AddRoute(message.IOS, "apns", "unknown")
+
+	s.Assert().ErrorIs(err, routing.ErrProviderNotDeclared)
+}
+
+func (s *RoutingPushServiceTestSuite) Test_SendMulticast_Routes_By_Platform() {
+	result, err := s.service.SendMulticast(context.Background(), s.message(), s.target())
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(4, result.SuccessCount)
+	s.Assert().Equal(0, result.FailureCount)
+	if s.Assert().Len(s.apns.Requests(), 1) {
+		s.Assert().Equal([]string{"ios_1", "ios_2"}, s.apns.Requests()[0].DeviceTokens)
+		s.Assert().Equal([]message.Platform{message.IOS}, s.apns.Requests()[0].Platforms)
+	}
+	if s.Assert().Len(s.firebase.Requests(), 1) {
+		s.Assert().Equal([]string{"android_1"}, s.firebase.Requests()[0].DeviceTokens)
+	}
+	if s.Assert().Len(s.webpush.Requests(), 1) {
+		s.Assert().Equal([]string{"web_1"}, s.webpush.Requests()[0].DeviceTokens)
+	}
+}
+
+func (s *RoutingPushServiceTestSuite) Test_SendMulticast_Failover() {
+	s.apns.failing = true
+
+	result, err := s.service.SendMulticast(context.Background(), s.message(), s.target())
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(4, result.SuccessCount)
+	s.Assert().Len(s.apns.Requests(), 1)
+	// the tokens of iOS are sent by the secondary provider
+	s.Assert().Len(s.firebase.Requests(), 2)
+}
+
+func (s *RoutingPushServiceTestSuite) Test_SendMulticast_Circuit_Open() {
+	s.apns.failing = true
+	s.service.SendMulticast(context.Background(), s.message(), s.target())
+	s.service.SendMulticast(context.Background(), s.message(), s.target())
+	s.Assert().Len(s.apns.Requests(), 2)
+
+	// the circuit of the primary is open, it is skipped
+	s.apns.failing = false
+	result, err := s.service.SendMulticast(context.Background(), s.message(), s.target())
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(4, result.SuccessCount)
+	s.Assert().Len(s.apns.Requests(), 2)
+	s.Assert().Len(s.firebase.Requests(), 6)
+}
+
+func (s *RoutingPushServiceTestSuite) Test_SendMulticast_Half_Open_Trial_Failed_With_Plain_Error() {
+	s.firebase.failing = true
+	service := routing.NewRoutingPushService(1, 10*time.Millisecond).AddProvider("firebase", s.firebase)
+	s.Require().NoError(service.AddRoute(message.Android, "firebase", ""))
+	target := &message.MulticastTarget{
+		DeviceTokens: []string{"android_1"},
+		Platforms:    []message.Platform{message.Android},
+	}
+	service.SendMulticast(context.Background(), s.message(), target)
+
+	// the trial call fails with an error other than a send error
+	time.Sleep(20 * time.Millisecond)
+	s.firebase.err = errors.New("request signing failed")
+	_, trialErr := service.SendMulticast(context.Background(), s.message(), target)
+	_, err := service.SendMulticast(context.Background(), s.message(), target)
+
+	s.Assert().Error(trialErr)
+	s.Assert().ErrorIs(err, routing.ErrProvidersUnavailable, "the circuit should be open again")
+	s.Assert().Len(s.firebase.Requests(), 2)
+}
+
+func (s *RoutingPushServiceTestSuite) Test_SendMulticast_Merges_Failed_Route() {
+	s.webpush.failing = true
+	target := s.target()
+	target.DeviceTokens = append(target.DeviceTokens, "unknown_1")
+
+	result, err := s.service.SendMulticast(context.Background(), s.message(), target)
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(3, result.SuccessCount)
+	s.Assert().Equal(2, result.FailureCount)
+	retryTokens, retryAfter := result.GetRetryableFailures()
+	s.Assert().Equal([]string{"web_1"}, retryTokens)
+	s.Assert().Equal(time.Second, retryAfter)
+	s.Assert().Empty(result.GetInvalidTokens(), "the unrouted tokens should not be removed")
+}
+
+func (s *RoutingPushServiceTestSuite) Test_SendMulticast_Every_Route_Failed() {
+	s.webpush.failing = true
+	target := &message.MulticastTarget{
+		DeviceTokens: []string{"web_1", "web_2"},
+		Platforms:    []message.Platform{message.Web},
+	}
+
+	_, err := s.service.SendMulticast(context.Background(), s.message(), target)
+
+	var sendErr *results.SendError
+	s.Assert().ErrorAs(err, &sendErr)
+}
+
+func (s *RoutingPushServiceTestSuite) message() *message.Message {
+	return &message.Message{
+		Title: "title",
+		Body:  "body",
+	}
+}
+
+func (s *RoutingPushServiceTestSuite) target() *message.MulticastTarget {
+	return &message.MulticastTarget{
+		DeviceTokens: []string{"ios_1", "android_1", "ios_2", "web_1"},
+		Platforms:    []message.Platform{message.IOS, message.Android, message.Web},
+		DevicePlatforms: map[string]message.Platform{
+			"ios_1":     message.IOS,
+			"ios_2":     message.IOS,
+			"android_1": message.Android,
+			"web_1":     message.Web,
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	msg "duolingo/libraries/push_notification/message"
+)
+
+var (
+	ErrPlatformNotSupported = errors.New("web push only delivers to the web platform")
+	ErrWebPushKeysMissing   = errors.New("web push subscription keys must be specified for each device token")
+)
+
+// The push services keep the undelivered messages for 4 weeks at most
+const defaultTTL = 28 * 24 * time.Hour
+
+// WebPushMulticast is a notification sent to each subscription by its own
+// request, the payload is encrypted for each subscription when sent.
+type WebPushMulticast struct {
+	Tokens  []string
+	Keys    map[string]*msg.WebPushKeys
+	Headers map[string]string
+	Payload []byte
+}
+
+// WebPushPayload is read by the service worker of the web app on "push" events
+type WebPushPayload struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Icon  string `json:"icon,omitempty"`
+}
+
+type WebPushMessageBuilder struct {
+}
+
+func NewWebPushMessageBuilder() *WebPushMessageBuilder {
+	return &WebPushMessageBuilder{}
+}
+
+func (builder *WebPushMessageBuilder) BuildMulticast(
+	message *msg.Message,
+	target *msg.MulticastTarget,
+) (any, error) {
+	if err := message.Validate(); err != nil {
+		return nil, err
+	}
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(target.Platforms, msg.Web) {
+		return nil, ErrPlatformNotSupported
+	}
+	for _, token := range target.DeviceTokens {
+		if keys := target.WebPushKeys[token]; keys == nil || keys.P256dh == "" || keys.Auth == "" {
+			return nil, ErrWebPushKeysMissing
+		}
+	}
+	payload, err := json.Marshal(&WebPushPayload{
+		Title: message.Title,
+		Body:  message.Body,
+		Icon:  message.Icon,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ttl := defaultTTL
+	if message.Expiration != time.Duration(0) {
+		ttl = message.Expiration
+	}
+	headers := map[string]string{
+		"TTL":     fmt.Sprint(int64(ttl.Seconds())),
+		"Urgency": getUrgency(message.Priority),
+	}
+	if message.CollapseKey != "" {
+		headers["Topic"] = message.CollapseKey
+	}
+	return &WebPushMulticast{
+		Tokens:  target.DeviceTokens,
+		Keys:    target.WebPushKeys,
+		Headers: headers,
+		Payload: payload,
+	}, nil
+}
+
+func getUrgency(priority msg.Priority) string {
+	urgencies := map[msg.Priority]string{
+		msg.PriorityHigh:   "high",
+		msg.PriorityNormal: "normal",
+	}
+	if found, ok := urgencies[priority]; ok {
+		return found
+	}
+	return "normal"
+}
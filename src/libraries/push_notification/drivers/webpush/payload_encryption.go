@@ -0,0 +1,113 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+const recordSize = 4096
+
+var (
+	ErrInvalidSubscriptionKeys = errors.New("web push subscription keys are invalid")
+	ErrPayloadTooLarge         = errors.New("web push payload exceeds a single record")
+)
+
+// EncryptPayload encrypts the payload for the subscription (RFC 8291), with the
+// "aes128gcm" content coding (RFC 8188). The payload is sent as a single record.
+func EncryptPayload(plaintext []byte, p256dh string, auth string) ([]byte, error) {
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encryptPayload(plaintext, p256dh, auth, asPrivate, salt)
+}
+
+func encryptPayload(
+	plaintext []byte,
+	p256dh string,
+	auth string,
+	asPrivate *ecdh.PrivateKey,
+	salt []byte,
+) ([]byte, error) {
+	uaPublicBytes, err := decodeBase64(p256dh)
+	if err != nil {
+		return nil, ErrInvalidSubscriptionKeys
+	}
+	authSecret, err := decodeBase64(auth)
+	if err != nil || len(authSecret) == 0 {
+		return nil, ErrInvalidSubscriptionKeys
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, ErrInvalidSubscriptionKeys
+	}
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, ErrInvalidSubscriptionKeys
+	}
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+
+	// combine the ECDH and authentication secrets
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublicBytes...)
+	keyInfo = append(keyInfo, asPublicBytes...)
+	ikm := hkdf(authSecret, ecdhSecret, keyInfo, 32)
+
+	// derive the content encryption key and nonce
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// the 0x02 delimiter marks the last record, without padding
+	record := append(append([]byte{}, plaintext...), 0x02)
+	if len(record)+gcm.Overhead() > recordSize {
+		return nil, ErrPayloadTooLarge
+	}
+	ciphertext := gcm.Seal(nil, nonce, record, nil)
+
+	// header: salt (16) | record size (4) | key id length (1) | key id
+	header := make([]byte, 0, 21+len(asPublicBytes))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublicBytes)))
+	header = append(header, asPublicBytes...)
+
+	return append(header, ciphertext...), nil
+}
+
+// hkdf extracts and expands the key material (RFC 5869), the length is at most
+// a single SHA-256 block.
+func hkdf(salt []byte, ikm []byte, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(ikm)
+	prk := extract.Sum(nil)
+
+	expand := hmac.New(sha256.New, prk)
+	expand.Write(info)
+	expand.Write([]byte{0x01})
+	return expand.Sum(nil)[:length]
+}
+
+// decodeBase64 accepts the base64url keys with or without padding,
+// as the browsers and the libraries disagree on the padding.
+func decodeBase64(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
@@ -0,0 +1,259 @@
+package test_suites
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"duolingo/libraries/push_notification/drivers/webpush"
+	"duolingo/libraries/push_notification/message"
+	"duolingo/libraries/push_notification/results"
+
+	"github.com/stretchr/testify/suite"
+)
+
+/*
+PushServiceTestSuite sends to a local HTTP/2 stub of a browser push service,
+the stub responds by the subscription endpoint:
+  - ".../gone_*" is rejected with 410 Gone
+  - ".../forbidden_*" is rejected with 403 Forbidden
+  - ".../throttled_*" is rejected with 429 Too Many Requests, retry after 3 seconds
+  - the others are accepted, and their payload is decrypted by the stub
+*/
+type PushServiceTestSuite struct {
+	suite.Suite
+	stub        *httptest.Server
+	service     *webpush.WebPushService
+	vapidPublic string
+
+	// the keys of the subscriptions, as created by the browser
+	uaPrivate *ecdh.PrivateKey
+	authKey   []byte
+
+	mu       sync.Mutex
+	requests []*http.Request
+	payloads []string
+}
+
+func NewPushServiceTestSuite() *PushServiceTestSuite {
+	return &PushServiceTestSuite{}
+}
+
+func (s *PushServiceTestSuite) SetupSuite() {
+	s.stub = httptest.NewUnstartedServer(http.HandlerFunc(s.respond))
+	s.stub.EnableHTTP2 = true
+	s.stub.StartTLS()
+
+	s.uaPrivate, _ = ecdh.P256().GenerateKey(rand.Reader)
+	s.authKey = make([]byte, 16)
+	rand.Read(s.authKey)
+
+	vapidPrivate, _ := ecdh.P256().GenerateKey(rand.Reader)
+	s.vapidPublic = encode(vapidPrivate.PublicKey().Bytes())
+	factory, err := webpush.NewWebPushNotiFactory(s.vapidPublic, encode(vapidPrivate.Bytes()), "mailto:push@example.com")
+	if err != nil {
+		panic(err)
+	}
+	factory.SetHTTPClient(s.stub.Client())
+	service, _ := factory.CreatePushService()
+	s.service = service.(*webpush.WebPushService)
+}
+
+func (s *PushServiceTestSuite) TearDownSuite() {
+	s.stub.Close()
+}
+
+func (s *PushServiceTestSuite) SetupTest() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests, s.payloads = nil, nil
+}
+
+func (s *PushServiceTestSuite) Test_SendMulticast() {
+	msg := &message.Message{
+		Title:       "title",
+		Body:        "body",
+		CollapseKey: "collapse",
+		Priority:    message.PriorityHigh,
+		Expiration:  time.Hour,
+	}
+	target := s.target("valid_1", "valid_2")
+	result, err := s.service.SendMulticast(context.Background(), msg, target)
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(2, result.SuccessCount)
+	s.Assert().Equal(0, result.FailureCount)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Assert().Len(s.requests, 2) {
+		req := s.requests[0]
+		s.Assert().Equal("HTTP/2.0", req.Proto)
+		s.Assert().Equal(http.MethodPost, req.Method)
+		s.Assert().Equal("aes128gcm", req.Header.Get("Content-Encoding"))
+		s.Assert().Equal("3600", req.Header.Get("TTL"))
+		s.Assert().Equal("high", req.Header.Get("Urgency"))
+		s.Assert().Equal("collapse", req.Header.Get("Topic"))
+		s.Assert().True(strings.HasPrefix(req.Header.Get("Authorization"), "vapid t="))
+		s.Assert().True(strings.HasSuffix(req.Header.Get("Authorization"), ", k="+s.vapidPublic))
+		s.Assert().Equal(
+			req.Header.Get("Authorization"),
+			s.requests[1].Header.Get("Authorization"),
+			"the VAPID token of the push service origin should be reused",
+		)
+		s.Assert().JSONEq(`{"title":"title","body":"body"}`, s.payloads[0])
+	}
+}
+
+func (s *PushServiceTestSuite) Test_SendMulticast_Failures() {
+	msg := &message.Message{
+		Title: "title",
+		Body:  "body",
+	}
+	target := s.target("valid_1", "gone_1", "forbidden_1", "throttled_1", "invalid_keys_1")
+	invalidKeys := s.stub.URL + "/push/invalid_keys_1"
+	target.WebPushKeys[invalidKeys] = &message.WebPushKeys{P256dh: "bm90LWEta2V5", Auth: encode(s.authKey)}
+	result, err := s.service.SendMulticast(context.Background(), msg, target)
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(1, result.SuccessCount)
+	s.Assert().Equal(4, result.FailureCount)
+
+	retryTokens, retryAfter := result.GetRetryableFailures()
+	s.Assert().Equal([]string{s.stub.URL + "/push/throttled_1"}, retryTokens)
+	s.Assert().Equal(3*time.Second, retryAfter)
+
+	reasons := map[string]results.FailureReason{}
+	for _, failure := range result.Failures {
+		reasons[strings.TrimPrefix(failure.Token, s.stub.URL+"/push/")] = failure.Reason
+	}
+	s.Assert().Equal(results.FailureUnregistered, reasons["gone_1"])
+	s.Assert().Equal(results.FailureSenderIdMismatch, reasons["forbidden_1"])
+	s.Assert().Equal(results.FailureTransient, reasons["throttled_1"])
+	s.Assert().Equal(results.FailureInvalidToken, reasons["invalid_keys_1"])
+}
+
+func (s *PushServiceTestSuite) Test_SendMulticast_Keys_Missing() {
+	msg := &message.Message{
+		Title: "title",
+		Body:  "body",
+	}
+	target := s.target("valid_1")
+	target.DeviceTokens = append(target.DeviceTokens, s.stub.URL+"/push/without_keys")
+	_, err := s.service.SendMulticast(context.Background(), msg, target)
+
+	s.Assert().Error(err)
+}
+
+func (s *PushServiceTestSuite) Test_SendMulticast_Android_Not_Supported() {
+	msg := &message.Message{
+		Title: "title",
+		Body:  "body",
+	}
+	target := s.target("valid_1")
+	target.Platforms = []message.Platform{message.Android}
+	_, err := s.service.SendMulticast(context.Background(), msg, target)
+
+	s.Assert().Error(err)
+}
+
+// target returns the stub endpoints, subscribed with the suite keys
+func (s *PushServiceTestSuite) target(ids ...string) *message.MulticastTarget {
+	target := &message.MulticastTarget{
+		Platforms:   []message.Platform{message.Web},
+		WebPushKeys: map[string]*message.WebPushKeys{},
+	}
+	for _, id := range ids {
+		endpoint := s.stub.URL + "/push/" + id
+		target.DeviceTokens = append(target.DeviceTokens, endpoint)
+		target.WebPushKeys[endpoint] = &message.WebPushKeys{
+			P256dh: encode(s.uaPrivate.PublicKey().Bytes()),
+			Auth:   encode(s.authKey),
+		}
+	}
+	return target
+}
+
+func (s *PushServiceTestSuite) respond(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	id := strings.TrimPrefix(r.URL.Path, "/push/")
+	switch {
+	case strings.HasPrefix(id, "gone_"):
+		w.WriteHeader(http.StatusGone)
+	case strings.HasPrefix(id, "forbidden_"):
+		w.WriteHeader(http.StatusForbidden)
+	case strings.HasPrefix(id, "throttled_"):
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+	default:
+		payload, err := s.decrypt(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		s.payloads = append(s.payloads, string(payload))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// decrypt reads the "aes128gcm" body as the browser does (RFC 8291)
+func (s *PushServiceTestSuite) decrypt(body []byte) ([]byte, error) {
+	salt := body[:16]
+	keyIdLen := int(body[20])
+	asPublicBytes := body[21 : 21+keyIdLen]
+	ciphertext := body[21+keyIdLen:]
+	if binary.BigEndian.Uint32(body[16:20]) != 4096 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+	ecdhSecret, err := s.uaPrivate.ECDH(asPublic)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := append([]byte("WebPush: info\x00"), s.uaPrivate.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, asPublicBytes...)
+	ikm := hkdf(s.authKey, ecdhSecret, keyInfo, 32)
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	record, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+	// remove the padding delimiter of the last record
+	return record[:strings.LastIndexByte(string(record), 0x02)], nil
+}
+
+func hkdf(salt []byte, ikm []byte, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(ikm)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write(info)
+	expand.Write([]byte{0x01})
+	return expand.Sum(nil)[:length]
+}
+
+func encode(key []byte) string {
+	return base64.RawURLEncoding.EncodeToString(key)
+}
@@ -0,0 +1,122 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidVAPIDKeys = errors.New("vapid keys must be a base64url P-256 key pair")
+)
+
+/*
+VAPIDSigner identifies the application server to the push services (RFC 8292),
+with a JWT signed by the VAPID key pair. The JWT audience is the origin of the
+push service, a JWT is reused for the same origin until half of its validity
+has passed.
+*/
+type VAPIDSigner struct {
+	key       *ecdsa.PrivateKey
+	publicKey string
+	subject   string
+	validity  time.Duration
+
+	mu     sync.Mutex
+	tokens map[string]*vapidToken
+}
+
+type vapidToken struct {
+	jwt       string
+	expiresAt time.Time
+}
+
+// NewVAPIDSigner takes the base64url encoded keys, the uncompressed public key
+// and the private key scalar. The subject is a contact of the application
+// server (e.g. "mailto:push@example.com").
+func NewVAPIDSigner(publicKey string, privateKey string, subject string) (*VAPIDSigner, error) {
+	publicBytes, err := decodeBase64(publicKey)
+	if err != nil {
+		return nil, ErrInvalidVAPIDKeys
+	}
+	privateBytes, err := decodeBase64(privateKey)
+	if err != nil || len(privateBytes) != 32 {
+		return nil, ErrInvalidVAPIDKeys
+	}
+	// the public key must be the one of the private key
+	ecdhKey, err := ecdh.P256().NewPrivateKey(privateBytes)
+	if err != nil || !bytes.Equal(ecdhKey.PublicKey().Bytes(), publicBytes) {
+		return nil, ErrInvalidVAPIDKeys
+	}
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(publicBytes[1:33]),
+			Y:     new(big.Int).SetBytes(publicBytes[33:65]),
+		},
+		D: new(big.Int).SetBytes(privateBytes),
+	}
+	return &VAPIDSigner{
+		key:       key,
+		publicKey: base64.RawURLEncoding.EncodeToString(publicBytes),
+		subject:   subject,
+		validity:  12 * time.Hour,
+		tokens:    make(map[string]*vapidToken),
+	}, nil
+}
+
+// Authorization returns the "Authorization" header for the push endpoint
+func (signer *VAPIDSigner) Authorization(endpoint string) (string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	audience := parsed.Scheme + "://" + parsed.Host
+
+	signer.mu.Lock()
+	defer signer.mu.Unlock()
+	token, exists := signer.tokens[audience]
+	if !exists || time.Until(token.expiresAt) < signer.validity/2 {
+		expiresAt := time.Now().Add(signer.validity)
+		jwt, err := signer.sign(audience, expiresAt)
+		if err != nil {
+			return "", err
+		}
+		token = &vapidToken{jwt: jwt, expiresAt: expiresAt}
+		signer.tokens[audience] = token
+	}
+	return "vapid t=" + token.jwt + ", k=" + signer.publicKey, nil
+}
+
+func (signer *VAPIDSigner) sign(audience string, expiresAt time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	claims, _ := json.Marshal(map[string]any{
+		"aud": audience,
+		"exp": expiresAt.Unix(),
+		"sub": signer.subject,
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, signer.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// the ES256 signature is the 32 bytes r and s concatenated
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
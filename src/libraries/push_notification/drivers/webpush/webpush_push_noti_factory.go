@@ -0,0 +1,52 @@
+package webpush
+
+import (
+	"net/http"
+
+	push_noti "duolingo/libraries/push_notification"
+	"duolingo/libraries/push_notification/message"
+
+	driver "duolingo/libraries/push_notification/drivers/webpush/message"
+)
+
+type WebPushNotiFactory struct {
+	client *http.Client
+	signer *VAPIDSigner
+}
+
+// NewWebPushNotiFactory signs the requests with the VAPID key pair, the
+// subscriptions must be created with the same public key by the web app.
+func NewWebPushNotiFactory(
+	vapidPublicKey string,
+	vapidPrivateKey string,
+	subject string,
+) (*WebPushNotiFactory, error) {
+	signer, err := NewVAPIDSigner(vapidPublicKey, vapidPrivateKey, subject)
+	if err != nil {
+		return nil, err
+	}
+	factory := &WebPushNotiFactory{
+		client: &http.Client{},
+		signer: signer,
+	}
+	return factory, nil
+}
+
+// SetHTTPClient replaces the HTTP client, e.g. with the client trusting a stub server
+func (factory *WebPushNotiFactory) SetHTTPClient(client *http.Client) *WebPushNotiFactory {
+	factory.client = client
+	return factory
+}
+
+func (factory *WebPushNotiFactory) CreatePushService() (push_noti.PushService, error) {
+	service := NewWebPushService(
+		factory.client,
+		factory.signer,
+		factory.CreateMessageBuilder(),
+	)
+	return service, nil
+}
+
+func (factory *WebPushNotiFactory) CreateMessageBuilder() message.MessageBuilder {
+	return driver.NewWebPushMessageBuilder()
+}
@@ -0,0 +1,197 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	events "duolingo/libraries/events/facade"
+	"duolingo/libraries/push_notification/message"
+	"duolingo/libraries/push_notification/results"
+
+	driver "duolingo/libraries/push_notification/drivers/webpush/message"
+)
+
+var (
+	ErrMessageDriverDriverMismatch = errors.New("message_builder.MessageBuilder driver mismatched")
+)
+
+/*
+### Notions:
+ 1. The device token of a web device is its subscription endpoint, the
+    notification is posted to each endpoint, up to "concurrency" at once.
+ 2. The expired subscriptions (404, 410) are reported as unregistered, and
+    the subscriptions created for another VAPID key (403) as sender mismatch.
+*/
+type WebPushService struct {
+	client      *http.Client
+	signer      *VAPIDSigner
+	builder     message.MessageBuilder
+	concurrency int
+}
+
+func NewWebPushService(
+	client *http.Client,
+	signer *VAPIDSigner,
+	builder message.MessageBuilder,
+) *WebPushService {
+	return &WebPushService{
+		client:      client,
+		signer:      signer,
+		builder:     builder,
+		concurrency: 20,
+	}
+}
+
+func (service *WebPushService) SetConcurrency(concurrency int) *WebPushService {
+	service.concurrency = max(1, concurrency)
+	return service
+}
+
+func (service *WebPushService) SendMulticast(
+	ctx context.Context,
+	noti *message.Message,
+	target *message.MulticastTarget,
+) (
+	*results.MulticastResult,
+	error,
+) {
+	var multicast any
+	var result *results.MulticastResult
+	var err error
+
+	evt := events.Start(ctx, "push_noti.push_service.send_multicast", map[string]any{
+		"driver":        "webpush",
+		"devices_total": len(target.DeviceTokens),
+		"platforms":     strings.Join(message.StrPlatforms(target.Platforms...), ", "),
+	})
+	defer func() {
+		if err == nil {
+			evt.SetData("success_total", result.SuccessCount)
+			evt.SetData("failure_total", result.FailureCount)
+		}
+		events.End(evt, true, err, nil)
+	}()
+
+	multicast, err = service.builder.BuildMulticast(noti, target)
+	if err != nil {
+		return nil, err
+	}
+	webPushMulticast, ok := multicast.(*driver.WebPushMulticast)
+	if !ok || webPushMulticast == nil {
+		panic(ErrMessageDriverDriverMismatch)
+	}
+
+	failures := make([]*results.TokenFailure, len(webPushMulticast.Tokens))
+	slots := make(chan struct{}, service.concurrency)
+	wg := new(sync.WaitGroup)
+	for i, token := range webPushMulticast.Tokens {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			failures[i] = service.send(ctx, token, webPushMulticast)
+		}()
+	}
+	wg.Wait()
+
+	result, err = newMulticastResult(failures, webPushMulticast.Tokens), nil
+
+	return result, err
+}
+
+// send posts the notification to a subscription endpoint, it returns nil if
+// the notification has been accepted.
+func (service *WebPushService) send(
+	ctx context.Context,
+	endpoint string,
+	multicast *driver.WebPushMulticast,
+) *results.TokenFailure {
+	keys := multicast.Keys[endpoint]
+	body, err := EncryptPayload(multicast.Payload, keys.P256dh, keys.Auth)
+	if errors.Is(err, ErrInvalidSubscriptionKeys) {
+		return &results.TokenFailure{Token: endpoint, Reason: results.FailureInvalidToken}
+	}
+	if err != nil {
+		return &results.TokenFailure{Token: endpoint, Reason: results.FailureUnknown}
+	}
+	authorization, err := service.signer.Authorization(endpoint)
+	if err != nil {
+		// the endpoint is not a valid URL
+		return &results.TokenFailure{Token: endpoint, Reason: results.FailureInvalidToken}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return &results.TokenFailure{Token: endpoint, Reason: results.FailureInvalidToken}
+	}
+	for name, value := range multicast.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", authorization)
+
+	res, err := service.client.Do(req)
+	if err != nil {
+		// the connection failures are retried
+		return &results.TokenFailure{Token: endpoint, Reason: results.FailureTransient}
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	return &results.TokenFailure{
+		Token:      endpoint,
+		Reason:     classifyFailure(res.StatusCode),
+		RetryAfter: retryAfter(res.Header.Get("Retry-After")),
+	}
+}
+
+func newMulticastResult(failures []*results.TokenFailure, tokens []string) *results.MulticastResult {
+	result := &results.MulticastResult{}
+	for i := range failures {
+		if failures[i] == nil {
+			result.SuccessCount++
+			continue
+		}
+		result.FailureCount++
+		result.FailureTokens = append(result.FailureTokens, tokens[i])
+		result.Failures = append(result.Failures, failures[i])
+	}
+	return result
+}
+
+// classifyFailure maps the push service responses, the throttled and the
+// server errors are transient.
+func classifyFailure(status int) results.FailureReason {
+	switch {
+	case status == http.StatusNotFound, status == http.StatusGone:
+		return results.FailureUnregistered
+	case status == http.StatusForbidden:
+		return results.FailureSenderIdMismatch
+	case status == http.StatusTooManyRequests, status >= http.StatusInternalServerError:
+		return results.FailureTransient
+	default:
+		return results.FailureUnknown
+	}
+}
+
+// retryAfter reads the "Retry-After" header, which is either a number of
+// seconds or a HTTP date.
+func retryAfter(header string) time.Duration {
+	if seconds, parseErr := strconv.Atoi(header); parseErr == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	if date, parseErr := http.ParseTime(header); parseErr == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
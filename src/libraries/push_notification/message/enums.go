@@ -9,6 +9,7 @@ type Visibility string
 const (
 	IOS     Platform = "ios"
 	Android Platform = "android"
+	Web     Platform = "web"
 )
 
 const (
@@ -12,6 +12,22 @@ var (
 type MulticastTarget struct {
 	Platforms    []Platform
 	DeviceTokens []string
+
+	// The platform of each device token, so that the tokens of several platforms
+	// can be routed to their own push service. Optional for a single platform.
+	DevicePlatforms map[string]Platform
+
+	// The keys of the Web Push subscriptions by their device token, which is
+	// the subscription endpoint. Only required for the web platform.
+	WebPushKeys map[string]*WebPushKeys
+}
+
+// WebPushKeys are the keys the browser generated for a Web Push subscription
+type WebPushKeys struct {
+	// The user agent public key (P-256, base64url encoded)
+	P256dh string
+	// The authentication secret (base64url encoded)
+	Auth string
 }
 
 func (t *MulticastTarget) Validate() error {
@@ -1,7 +1,66 @@
 package results
 
+import "time"
+
+type FailureReason string
+
+const (
+	FailureUnregistered     FailureReason = "unregistered"
+	FailureInvalidToken     FailureReason = "invalid_token"
+	FailureSenderIdMismatch FailureReason = "sender_id_mismatch"
+	FailureTransient        FailureReason = "transient"
+	FailureUnknown          FailureReason = "unknown"
+)
+
+// IsPermanent tells whether the token will never be delivered, such tokens
+// should be removed rather than retried.
+func (reason FailureReason) IsPermanent() bool {
+	return reason == FailureUnregistered ||
+		reason == FailureInvalidToken ||
+		reason == FailureSenderIdMismatch
+}
+
+// IsRetryable tells whether the token may be delivered by a later attempt
+func (reason FailureReason) IsRetryable() bool {
+	return reason == FailureTransient
+}
+
+type TokenFailure struct {
+	Token  string
+	Reason FailureReason
+
+	// The wait requested by the push service before retrying, zero if not provided
+	RetryAfter time.Duration
+}
+
 type MulticastResult struct {
 	SuccessCount  int
 	FailureCount  int
 	FailureTokens []string
+	Failures      []*TokenFailure
+}
+
+// GetInvalidTokens returns the tokens failed permanently
+func (result *MulticastResult) GetInvalidTokens() []string {
+	tokens := []string{}
+	for _, failure := range result.Failures {
+		if failure.Reason.IsPermanent() {
+			tokens = append(tokens, failure.Token)
+		}
+	}
+	return tokens
+}
+
+// GetRetryableFailures returns the tokens failed transiently, along with the
+// longest wait requested by the push service among them.
+func (result *MulticastResult) GetRetryableFailures() ([]string, time.Duration) {
+	tokens := []string{}
+	var retryAfter time.Duration
+	for _, failure := range result.Failures {
+		if failure.Reason.IsRetryable() {
+			tokens = append(tokens, failure.Token)
+			retryAfter = max(retryAfter, failure.RetryAfter)
+		}
+	}
+	return tokens, retryAfter
 }
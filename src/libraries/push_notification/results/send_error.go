@@ -0,0 +1,20 @@
+package results
+
+import "time"
+
+// SendError is returned when the whole send request failed,
+// none of the target tokens has been delivered.
+type SendError struct {
+	Err error
+
+	// The wait requested by the push service before retrying, zero if not provided
+	RetryAfter time.Duration
+}
+
+func (e *SendError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,52 @@
+package test_suites
+
+import (
+	"time"
+
+	"duolingo/libraries/push_notification/results"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MulticastResultTestSuite struct {
+	suite.Suite
+}
+
+func NewMulticastResultTestSuite() *MulticastResultTestSuite {
+	return &MulticastResultTestSuite{}
+}
+
+func (s *MulticastResultTestSuite) Test_GetInvalidTokens() {
+	result := &results.MulticastResult{
+		SuccessCount: 1,
+		FailureCount: 4,
+		Failures: []*results.TokenFailure{
+			{Token: "t1", Reason: results.FailureUnregistered},
+			{Token: "t2", Reason: results.FailureTransient},
+			{Token: "t3", Reason: results.FailureInvalidToken},
+			{Token: "t4", Reason: results.FailureSenderIdMismatch},
+		},
+	}
+	empty := &results.MulticastResult{SuccessCount: 1}
+
+	s.Assert().Equal([]string{"t1", "t3", "t4"}, result.GetInvalidTokens())
+	s.Assert().Empty(empty.GetInvalidTokens())
+}
+
+func (s *MulticastResultTestSuite) Test_GetRetryableFailures() {
+	result := &results.MulticastResult{
+		SuccessCount: 1,
+		FailureCount: 4,
+		Failures: []*results.TokenFailure{
+			{Token: "t1", Reason: results.FailureTransient, RetryAfter: time.Second},
+			{Token: "t2", Reason: results.FailureUnregistered},
+			{Token: "t3", Reason: results.FailureTransient, RetryAfter: 3 * time.Second},
+			{Token: "t4", Reason: results.FailureUnknown},
+		},
+	}
+
+	tokens, retryAfter := result.GetRetryableFailures()
+
+	s.Assert().Equal([]string{"t1", "t3"}, tokens)
+	s.Assert().Equal(3*time.Second, retryAfter)
+}
@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	connection "duolingo/libraries/connection_manager/drivers/redis"
+	events "duolingo/libraries/events/facade"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+GCRA (generic cell rate algorithm), the key holds the theoretical arrival time
+(TAT) of the next permit in microseconds. Each permit pushes the TAT forward by
+the emission interval, the permits are usable once the TAT minus the burst
+tolerance has been reached. The time is read from the redis server, so that
+the callers with clock skew still share the same limit.
+*/
+var reservePermitsScript = redis.NewScript(`
+	local now = redis.call("TIME")
+	local now_us = tonumber(now[1]) * 1000000 + tonumber(now[2])
+	local interval = tonumber(ARGV[1])
+	local tolerance = tonumber(ARGV[2])
+	local count = tonumber(ARGV[3])
+
+	local tat = tonumber(redis.call("GET", KEYS[1]) or now_us)
+	tat = math.max(tat, now_us) + interval * count
+	redis.call("SET", KEYS[1], string.format("%d", tat), "PX", math.ceil((tat - now_us) / 1000) + 1)
+
+	return math.max(0, math.ceil(tat - tolerance - now_us))
+`)
+
+/*
+### Notions:
+ 1. The limit is "rate" permits per second, shared by the limiters of the
+    same scope on every replica.
+ 2. Up to "burst" permits are usable at once after the limiter has been idle,
+    the following permits are spaced evenly by the emission interval.
+*/
+type RedisRateLimiter struct {
+	connection.RedisClient
+
+	scope string
+	rate  int
+	burst int
+}
+
+func NewRedisRateLimiter(client *connection.RedisClient, scope string, rate int) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		RedisClient: *client,
+		scope:       scope,
+		rate:        max(1, rate),
+		burst:       1,
+	}
+}
+
+func (limiter *RedisRateLimiter) SetBurst(burst int) *RedisRateLimiter {
+	limiter.burst = max(1, burst)
+	return limiter
+}
+
+func (limiter *RedisRateLimiter) Reserve(ctx context.Context, count int) (time.Duration, error) {
+	var waitMicros int64
+	var err error
+
+	evt := events.Start(ctx, "rate_limiter.redis.reserve", map[string]any{
+		"operation_name": "reserve",
+		"scope":          limiter.scope,
+	})
+	defer events.End(evt, true, err, nil)
+
+	interval := time.Second.Microseconds() / int64(limiter.rate)
+	tolerance := interval * int64(limiter.burst)
+	err = limiter.ExecuteClosure(evt.Context(), limiter.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		result, scriptErr := reservePermitsScript.Run(
+			timeoutCtx,
+			rdb,
+			[]string{limiter.limitKey()},
+			interval,
+			tolerance,
+			count,
+		).Int64()
+		waitMicros = result
+		return scriptErr
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(waitMicros) * time.Microsecond, nil
+}
+
+func (limiter *RedisRateLimiter) limitKey() string {
+	return "rate_limiter:" + limiter.scope
+}
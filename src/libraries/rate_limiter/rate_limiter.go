@@ -0,0 +1,34 @@
+package rate_limiter
+
+import (
+	"context"
+	"time"
+)
+
+type RateLimiter interface {
+	// Reserve takes a number of permits from the limit shared by the callers,
+	// it returns the wait before the permits can be used. The permits are
+	// taken even if the caller has to wait, so that the waiting callers are
+	// served in order rather than competing for the next permits.
+	Reserve(ctx context.Context, count int) (time.Duration, error)
+}
+
+// Wait reserves the permits and blocks until they can be used,
+// or until the context is done.
+func Wait(ctx context.Context, limiter RateLimiter, count int) error {
+	wait, err := limiter.Reserve(ctx, count)
+	if err != nil {
+		return err
+	}
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,54 @@
+package test_suites
+
+import (
+	"context"
+	"time"
+
+	"duolingo/libraries/rate_limiter"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimiterTestSuite struct {
+	suite.Suite
+	limiter rate_limiter.RateLimiter
+	rate    int
+	burst   int
+}
+
+// NewRateLimiterTestSuite expects a fresh limiter, whose permits have not been taken
+func NewRateLimiterTestSuite(limiter rate_limiter.RateLimiter, rate int, burst int) *RateLimiterTestSuite {
+	return &RateLimiterTestSuite{
+		limiter: limiter,
+		rate:    rate,
+		burst:   burst,
+	}
+}
+
+func (s *RateLimiterTestSuite) Test_Reserve() {
+	interval := time.Second / time.Duration(s.rate)
+
+	// the burst permits are usable right away
+	burstWait, burstErr := s.limiter.Reserve(context.Background(), s.burst)
+	nextWait, nextErr := s.limiter.Reserve(context.Background(), s.burst)
+
+	s.Assert().NoError(burstErr)
+	s.Assert().Zero(burstWait)
+	s.Assert().NoError(nextErr)
+	s.Assert().InDelta(float64(time.Duration(s.burst)*interval), float64(nextWait), float64(interval))
+}
+
+func (s *RateLimiterTestSuite) Test_Wait() {
+	interval := time.Second / time.Duration(s.rate)
+
+	start := time.Now()
+	err := rate_limiter.Wait(context.Background(), s.limiter, s.burst)
+	s.Assert().NoError(err)
+	s.Assert().Less(time.Since(start), time.Duration(3*s.burst)*interval)
+
+	// the wait is longer than the context deadline
+	ctx, cancel := context.WithTimeout(context.Background(), interval)
+	defer cancel()
+	err = rate_limiter.Wait(ctx, s.limiter, s.rate)
+	s.Assert().ErrorIs(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,15 @@
+package restful
+
+import "errors"
+
+var (
+	ErrMissingCredentials = errors.New("missing authentication credentials")
+	ErrInvalidCredentials = errors.New("invalid authentication credentials")
+)
+
+// Authenticator resolves the principal from the credentials of a request,
+// ErrMissingCredentials is returned if the request does not carry the
+// credentials of its scheme, so that the next authenticator can be tried.
+type Authenticator interface {
+	Authenticate(req *Request) (*Principal, error)
+}
@@ -0,0 +1,49 @@
+package authenticators
+
+import (
+	"crypto/subtle"
+
+	"duolingo/libraries/restful"
+)
+
+const (
+	SchemeAPIKey = "api_key"
+
+	APIKeyHeader = "X-Api-Key"
+)
+
+// APIKeyAuthenticator authenticates the requests carrying one of the
+// static API keys in the "X-Api-Key" header.
+type APIKeyAuthenticator struct {
+	keys map[string]string
+}
+
+func NewAPIKeyAuthenticator() *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{
+		keys: make(map[string]string),
+	}
+}
+
+func (authenticator *APIKeyAuthenticator) AddKey(principalId string, key string) *APIKeyAuthenticator {
+	authenticator.keys[key] = principalId
+	return authenticator
+}
+
+func (authenticator *APIKeyAuthenticator) Authenticate(req *restful.Request) (*restful.Principal, error) {
+	key := req.Header().Get(APIKeyHeader)
+	if key == "" {
+		return nil, restful.ErrMissingCredentials
+	}
+	// every key is compared in constant time, so that the
+	// response time does not tell how close the given key is
+	var principalId string
+	for validKey, id := range authenticator.keys {
+		if subtle.ConstantTimeCompare([]byte(validKey), []byte(key)) == 1 {
+			principalId = id
+		}
+	}
+	if principalId == "" {
+		return nil, restful.ErrInvalidCredentials
+	}
+	return &restful.Principal{Id: principalId, Scheme: SchemeAPIKey}, nil
+}
@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	connection "duolingo/libraries/connection_manager/drivers/redis"
+	events "duolingo/libraries/events/facade"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+### Notions:
+ 1. Each scope has its own key space, hence the registries of several
+    servers can share the same redis instance.
+ 2. The signatures expire along with the replay window, the requests signed
+    outside the window are rejected by the authenticator anyway.
+*/
+type RedisSignatureRegistry struct {
+	connection.RedisClient
+
+	scope string
+}
+
+func NewRedisSignatureRegistry(client *connection.RedisClient, scope string) *RedisSignatureRegistry {
+	return &RedisSignatureRegistry{
+		RedisClient: *client,
+		scope:       scope,
+	}
+}
+
+func (registry *RedisSignatureRegistry) Remember(
+	ctx context.Context,
+	signature string,
+	ttl time.Duration,
+) (bool, error) {
+	var remembered bool
+	var err error
+
+	evt := events.Start(ctx, "restful.signature_registry.redis.remember", map[string]any{
+		"operation_name": "remember",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = registry.ExecuteClosure(evt.Context(), registry.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		result, setErr := rdb.SetNX(timeoutCtx, registry.signatureKey(signature), 1, ttl).Result()
+		remembered = result
+		return setErr
+	})
+
+	return remembered, err
+}
+
+func (registry *RedisSignatureRegistry) signatureKey(signature string) string {
+	return "hmac_signatures:" + registry.scope + ":" + signature
+}
@@ -0,0 +1,121 @@
+package authenticators
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"duolingo/libraries/restful"
+)
+
+const (
+	SchemeHMAC = "hmac"
+
+	HMACKeyIdHeader     = "X-Signature-Key-Id"
+	HMACTimestampHeader = "X-Signature-Timestamp"
+	HMACSignatureHeader = "X-Signature"
+)
+
+var (
+	ErrSignatureExpired    = errors.New("request signature is out of the replay window")
+	ErrSignatureReplayed   = errors.New("request signature has already been used")
+	ErrSignatureUnverified = errors.New("request signature can not be verified for now")
+)
+
+/*
+HMACAuthenticator authenticates the signed requests, the signature is the
+hex encoded HMAC-SHA256 of:
+
+	<unix timestamp in seconds>\n<method>\n<request uri>\n<raw body>
+
+### Notions:
+ 1. The signature is sent in the "X-Signature" header, along with the
+    "X-Signature-Key-Id" and the "X-Signature-Timestamp" headers.
+ 2. The requests signed outside the replay window (in both directions, to
+    tolerate the clock skew) are rejected, so that a captured request can
+    not be replayed later on.
+ 3. Within the window, a captured request is rejected only if a signature
+    registry is set, it remembers the accepted signatures until they are
+    out of the window, so that each signed request is accepted once.
+*/
+type HMACAuthenticator struct {
+	secrets      map[string][]byte
+	replayWindow time.Duration
+	registry     SignatureRegistry
+}
+
+func NewHMACAuthenticator() *HMACAuthenticator {
+	return &HMACAuthenticator{
+		secrets:      make(map[string][]byte),
+		replayWindow: 5 * time.Minute,
+	}
+}
+
+func (authenticator *HMACAuthenticator) AddSecret(principalId string, secret string) *HMACAuthenticator {
+	authenticator.secrets[principalId] = []byte(secret)
+	return authenticator
+}
+
+func (authenticator *HMACAuthenticator) SetReplayWindow(window time.Duration) *HMACAuthenticator {
+	authenticator.replayWindow = window
+	return authenticator
+}
+
+func (authenticator *HMACAuthenticator) SetSignatureRegistry(registry SignatureRegistry) *HMACAuthenticator {
+	authenticator.registry = registry
+	return authenticator
+}
+
+func (authenticator *HMACAuthenticator) Authenticate(req *restful.Request) (*restful.Principal, error) {
+	principalId := req.Header().Get(HMACKeyIdHeader)
+	timestamp := req.Header().Get(HMACTimestampHeader)
+	signature := req.Header().Get(HMACSignatureHeader)
+	if principalId == "" && timestamp == "" && signature == "" {
+		return nil, restful.ErrMissingCredentials
+	}
+
+	secret, exists := authenticator.secrets[principalId]
+	if !exists {
+		return nil, restful.ErrInvalidCredentials
+	}
+	signedAt, parseErr := strconv.ParseInt(timestamp, 10, 64)
+	if parseErr != nil {
+		return nil, restful.ErrInvalidCredentials
+	}
+	if skew := time.Since(time.Unix(signedAt, 0)).Abs(); skew > authenticator.replayWindow {
+		return nil, ErrSignatureExpired
+	}
+	given, decodeErr := hex.DecodeString(signature)
+	if decodeErr != nil {
+		return nil, restful.ErrInvalidCredentials
+	}
+	expected := Sign(secret, timestamp, req.Method(), req.URL().RequestURI(), req.RawBody())
+	if !hmac.Equal(given, expected) {
+		return nil, restful.ErrInvalidCredentials
+	}
+	if authenticator.registry != nil {
+		// the signature stays valid until the window has passed its timestamp
+		ttl := time.Until(time.Unix(signedAt, 0).Add(authenticator.replayWindow)) + time.Second
+		remembered, rememberErr := authenticator.registry.Remember(req.Context(), principalId+":"+signature, ttl)
+		if rememberErr != nil {
+			return nil, ErrSignatureUnverified
+		}
+		if !remembered {
+			return nil, ErrSignatureReplayed
+		}
+	}
+
+	return &restful.Principal{Id: principalId, Scheme: SchemeHMAC}, nil
+}
+
+// Sign computes the signature of a request, the clients may use it as the
+// reference implementation.
+func Sign(secret []byte, timestamp string, method string, uri string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "\n" + method + "\n" + uri + "\n"))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,14 @@
+package authenticators
+
+import (
+	"context"
+	"time"
+)
+
+// SignatureRegistry remembers the signatures of the accepted requests, so
+// that a signed request is accepted only once.
+type SignatureRegistry interface {
+	// Remember records the signature for the ttl, it returns false if the
+	// signature has already been recorded and not expired yet.
+	Remember(ctx context.Context, signature string, ttl time.Duration) (bool, error)
+}
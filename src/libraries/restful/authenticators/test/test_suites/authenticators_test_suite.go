@@ -0,0 +1,141 @@
+package test_suites
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"duolingo/libraries/restful"
+	"duolingo/libraries/restful/authenticators"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthenticatorsTestSuite struct {
+	suite.Suite
+}
+
+func NewAuthenticatorsTestSuite() *AuthenticatorsTestSuite {
+	return &AuthenticatorsTestSuite{}
+}
+
+func (s *AuthenticatorsTestSuite) Test_APIKeyAuthenticator() {
+	authenticator := authenticators.NewAPIKeyAuthenticator().
+		AddKey("P1", "key_1").
+		AddKey("P2", "key_2")
+
+	principal, validErr := authenticator.Authenticate(s.request("", map[string]string{
+		authenticators.APIKeyHeader: "key_2",
+	}))
+	_, invalidErr := authenticator.Authenticate(s.request("", map[string]string{
+		authenticators.APIKeyHeader: "key_3",
+	}))
+	_, missingErr := authenticator.Authenticate(s.request("", nil))
+
+	s.Assert().NoError(validErr)
+	if s.Assert().NotNil(principal) {
+		s.Assert().Equal("P2", principal.Id)
+		s.Assert().Equal(authenticators.SchemeAPIKey, principal.Scheme)
+	}
+	s.Assert().Equal(restful.ErrInvalidCredentials, invalidErr)
+	s.Assert().Equal(restful.ErrMissingCredentials, missingErr)
+}
+
+func (s *AuthenticatorsTestSuite) Test_HMACAuthenticator() {
+	authenticator := authenticators.NewHMACAuthenticator().
+		AddSecret("P1", "secret_1").
+		SetReplayWindow(time.Minute)
+	body := `{"title":"T1"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	expired := strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10)
+
+	principal, validErr := authenticator.Authenticate(s.request(body, s.sign("P1", "secret_1", now, body)))
+	_, tamperedErr := authenticator.Authenticate(s.request(`{"title":"T2"}`, s.sign("P1", "secret_1", now, body)))
+	_, wrongSecretErr := authenticator.Authenticate(s.request(body, s.sign("P1", "secret_2", now, body)))
+	_, unknownErr := authenticator.Authenticate(s.request(body, s.sign("P2", "secret_1", now, body)))
+	_, expiredErr := authenticator.Authenticate(s.request(body, s.sign("P1", "secret_1", expired, body)))
+	_, missingErr := authenticator.Authenticate(s.request(body, nil))
+
+	s.Assert().NoError(validErr)
+	if s.Assert().NotNil(principal) {
+		s.Assert().Equal("P1", principal.Id)
+		s.Assert().Equal(authenticators.SchemeHMAC, principal.Scheme)
+	}
+	s.Assert().Equal(restful.ErrInvalidCredentials, tamperedErr)
+	s.Assert().Equal(restful.ErrInvalidCredentials, wrongSecretErr)
+	s.Assert().Equal(restful.ErrInvalidCredentials, unknownErr)
+	s.Assert().Equal(authenticators.ErrSignatureExpired, expiredErr)
+	s.Assert().Equal(restful.ErrMissingCredentials, missingErr)
+}
+
+func (s *AuthenticatorsTestSuite) Test_HMACAuthenticator_Rejects_Replayed_Signature() {
+	registry := &fakeSignatureRegistry{signatures: make(map[string]time.Duration)}
+	authenticator := authenticators.NewHMACAuthenticator().
+		AddSecret("P1", "secret_1").
+		SetReplayWindow(time.Minute).
+		SetSignatureRegistry(registry)
+	body := `{"title":"T1"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	headers := s.sign("P1", "secret_1", now, body)
+
+	_, firstErr := authenticator.Authenticate(s.request(body, headers))
+	_, replayedErr := authenticator.Authenticate(s.request(body, headers))
+	_, tamperedErr := authenticator.Authenticate(s.request(`{"title":"T2"}`, s.sign("P1", "secret_1", now, `{"title":"T1"}`)))
+	registry.err = errors.New("registry unavailable")
+	_, unverifiedErr := authenticator.Authenticate(s.request(`{"title":"T3"}`, s.sign("P1", "secret_1", now, `{"title":"T3"}`)))
+
+	s.Assert().NoError(firstErr)
+	s.Assert().Equal(authenticators.ErrSignatureReplayed, replayedErr)
+	// the invalid signatures are not remembered
+	s.Assert().Equal(restful.ErrInvalidCredentials, tamperedErr)
+	s.Assert().Len(registry.signatures, 1)
+	for _, ttl := range registry.signatures {
+		s.Assert().LessOrEqual(ttl, time.Minute+time.Second)
+	}
+	s.Assert().Equal(authenticators.ErrSignatureUnverified, unverifiedErr)
+}
+
+type fakeSignatureRegistry struct {
+	signatures map[string]time.Duration
+	err        error
+}
+
+func (registry *fakeSignatureRegistry) Remember(_ context.Context, signature string, ttl time.Duration) (bool, error) {
+	if registry.err != nil {
+		return false, registry.err
+	}
+	if _, exists := registry.signatures[signature]; exists {
+		return false, nil
+	}
+	registry.signatures[signature] = ttl
+	return true, nil
+}
+
+func (s *AuthenticatorsTestSuite) request(body string, headers map[string]string) *restful.Request {
+	base := httptest.NewRequest("POST", "/api/v1/campaigns/C1/message-input", strings.NewReader(body))
+	for key, value := range headers {
+		base.Header.Set(key, value)
+	}
+	req := restful.NewRequest(base)
+	restful.NewRequestBuilder(req).ReadRawBody()
+	return req
+}
+
+func (s *AuthenticatorsTestSuite) sign(principalId, secret, timestamp, body string) map[string]string {
+	signature := authenticators.Sign(
+		[]byte(secret),
+		timestamp,
+		"POST",
+		"/api/v1/campaigns/C1/message-input",
+		[]byte(body),
+	)
+	return map[string]string{
+		authenticators.HMACKeyIdHeader:     principalId,
+		authenticators.HMACTimestampHeader: timestamp,
+		authenticators.HMACSignatureHeader: hex.EncodeToString(signature),
+	}
+}
@@ -0,0 +1,7 @@
+package restful
+
+// Principal is the authenticated client submitting a request
+type Principal struct {
+	Id     string `json:"id"`
+	Scheme string `json:"scheme"`
+}
@@ -9,12 +9,13 @@ import (
 )
 
 type Request struct {
-	base     *http.Request
-	rawBody  []byte
-	queries  gjson.Result
-	pathArgs gjson.Result
-	inputs   gjson.Result
-	handler  func(*Request, *Response)
+	base      *http.Request
+	rawBody   []byte
+	queries   gjson.Result
+	pathArgs  gjson.Result
+	inputs    gjson.Result
+	handler   func(*Request, *Response)
+	principal *Principal
 }
 
 func NewRequest(base *http.Request) *Request {
@@ -77,3 +78,8 @@ func (request *Request) Input(path string) gjson.Result {
 func (request *Request) RawBody() []byte {
 	return request.rawBody
 }
+
+// Principal returns nil if the request has not been authenticated
+func (request *Request) Principal() *Principal {
+	return request.principal
+}
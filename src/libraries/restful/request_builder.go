@@ -34,6 +34,23 @@ func (b *RequestBuilder) SetPathArgs(args map[string]string) {
 	b.pathArgs = args
 }
 
+func (b *RequestBuilder) SetPrincipal(principal *Principal) {
+	b.request.principal = principal
+}
+
+// ReadRawBody reads the request body ahead of Build(), e.g. to verify
+// the body signature before routing the request.
+func (b *RequestBuilder) ReadRawBody() []byte {
+	if b.request.rawBody == nil {
+		rawBody, readErr := io.ReadAll(b.request.base.Body)
+		b.request.base.Body.Close()
+		if readErr == nil {
+			b.request.rawBody = rawBody
+		}
+	}
+	return b.request.rawBody
+}
+
 func (b *RequestBuilder) Build() {
 	b.buildPathArgsObject()
 	b.buildQueriesObject()
@@ -64,10 +81,7 @@ func (b *RequestBuilder) buildQueriesObject() {
 }
 
 func (b *RequestBuilder) buildInputsObject() {
-	rawBody, readErr := io.ReadAll(b.request.base.Body)
-	b.request.base.Body.Close()
-	if readErr == nil {
-		b.request.rawBody = rawBody
+	if rawBody := b.ReadRawBody(); rawBody != nil {
 		b.request.inputs = gjson.ParseBytes(rawBody)
 	}
 }
@@ -10,6 +10,7 @@ type Response struct {
 	base    http.ResponseWriter
 	sent    bool
 	status  int
+	body    []byte
 	err     error
 	success bool
 }
@@ -21,6 +22,7 @@ func NewResponse(base http.ResponseWriter) *Response {
 func (res *Response) Sent() bool    { return res.sent }
 func (res *Response) Status() int   { return res.status }
 func (res *Response) Success() bool { return res.success }
+func (res *Response) Body() []byte  { return res.body }
 
 func (res *Response) Error() error { return res.err }
 func (res *Response) SetErr(errs any) {
@@ -46,6 +48,10 @@ func (res *Response) Created(message string, data any) {
 	res.Send(http.StatusCreated, true, message, nil, data)
 }
 
+func (res *Response) Accepted(message string, data any) {
+	res.Send(http.StatusAccepted, true, message, nil, data)
+}
+
 func (res *Response) NotFound(message string) {
 	res.Send(http.StatusNotFound, false, message, errors.New(message), nil)
 }
@@ -54,6 +60,14 @@ func (res *Response) BadRequest(message string, errs any) {
 	res.Send(http.StatusBadRequest, false, message, errs, nil)
 }
 
+func (res *Response) Unauthorized(message string) {
+	res.Send(http.StatusUnauthorized, false, message, errors.New(message), nil)
+}
+
+func (res *Response) Conflict(message string) {
+	res.Send(http.StatusConflict, false, message, errors.New(message), nil)
+}
+
 func (res *Response) ServerErr(message string) {
 	res.Send(http.StatusInternalServerError, false, message, errors.New(message), nil)
 }
@@ -78,14 +92,28 @@ func (res *Response) Send(
 		panic(bodyErr)
 	}
 
+	res.write(status, body)
+	res.success = success
+	res.SetErr(errors)
+}
+
+// Replay sends a response body as it was built by a previous Send()
+func (res *Response) Replay(status int, body []byte) {
+	if res.sent {
+		return
+	}
+	res.write(status, body)
+	res.success = status < http.StatusBadRequest
+}
+
+func (res *Response) write(status int, body []byte) {
 	res.base.Header().Set("Content-Type", "application/json")
 	res.base.WriteHeader(status)
 	res.base.Write(body)
 
 	res.sent = true
 	res.status = status
-	res.success = success
-	res.SetErr(errors)
+	res.body = body
 }
 
 func (res *Response) buildBody(
@@ -0,0 +1,41 @@
+package pipelines
+
+import (
+	"duolingo/libraries/restful"
+)
+
+// AuthenticateRequest tries the authenticators in order, the request is
+// rejected if none of them authenticates it. The pipeline lets all the
+// requests through if there is no authenticator.
+type AuthenticateRequest struct {
+	restful.BasePipeline
+	Authenticators []restful.Authenticator
+}
+
+func (pipeline *AuthenticateRequest) Handle(req *restful.Request, res *restful.Response) {
+	if len(pipeline.Authenticators) > 0 {
+		pipeline.authenticate(req, res)
+	}
+	if !res.Sent() {
+		pipeline.Next(req, res)
+	}
+}
+
+func (pipeline *AuthenticateRequest) authenticate(req *restful.Request, res *restful.Response) {
+	builder := restful.NewRequestBuilder(req)
+	// the body is read ahead, for the authenticators verifying its signature
+	builder.ReadRawBody()
+	for _, authenticator := range pipeline.Authenticators {
+		principal, err := authenticator.Authenticate(req)
+		if err == restful.ErrMissingCredentials {
+			continue
+		}
+		if err != nil {
+			res.Unauthorized(err.Error())
+			return
+		}
+		builder.SetPrincipal(principal)
+		return
+	}
+	res.Unauthorized(restful.ErrMissingCredentials.Error())
+}
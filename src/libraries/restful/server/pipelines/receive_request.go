@@ -24,9 +24,13 @@ func (r *ReceiveRequest) Handle(req *restful.Request, res *restful.Response) {
 		},
 	)
 	defer func() {
-		events.End(evt, res.Success(), res.Error(), map[string]any{
+		data := map[string]any{
 			"status_code": res.Status(),
-		})
+		}
+		if principal := req.Principal(); principal != nil {
+			data["principal"] = principal.Id
+		}
+		events.End(evt, res.Success(), res.Error(), data)
 	}()
 
 	builder.SetRequestContext(evt.Context())
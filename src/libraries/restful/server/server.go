@@ -13,6 +13,7 @@ type Server struct {
 	instance       *http.Server
 	addr           string
 	router         *router.Router
+	authentication *pipelines.AuthenticateRequest
 	pipelineGroups *restful.PipelineGroups
 }
 
@@ -20,6 +21,7 @@ func NewServer(addr string) *Server {
 	server := &Server{
 		addr:           addr,
 		router:         router.NewRouter(),
+		authentication: &pipelines.AuthenticateRequest{},
 		pipelineGroups: restful.NewPipelineGroups(),
 	}
 
@@ -40,6 +42,15 @@ func (server *Server) Addr() string {
 	return server.addr
 }
 
+// Authenticate requires every request to be authenticated by one of the
+// authenticators, which are tried in the given order.
+func (server *Server) Authenticate(authenticators ...restful.Authenticator) {
+	server.authentication.Authenticators = append(
+		server.authentication.Authenticators,
+		authenticators...,
+	)
+}
+
 func (server *Server) Get(path string, handler func(*restful.Request, *restful.Response)) {
 	server.addRoute("GET", path, handler)
 }
@@ -74,6 +85,7 @@ func (server *Server) configServer() {
 		&pipelines.ReceiveRequest{},
 		&pipelines.HandlePreflightRequest{},
 		&pipelines.ValidateCORS{},
+		server.authentication,
 		&pipelines.RouteRequestSetHandler{Router: server.router},
 	)
 	server.pipelineGroups.Push("handlingRequest",
@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+### Task storage:
+ 1. A hash maps the task id to the task json.
+ 2. A sorted set maps the task id to its due time (unix ms).
+ 3. A set per group holds the ids of the tasks of that group.
+ 4. Claiming a task moves its due time forward by the claim timeout, the
+    claim timestamps are computed from the redis server TIME, so that
+    schedulers with clock skew still agree on when a claim expires.
+ 5. A hash maps the claimed task id to the token of its claim. A task is
+    claimed while it has a token and its due time is in the future, saving
+    or deleting a claimed task is refused, only the holder of the token can
+    delete it.
+*/
+
+const claimedTaskLuaFunc = `
+	local function now_ms()
+		local now = redis.call("TIME")
+		return tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+	end
+	local function is_claimed(due_key, claims_key, id)
+		if redis.call("HEXISTS", claims_key, id) == 0 then
+			return false
+		end
+		local due = redis.call("ZSCORE", due_key, id)
+		return due ~= false and tonumber(due) > now_ms()
+	end
+`
+
+func claimDueTasks(
+	ctx context.Context,
+	rdb *redis.Client,
+	schedulerName string,
+	limit int64,
+	claimTimeout time.Duration,
+	claimToken string,
+) ([]any, error) {
+	script := redis.NewScript(claimedTaskLuaFunc + `
+		local claimed_at = now_ms()
+
+		local ids = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", claimed_at, "LIMIT", 0, tonumber(ARGV[1]))
+		if #ids == 0 then
+			return {}
+		end
+		for _, id in ipairs(ids) do
+			redis.call("ZADD", KEYS[1], claimed_at + tonumber(ARGV[2]), id)
+			redis.call("HSET", KEYS[3], id, ARGV[3])
+		end
+
+		return redis.call("HMGET", KEYS[2], unpack(ids))
+	`)
+	keys := []string{
+		dueTasksKey(schedulerName),
+		tasksKey(schedulerName),
+		claimsKey(schedulerName),
+	}
+	return script.Run(ctx, rdb, keys, limit, claimTimeout.Milliseconds(), claimToken).Slice()
+}
+
+// saveUnclaimedTask returns 0 if the task is claimed, 1 once saved.
+func saveUnclaimedTask(
+	ctx context.Context,
+	rdb *redis.Client,
+	schedulerName string,
+	taskId string,
+	group string,
+	marshaled string,
+	dueAt time.Time,
+) (int64, error) {
+	script := redis.NewScript(claimedTaskLuaFunc + `
+		if is_claimed(KEYS[1], KEYS[4], ARGV[1]) then
+			return 0
+		end
+		redis.call("HSET", KEYS[2], ARGV[1], ARGV[2])
+		redis.call("ZADD", KEYS[1], tonumber(ARGV[3]), ARGV[1])
+		redis.call("SADD", KEYS[3], ARGV[1])
+		redis.call("HDEL", KEYS[4], ARGV[1])
+		return 1
+	`)
+	keys := []string{
+		dueTasksKey(schedulerName),
+		tasksKey(schedulerName),
+		tasksOfGroupKey(schedulerName, group),
+		claimsKey(schedulerName),
+	}
+	return script.Run(ctx, rdb, keys, taskId, marshaled, dueAt.UnixMilli()).Int64()
+}
+
+// deleteTask deletes the task if it is not claimed, or if it is claimed by the
+// given token when the token is not empty. It returns -1 if the task does not
+// exist, 0 if the claim check refuses the deletion, 1 once deleted.
+func deleteTask(
+	ctx context.Context,
+	rdb *redis.Client,
+	schedulerName string,
+	taskId string,
+	group string,
+	claimToken string,
+) (int64, error) {
+	script := redis.NewScript(claimedTaskLuaFunc + `
+		if redis.call("HEXISTS", KEYS[2], ARGV[1]) == 0 then
+			return -1
+		end
+		if ARGV[2] ~= "" then
+			if redis.call("HGET", KEYS[4], ARGV[1]) ~= ARGV[2] then
+				return 0
+			end
+		elseif is_claimed(KEYS[1], KEYS[4], ARGV[1]) then
+			return 0
+		end
+		redis.call("HDEL", KEYS[2], ARGV[1])
+		redis.call("ZREM", KEYS[1], ARGV[1])
+		redis.call("SREM", KEYS[3], ARGV[1])
+		redis.call("HDEL", KEYS[4], ARGV[1])
+		return 1
+	`)
+	keys := []string{
+		dueTasksKey(schedulerName),
+		tasksKey(schedulerName),
+		tasksOfGroupKey(schedulerName, group),
+		claimsKey(schedulerName),
+	}
+	return script.Run(ctx, rdb, keys, taskId, claimToken).Int64()
+}
@@ -0,0 +1,37 @@
+package redis
+
+import (
+	"duolingo/libraries/task_scheduler"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func dueTasksKey(schedulerName string) string {
+	return "task_scheduler:" + schedulerName + ":due"
+}
+
+func tasksKey(schedulerName string) string {
+	return "task_scheduler:" + schedulerName + ":tasks"
+}
+
+func tasksOfGroupKey(schedulerName string, group string) string {
+	return "task_scheduler:" + schedulerName + ":group:" + group
+}
+
+func claimsKey(schedulerName string) string {
+	return "task_scheduler:" + schedulerName + ":claims"
+}
+
+func errOrRedisNilAlias(err error, ifNilErr error) error {
+	if err == redis.Nil {
+		return ifNilErr
+	}
+	return err
+}
+
+func unmarshalTaskIgnoreErr(jsonStr string) *task_scheduler.ScheduledTask {
+	task := new(task_scheduler.ScheduledTask)
+	json.Unmarshal([]byte(jsonStr), task)
+	return task
+}
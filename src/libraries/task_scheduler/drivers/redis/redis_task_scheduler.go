@@ -0,0 +1,14 @@
+package redis
+
+import (
+	"duolingo/libraries/connection_manager/drivers/redis"
+	"duolingo/libraries/task_scheduler"
+)
+
+func NewRedisTaskScheduler(
+	client *redis.RedisClient,
+	schedulerName string,
+) *task_scheduler.TaskScheduler {
+	proxy := NewRedisTaskStorageProxy(client, schedulerName)
+	return task_scheduler.NewTaskScheduler(proxy)
+}
@@ -0,0 +1,234 @@
+package redis
+
+import (
+	"context"
+	connection "duolingo/libraries/connection_manager/drivers/redis"
+	events "duolingo/libraries/events/facade"
+	scheduler "duolingo/libraries/task_scheduler"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+### Notions:
+ 1. Each scheduler name has its own key space, hence several schedulers
+    can share the same redis instance.
+ 2. Saving a task that already exists overwrites it, this is how tasks are
+    rescheduled. A task can not be moved to another group.
+ 3. A task being dispatched can not be saved nor deleted until its claim
+    expires, except by DeleteClaimedTask with the token of the claim.
+*/
+type RedisTaskStorageProxy struct {
+	connection.RedisClient
+
+	schedulerName string
+}
+
+func NewRedisTaskStorageProxy(
+	client *connection.RedisClient,
+	schedulerName string,
+) *RedisTaskStorageProxy {
+	return &RedisTaskStorageProxy{
+		RedisClient:   *client,
+		schedulerName: schedulerName,
+	}
+}
+
+func (proxy *RedisTaskStorageProxy) SaveTask(
+	ctx context.Context,
+	task *scheduler.ScheduledTask,
+) error {
+	var err error
+
+	saveEvt := events.Start(ctx, "task_scheduler.proxy.redis.save_task", nil)
+	defer events.End(saveEvt, true, err, nil)
+
+	if err = task.Validate(); err != nil {
+		return err
+	}
+
+	marshaled, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	err = proxy.ExecuteClosure(saveEvt.Context(), proxy.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		saved, saveErr := saveUnclaimedTask(
+			timeoutCtx, rdb, proxy.schedulerName,
+			task.Id, task.Group, string(marshaled), task.DueAt,
+		)
+		if saveErr == nil && saved == 0 {
+			return scheduler.ErrScheduledTaskClaimed
+		}
+		return saveErr
+	})
+
+	return err
+}
+
+func (proxy *RedisTaskStorageProxy) GetTask(
+	ctx context.Context,
+	taskId string,
+) (*scheduler.ScheduledTask, error) {
+	var marshaled string
+	var err error
+
+	getEvt := events.Start(ctx, "task_scheduler.proxy.redis.get_task", nil)
+	defer events.End(getEvt, true, err, nil)
+
+	err = proxy.ExecuteClosure(getEvt.Context(), proxy.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		result, getErr := rdb.HGet(timeoutCtx, tasksKey(proxy.schedulerName), taskId).Result()
+		if getErr != nil {
+			return errOrRedisNilAlias(getErr, scheduler.ErrScheduledTaskNotExists)
+		}
+		marshaled = result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalTaskIgnoreErr(marshaled), nil
+}
+
+func (proxy *RedisTaskStorageProxy) ListTasks(
+	ctx context.Context,
+	group string,
+) ([]*scheduler.ScheduledTask, error) {
+	var tasks []*scheduler.ScheduledTask
+	var err error
+
+	listEvt := events.Start(ctx, "task_scheduler.proxy.redis.list_tasks", nil)
+	defer events.End(listEvt, true, err, nil)
+
+	err = proxy.ExecuteClosure(listEvt.Context(), proxy.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		ids, membersErr := rdb.SMembers(timeoutCtx, tasksOfGroupKey(proxy.schedulerName, group)).Result()
+		if membersErr != nil || len(ids) == 0 {
+			return membersErr
+		}
+		values, getErr := rdb.HMGet(timeoutCtx, tasksKey(proxy.schedulerName), ids...).Result()
+		if getErr != nil {
+			return getErr
+		}
+		for _, val := range values {
+			// the task has been deleted in between the two commands
+			if marshaled, ok := val.(string); ok {
+				tasks = append(tasks, unmarshalTaskIgnoreErr(marshaled))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func (proxy *RedisTaskStorageProxy) DeleteTask(
+	ctx context.Context,
+	taskId string,
+) error {
+	var err error
+
+	deleteEvt := events.Start(ctx, "task_scheduler.proxy.redis.delete_task", nil)
+	defer events.End(deleteEvt, true, err, nil)
+
+	task, err := proxy.GetTask(deleteEvt.Context(), taskId)
+	if err != nil {
+		return err
+	}
+	err = proxy.deleteTask(deleteEvt.Context(), task, "", scheduler.ErrScheduledTaskClaimed)
+
+	return err
+}
+
+func (proxy *RedisTaskStorageProxy) DeleteClaimedTask(
+	ctx context.Context,
+	task *scheduler.ScheduledTask,
+) error {
+	var err error
+
+	deleteEvt := events.Start(ctx, "task_scheduler.proxy.redis.delete_claimed_task", nil)
+	defer events.End(deleteEvt, true, err, nil)
+
+	if task.ClaimToken == "" {
+		err = scheduler.ErrScheduledTaskClaimLost
+		return err
+	}
+	err = proxy.deleteTask(deleteEvt.Context(), task, task.ClaimToken, scheduler.ErrScheduledTaskClaimLost)
+
+	return err
+}
+
+func (proxy *RedisTaskStorageProxy) deleteTask(
+	ctx context.Context,
+	task *scheduler.ScheduledTask,
+	claimToken string,
+	refusedErr error,
+) error {
+	return proxy.ExecuteClosure(ctx, proxy.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		deleted, deleteErr := deleteTask(timeoutCtx, rdb, proxy.schedulerName, task.Id, task.Group, claimToken)
+		if deleteErr != nil {
+			return deleteErr
+		}
+		switch deleted {
+		case -1:
+			return scheduler.ErrScheduledTaskNotExists
+		case 0:
+			return refusedErr
+		}
+		return nil
+	})
+}
+
+func (proxy *RedisTaskStorageProxy) ClaimDueTasks(
+	ctx context.Context,
+	limit int64,
+	claimTimeout time.Duration,
+) ([]*scheduler.ScheduledTask, error) {
+	var tasks []*scheduler.ScheduledTask
+	var err error
+
+	claimEvt := events.Start(ctx, "task_scheduler.proxy.redis.claim_due_tasks", nil)
+	defer events.End(claimEvt, true, err, nil)
+
+	err = proxy.ExecuteClosure(claimEvt.Context(), proxy.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		claimToken := uuid.NewString()
+		values, claimErr := claimDueTasks(timeoutCtx, rdb, proxy.schedulerName, limit, claimTimeout, claimToken)
+		if claimErr != nil {
+			return claimErr
+		}
+		for _, val := range values {
+			if marshaled, ok := val.(string); ok {
+				task := unmarshalTaskIgnoreErr(marshaled)
+				task.ClaimToken = claimToken
+				tasks = append(tasks, task)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
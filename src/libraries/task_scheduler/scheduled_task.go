@@ -0,0 +1,59 @@
+package task_scheduler
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidScheduledTask   = errors.New("invalid scheduled task parameters")
+	ErrScheduledTaskNotExists = errors.New("scheduled task not exists")
+	ErrScheduledTaskClaimed   = errors.New("scheduled task is being dispatched")
+	ErrScheduledTaskClaimLost = errors.New("scheduled task claim has been lost")
+)
+
+type ScheduledTask struct {
+	Id      string    `json:"id"`
+	Group   string    `json:"group"`
+	Payload string    `json:"payload"`
+	DueAt   time.Time `json:"due_at"`
+
+	// The claim of the task being dispatched, set once the task is claimed
+	ClaimToken string `json:"-"`
+}
+
+func NewScheduledTask(
+	id string,
+	group string,
+	payload string,
+	dueAt time.Time,
+) (*ScheduledTask, error) {
+	task := &ScheduledTask{
+		Id:      id,
+		Group:   group,
+		Payload: payload,
+		DueAt:   dueAt,
+	}
+	if err := task.Validate(); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (task *ScheduledTask) Validate() error {
+	if task.Id == "" ||
+		task.Group == "" ||
+		task.Payload == "" ||
+		task.DueAt.IsZero() {
+		return ErrInvalidScheduledTask
+	}
+	return nil
+}
+
+func (task *ScheduledTask) IsDue(now time.Time) bool {
+	return !task.DueAt.After(now)
+}
+
+func (task *ScheduledTask) Equal(target *ScheduledTask) bool {
+	return target != nil && task.Id == target.Id
+}
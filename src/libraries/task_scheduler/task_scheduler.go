@@ -0,0 +1,163 @@
+package task_scheduler
+
+import (
+	"context"
+	"time"
+
+	events "duolingo/libraries/events/facade"
+)
+
+/*
+### Notions:
+ 1. Tasks are stored durably until they are dispatched successfully, a task
+    is delivered at least once.
+ 2. Due tasks are claimed for "claimTimeout" before being dispatched, so that
+    concurrent schedulers do not dispatch the same task. A claimed task that is
+    not dispatched successfully (e.g, the handler failed, or the scheduler has
+    crashed) becomes due again after the claim timeout.
+ 3. A claimed task can not be rescheduled nor canceled until its claim expires,
+    and the dispatched task is deleted only if it still holds its claim.
+*/
+type TaskScheduler struct {
+	proxy TaskStorageProxy
+
+	pollInterval time.Duration
+	claimTimeout time.Duration
+	claimLimit   int64
+}
+
+func NewTaskScheduler(proxy TaskStorageProxy) *TaskScheduler {
+	return &TaskScheduler{
+		proxy:        proxy,
+		pollInterval: time.Second,
+		claimTimeout: 30 * time.Second,
+		claimLimit:   100,
+	}
+}
+
+func (scheduler *TaskScheduler) SetPollInterval(interval time.Duration) *TaskScheduler {
+	scheduler.pollInterval = interval
+	return scheduler
+}
+
+func (scheduler *TaskScheduler) SetClaimTimeout(timeout time.Duration) *TaskScheduler {
+	scheduler.claimTimeout = timeout
+	return scheduler
+}
+
+func (scheduler *TaskScheduler) SetClaimLimit(limit int64) *TaskScheduler {
+	scheduler.claimLimit = limit
+	return scheduler
+}
+
+func (scheduler *TaskScheduler) Schedule(ctx context.Context, task *ScheduledTask) error {
+	var err error
+
+	evt := events.Start(ctx, "task_scheduler.schedule", map[string]any{
+		"operation_name": "schedule",
+	})
+	defer events.End(evt, true, err, nil)
+
+	if err = task.Validate(); err != nil {
+		return err
+	}
+	err = scheduler.proxy.SaveTask(evt.Context(), task)
+
+	return err
+}
+
+func (scheduler *TaskScheduler) Reschedule(
+	ctx context.Context,
+	taskId string,
+	dueAt time.Time,
+) (*ScheduledTask, error) {
+	var task *ScheduledTask
+	var err error
+
+	evt := events.Start(ctx, "task_scheduler.reschedule", map[string]any{
+		"operation_name": "reschedule",
+	})
+	defer events.End(evt, true, err, nil)
+
+	if task, err = scheduler.proxy.GetTask(evt.Context(), taskId); err != nil {
+		return nil, err
+	}
+	task.DueAt = dueAt
+	if err = task.Validate(); err != nil {
+		return nil, err
+	}
+	if err = scheduler.proxy.SaveTask(evt.Context(), task); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (scheduler *TaskScheduler) Cancel(ctx context.Context, taskId string) error {
+	var err error
+
+	evt := events.Start(ctx, "task_scheduler.cancel", map[string]any{
+		"operation_name": "cancel",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = scheduler.proxy.DeleteTask(evt.Context(), taskId)
+
+	return err
+}
+
+func (scheduler *TaskScheduler) GetTask(ctx context.Context, taskId string) (*ScheduledTask, error) {
+	return scheduler.proxy.GetTask(ctx, taskId)
+}
+
+func (scheduler *TaskScheduler) ListTasks(ctx context.Context, group string) ([]*ScheduledTask, error) {
+	return scheduler.proxy.ListTasks(ctx, group)
+}
+
+// Dispatching polls for due tasks, and calls the handler for each of them until
+// the context is canceled. A task is removed only if the handler succeeded.
+func (scheduler *TaskScheduler) Dispatching(
+	ctx context.Context,
+	handler func(ctx context.Context, task *ScheduledTask) error,
+) error {
+	poll := time.NewTicker(scheduler.pollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll.C:
+			scheduler.dispatchDueTasks(ctx, handler)
+		}
+	}
+}
+
+func (scheduler *TaskScheduler) dispatchDueTasks(
+	ctx context.Context,
+	handler func(ctx context.Context, task *ScheduledTask) error,
+) {
+	var tasks []*ScheduledTask
+	var err error
+
+	evt := events.Start(ctx, "task_scheduler.dispatch_due_tasks", map[string]any{
+		"operation_name": "dispatch_due_tasks",
+	})
+	defer func() {
+		evt.SetData("tasks_total", len(tasks))
+		events.End(evt, true, err, nil)
+	}()
+
+	tasks, err = scheduler.proxy.ClaimDueTasks(evt.Context(), scheduler.claimLimit, scheduler.claimTimeout)
+	if err != nil {
+		return
+	}
+	for _, task := range tasks {
+		if handleErr := handler(evt.Context(), task); handleErr != nil {
+			err = handleErr
+			continue
+		}
+		if deleteErr := scheduler.proxy.DeleteClaimedTask(evt.Context(), task); deleteErr != nil {
+			err = deleteErr
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package task_scheduler
+
+import (
+	"context"
+	"time"
+)
+
+type TaskStorageProxy interface {
+	// SaveTask and DeleteTask refuse the tasks being dispatched, whose claim has
+	// not expired yet, with ErrScheduledTaskClaimed.
+	SaveTask(ctx context.Context, task *ScheduledTask) error
+	GetTask(ctx context.Context, taskId string) (*ScheduledTask, error)
+	ListTasks(ctx context.Context, group string) ([]*ScheduledTask, error)
+	DeleteTask(ctx context.Context, taskId string) error
+
+	// ClaimDueTasks sets the claim token of the claimed tasks, DeleteClaimedTask
+	// deletes the dispatched task only if it still holds the claim, otherwise
+	// it returns ErrScheduledTaskClaimLost.
+	ClaimDueTasks(ctx context.Context, limit int64, claimTimeout time.Duration) ([]*ScheduledTask, error)
+	DeleteClaimedTask(ctx context.Context, task *ScheduledTask) error
+}
@@ -0,0 +1,45 @@
+package test_suites
+
+import (
+	"duolingo/libraries/task_scheduler"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ScheduledTaskTestSuite struct {
+	suite.Suite
+}
+
+func NewScheduledTaskTestSuite() *ScheduledTaskTestSuite {
+	return &ScheduledTaskTestSuite{}
+}
+
+func (s *ScheduledTaskTestSuite) Test_NewScheduledTask() {
+	t1, err1 := task_scheduler.NewScheduledTask("", "G1", "P1", time.Now())          // missing id
+	t2, err2 := task_scheduler.NewScheduledTask("T2", "", "P2", time.Now())          // missing group
+	t3, err3 := task_scheduler.NewScheduledTask("T3", "G1", "", time.Now())          // missing payload
+	t4, err4 := task_scheduler.NewScheduledTask("T4", "G1", "P4", time.Time{})       // missing due time
+	t5, err5 := task_scheduler.NewScheduledTask("T5", "G1", "P5", time.Now().Add(1)) // valid
+	s.Assert().Nil(t1)
+	s.Assert().Nil(t2)
+	s.Assert().Nil(t3)
+	s.Assert().Nil(t4)
+	s.Assert().Equal(task_scheduler.ErrInvalidScheduledTask, err1)
+	s.Assert().Equal(task_scheduler.ErrInvalidScheduledTask, err2)
+	s.Assert().Equal(task_scheduler.ErrInvalidScheduledTask, err3)
+	s.Assert().Equal(task_scheduler.ErrInvalidScheduledTask, err4)
+	s.Assert().NotNil(t5)
+	s.Assert().NoError(err5)
+}
+
+func (s *ScheduledTaskTestSuite) Test_IsDue() {
+	now := time.Now()
+	past, _ := task_scheduler.NewScheduledTask("T1", "G1", "P1", now.Add(-time.Second))
+	present, _ := task_scheduler.NewScheduledTask("T2", "G1", "P2", now)
+	future, _ := task_scheduler.NewScheduledTask("T3", "G1", "P3", now.Add(time.Second))
+
+	s.Assert().True(past.IsDue(now))
+	s.Assert().True(present.IsDue(now))
+	s.Assert().False(future.IsDue(now))
+}
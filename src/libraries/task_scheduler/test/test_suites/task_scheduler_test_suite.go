@@ -0,0 +1,89 @@
+package test_suites
+
+import (
+	"context"
+	"duolingo/libraries/task_scheduler"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type TaskSchedulerTestSuite struct {
+	suite.Suite
+	scheduler *task_scheduler.TaskScheduler
+}
+
+func NewTaskSchedulerTestSuite(scheduler *task_scheduler.TaskScheduler) *TaskSchedulerTestSuite {
+	scheduler.
+		SetPollInterval(50 * time.Millisecond).
+		SetClaimTimeout(200 * time.Millisecond)
+	return &TaskSchedulerTestSuite{
+		scheduler: scheduler,
+	}
+}
+
+func (s *TaskSchedulerTestSuite) Test_Reschedule() {
+	task, _ := task_scheduler.NewScheduledTask(uuid.NewString(), "G1", "P1", time.Now().Add(time.Hour))
+	s.scheduler.Schedule(context.Background(), task)
+	defer s.scheduler.Cancel(context.Background(), task.Id)
+
+	dueAt := time.Now().Add(2 * time.Hour)
+	rescheduled, err := s.scheduler.Reschedule(context.Background(), task.Id, dueAt)
+	getResult, _ := s.scheduler.GetTask(context.Background(), task.Id)
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(dueAt.UnixMilli(), rescheduled.DueAt.UnixMilli())
+	s.Assert().Equal(dueAt.UnixMilli(), getResult.DueAt.UnixMilli())
+
+	_, notExistErr := s.scheduler.Reschedule(context.Background(), "not_exist_id", dueAt)
+	s.Assert().Equal(task_scheduler.ErrScheduledTaskNotExists, notExistErr)
+}
+
+func (s *TaskSchedulerTestSuite) Test_Dispatching() {
+	task, _ := task_scheduler.NewScheduledTask(uuid.NewString(), "G1", "P1", time.Now().Add(100*time.Millisecond))
+	canceled, _ := task_scheduler.NewScheduledTask(uuid.NewString(), "G1", "P2", time.Now().Add(100*time.Millisecond))
+	s.scheduler.Schedule(context.Background(), task)
+	s.scheduler.Schedule(context.Background(), canceled)
+	s.scheduler.Cancel(context.Background(), canceled.Id)
+
+	dispatched := []*task_scheduler.ScheduledTask{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s.scheduler.Dispatching(ctx, func(ctx context.Context, t *task_scheduler.ScheduledTask) error {
+		dispatched = append(dispatched, t)
+		return nil
+	})
+
+	_, getErr := s.scheduler.GetTask(context.Background(), task.Id)
+
+	s.Assert().Len(dispatched, 1)
+	s.Assert().True(containsTask(dispatched, task))
+	s.Assert().Equal(task_scheduler.ErrScheduledTaskNotExists, getErr)
+}
+
+func (s *TaskSchedulerTestSuite) Test_Dispatching_RetryFailedTask() {
+	task, _ := task_scheduler.NewScheduledTask(uuid.NewString(), "G1", "P1", time.Now())
+	s.scheduler.Schedule(context.Background(), task)
+	defer s.scheduler.Cancel(context.Background(), task.Id)
+
+	attempts := 0
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s.scheduler.Dispatching(ctx, func(ctx context.Context, t *task_scheduler.ScheduledTask) error {
+		if t.Equal(task) {
+			attempts++
+		}
+		if attempts == 1 {
+			return errors.New("first attempt failed")
+		}
+		return nil
+	})
+
+	_, getErr := s.scheduler.GetTask(context.Background(), task.Id)
+
+	s.Assert().Equal(2, attempts)
+	s.Assert().Equal(task_scheduler.ErrScheduledTaskNotExists, getErr)
+}
@@ -0,0 +1,154 @@
+package test_suites
+
+import (
+	"context"
+	"duolingo/libraries/task_scheduler"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type TaskStorageProxyTestSuite struct {
+	suite.Suite
+	proxy task_scheduler.TaskStorageProxy
+}
+
+func NewTaskStorageProxyTestSuite(proxy task_scheduler.TaskStorageProxy) *TaskStorageProxyTestSuite {
+	return &TaskStorageProxyTestSuite{
+		proxy: proxy,
+	}
+}
+
+func (s *TaskStorageProxyTestSuite) Test_SaveTask_GetTask() {
+	task, _ := task_scheduler.NewScheduledTask(uuid.NewString(), "G1", "P1", time.Now().Add(time.Hour))
+	saveErr := s.proxy.SaveTask(context.Background(), task)
+	defer s.proxy.DeleteTask(context.Background(), task.Id)
+
+	getResult1, getErr1 := s.proxy.GetTask(context.Background(), "not_exist_id")
+	getResult2, getErr2 := s.proxy.GetTask(context.Background(), task.Id)
+
+	s.Assert().NoError(saveErr)
+	s.Assert().Nil(getResult1)
+	s.Assert().Equal(task_scheduler.ErrScheduledTaskNotExists, getErr1)
+	s.Assert().NoError(getErr2)
+	s.Assert().True(task.Equal(getResult2))
+	s.Assert().Equal(task.Payload, getResult2.Payload)
+	s.Assert().Equal(task.DueAt.UnixMilli(), getResult2.DueAt.UnixMilli())
+}
+
+func (s *TaskStorageProxyTestSuite) Test_ListTasks() {
+	group := uuid.NewString()
+	t1, _ := task_scheduler.NewScheduledTask(uuid.NewString(), group, "P1", time.Now().Add(time.Hour))
+	t2, _ := task_scheduler.NewScheduledTask(uuid.NewString(), group, "P2", time.Now().Add(time.Hour))
+	s.proxy.SaveTask(context.Background(), t1)
+	s.proxy.SaveTask(context.Background(), t2)
+	defer s.proxy.DeleteTask(context.Background(), t1.Id)
+	defer s.proxy.DeleteTask(context.Background(), t2.Id)
+
+	tasks, listErr := s.proxy.ListTasks(context.Background(), group)
+	emptyTasks, emptyErr := s.proxy.ListTasks(context.Background(), "not_exist_group")
+
+	s.Assert().NoError(listErr)
+	s.Assert().Len(tasks, 2)
+	s.Assert().NoError(emptyErr)
+	s.Assert().Empty(emptyTasks)
+}
+
+func (s *TaskStorageProxyTestSuite) Test_DeleteTask() {
+	task, _ := task_scheduler.NewScheduledTask(uuid.NewString(), uuid.NewString(), "P1", time.Now().Add(time.Hour))
+	s.proxy.SaveTask(context.Background(), task)
+
+	deleteErr1 := s.proxy.DeleteTask(context.Background(), task.Id)
+	deleteErr2 := s.proxy.DeleteTask(context.Background(), task.Id)
+	getResult, getErr := s.proxy.GetTask(context.Background(), task.Id)
+	tasks, _ := s.proxy.ListTasks(context.Background(), task.Group)
+
+	s.Assert().NoError(deleteErr1)
+	s.Assert().Equal(task_scheduler.ErrScheduledTaskNotExists, deleteErr2)
+	s.Assert().Nil(getResult)
+	s.Assert().Equal(task_scheduler.ErrScheduledTaskNotExists, getErr)
+	s.Assert().Empty(tasks)
+}
+
+func (s *TaskStorageProxyTestSuite) Test_ClaimDueTasks() {
+	due, _ := task_scheduler.NewScheduledTask(uuid.NewString(), "G1", "P1", time.Now().Add(-time.Second))
+	notDue, _ := task_scheduler.NewScheduledTask(uuid.NewString(), "G1", "P2", time.Now().Add(time.Hour))
+	s.proxy.SaveTask(context.Background(), due)
+	s.proxy.SaveTask(context.Background(), notDue)
+	defer s.proxy.DeleteTask(context.Background(), notDue.Id)
+
+	claimTimeout := 200 * time.Millisecond
+	claimed1, claimErr1 := s.proxy.ClaimDueTasks(context.Background(), 100, claimTimeout)
+	claimed2, _ := s.proxy.ClaimDueTasks(context.Background(), 100, claimTimeout)
+	time.Sleep(2 * claimTimeout)
+	claimed3, _ := s.proxy.ClaimDueTasks(context.Background(), 100, claimTimeout)
+	for _, task := range claimed3 {
+		s.proxy.DeleteClaimedTask(context.Background(), task)
+	}
+
+	s.Assert().NoError(claimErr1)
+	s.Assert().True(containsTask(claimed1, due))
+	s.Assert().False(containsTask(claimed1, notDue))
+	// claimed tasks are hidden until the claim timeout has passed
+	s.Assert().False(containsTask(claimed2, due))
+	s.Assert().True(containsTask(claimed3, due))
+}
+
+func (s *TaskStorageProxyTestSuite) Test_Claimed_Task_Refuses_Save_And_Delete() {
+	task, _ := task_scheduler.NewScheduledTask(uuid.NewString(), "G1", "P1", time.Now().Add(-time.Second))
+	s.proxy.SaveTask(context.Background(), task)
+
+	claimTimeout := 200 * time.Millisecond
+	claimed, _ := s.proxy.ClaimDueTasks(context.Background(), 100, claimTimeout)
+	rescheduled, _ := task_scheduler.NewScheduledTask(task.Id, "G1", "P1", time.Now().Add(time.Hour))
+	saveErr := s.proxy.SaveTask(context.Background(), rescheduled)
+	deleteErr := s.proxy.DeleteTask(context.Background(), task.Id)
+	time.Sleep(2 * claimTimeout)
+	// an expired claim no longer protects the task
+	deleteAfterExpiryErr := s.proxy.DeleteTask(context.Background(), task.Id)
+
+	s.Assert().True(containsTask(claimed, task))
+	s.Assert().Equal(task_scheduler.ErrScheduledTaskClaimed, saveErr)
+	s.Assert().Equal(task_scheduler.ErrScheduledTaskClaimed, deleteErr)
+	s.Assert().NoError(deleteAfterExpiryErr)
+}
+
+func (s *TaskStorageProxyTestSuite) Test_DeleteClaimedTask() {
+	task, _ := task_scheduler.NewScheduledTask(uuid.NewString(), "G1", "P1", time.Now().Add(-time.Second))
+	s.proxy.SaveTask(context.Background(), task)
+
+	claimTimeout := 200 * time.Millisecond
+	firstClaim, _ := s.proxy.ClaimDueTasks(context.Background(), 100, claimTimeout)
+	time.Sleep(2 * claimTimeout)
+	secondClaim, _ := s.proxy.ClaimDueTasks(context.Background(), 100, claimTimeout)
+	s.Require().True(containsTask(firstClaim, task))
+	s.Require().True(containsTask(secondClaim, task))
+
+	lostErr := s.proxy.DeleteClaimedTask(context.Background(), findTask(firstClaim, task))
+	deleteErr := s.proxy.DeleteClaimedTask(context.Background(), findTask(secondClaim, task))
+	_, getErr := s.proxy.GetTask(context.Background(), task.Id)
+
+	// the first claim expired and was taken over by the second one
+	s.Assert().Equal(task_scheduler.ErrScheduledTaskClaimLost, lostErr)
+	s.Assert().NoError(deleteErr)
+	s.Assert().Equal(task_scheduler.ErrScheduledTaskNotExists, getErr)
+}
+
+func findTask(tasks []*task_scheduler.ScheduledTask, target *task_scheduler.ScheduledTask) *task_scheduler.ScheduledTask {
+	for _, task := range tasks {
+		if task.Equal(target) {
+			return task
+		}
+	}
+	return nil
+}
+
+func containsTask(tasks []*task_scheduler.ScheduledTask, target *task_scheduler.ScheduledTask) bool {
+	for _, task := range tasks {
+		if task.Equal(target) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,7 +3,8 @@ package work_distributor
 import "errors"
 
 var (
-	ErrInvalidAssignment = errors.New("invalid assignment parameters")
+	ErrInvalidAssignment   = errors.New("invalid assignment parameters")
+	ErrAssignmentLeaseLost = errors.New("assignment lease has expired or been reclaimed")
 )
 
 type Assignment struct {
@@ -12,6 +13,21 @@ type Assignment struct {
 	StartIndex int64  `json:"start_idx"`
 	EndIndex   int64  `json:"end_idx"`
 	Progress   int64  `json:"progress"`
+
+	// The range of the keys to work on, set when the workload is split by key
+	// boundaries rather than by indexes. The indexes then only count the ranges.
+	KeyRange *KeyRange `json:"key_range,omitempty"`
+
+	// Identify the lease currently held on the assignment. It is never stored
+	// within the queue, every assign operation grants a new one.
+	LeaseToken string `json:"-"`
+}
+
+// KeyRange bounds the keys of an assignment, "After" is exclusive and "Until"
+// inclusive. The empty bound is unbounded.
+type KeyRange struct {
+	After string `json:"after"`
+	Until string `json:"until"`
 }
 
 func NewAssignment(
@@ -33,6 +49,29 @@ func NewAssignment(
 	return assignment, nil
 }
 
+// NewKeyRangeAssignment creates the assignment of the keys within the range,
+// the index is the position of the range within the workload.
+func NewKeyRangeAssignment(
+	id string,
+	workloadId string,
+	index int64,
+	after string,
+	until string,
+) (*Assignment, error) {
+	assignment := &Assignment{
+		Id:         id,
+		WorkloadId: workloadId,
+		StartIndex: index,
+		EndIndex:   index,
+		Progress:   0,
+		KeyRange:   &KeyRange{After: after, Until: until},
+	}
+	if err := assignment.Validate(); err != nil {
+		return nil, err
+	}
+	return assignment, nil
+}
+
 func (assignment *Assignment) Validate() error {
 	if assignment.Id == "" ||
 		assignment.WorkloadId == "" ||
@@ -40,6 +79,9 @@ func (assignment *Assignment) Validate() error {
 		assignment.Progress > assignment.EndIndex {
 		return ErrInvalidAssignment
 	}
+	if r := assignment.KeyRange; r != nil && r.After != "" && r.Until != "" && r.After >= r.Until {
+		return ErrInvalidAssignment
+	}
 	return nil
 }
 
@@ -54,6 +96,14 @@ func (assignment *Assignment) WorkEndAt() int64 {
 	return assignment.EndIndex
 }
 
+func (assignment *Assignment) IsKeyRanged() bool {
+	return assignment.KeyRange != nil
+}
+
+func (assignment *Assignment) IsLeased() bool {
+	return assignment.LeaseToken != ""
+}
+
 func (assignment *Assignment) IsCompleted() bool {
 	return assignment.Progress == assignment.EndIndex
 }
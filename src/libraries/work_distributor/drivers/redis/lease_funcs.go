@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+### Lease storage:
+ 1. A hash maps the assignment id to its lease record, which holds the lease
+    token and the assignment json as it was popped from the queue.
+ 2. A sorted set maps the assignment id to its lease expiration (unix ms).
+ 3. Expiration timestamps are computed from the redis server TIME, so that
+    workers with clock skew still agree on when a lease expires.
+*/
+
+const luaNowMillis = `
+	local now = redis.call("TIME")
+	local now_ms = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+`
+
+// leaseRecord is the value stored for each leased assignment
+type leaseRecord struct {
+	Token      string `json:"token"`
+	Assignment string `json:"assignment"`
+}
+
+func leaseAssignment(
+	ctx context.Context,
+	rdb *redis.Client,
+	workloadId string,
+	leaseToken string,
+	leaseDuration time.Duration,
+) (string, error) {
+	script := redis.NewScript(luaNowMillis + `
+		local assignment = redis.call("LPOP", KEYS[1])
+		if not assignment then
+			return false -- The queue is empty
+		end
+
+		local id = cjson.decode(assignment)["id"]
+		local record = cjson.encode({ token = ARGV[1], assignment = assignment })
+		redis.call("HSET", KEYS[2], id, record)
+		redis.call("ZADD", KEYS[3], now_ms + tonumber(ARGV[2]), id)
+
+		return assignment
+	`)
+	keys := []string{
+		assignmentsOfWorkloadKey(workloadId),
+		assignmentLeasesKey(workloadId),
+		assignmentLeaseExpiriesKey(workloadId),
+	}
+	return script.Run(ctx, rdb, keys, leaseToken, leaseDuration.Milliseconds()).Text()
+}
+
+func extendLease(
+	ctx context.Context,
+	rdb *redis.Client,
+	workloadId string,
+	assignmentId string,
+	leaseToken string,
+	leaseDuration time.Duration,
+) (bool, error) {
+	script := redis.NewScript(luaNowMillis + `
+		local record = redis.call("HGET", KEYS[1], ARGV[1])
+		if not record or cjson.decode(record)["token"] ~= ARGV[2] then
+			return 0 -- The lease is lost
+		end
+
+		redis.call("ZADD", KEYS[2], "XX", now_ms + tonumber(ARGV[3]), ARGV[1])
+
+		return 1
+	`)
+	keys := []string{
+		assignmentLeasesKey(workloadId),
+		assignmentLeaseExpiriesKey(workloadId),
+	}
+	result, err := script.Run(ctx, rdb, keys, assignmentId, leaseToken, leaseDuration.Milliseconds()).Int()
+	return result == 1, err
+}
+
+func requeueLease(
+	ctx context.Context,
+	rdb *redis.Client,
+	workloadId string,
+	assignmentId string,
+	leaseToken string,
+	assignmentJson string,
+) (bool, error) {
+	script := redis.NewScript(`
+		local record = redis.call("HGET", KEYS[2], ARGV[1])
+		if not record or cjson.decode(record)["token"] ~= ARGV[2] then
+			return 0 -- The lease is lost
+		end
+
+		redis.call("HDEL", KEYS[2], ARGV[1])
+		redis.call("ZREM", KEYS[3], ARGV[1])
+		redis.call("RPUSH", KEYS[1], ARGV[3])
+
+		return 1
+	`)
+	keys := []string{
+		assignmentsOfWorkloadKey(workloadId),
+		assignmentLeasesKey(workloadId),
+		assignmentLeaseExpiriesKey(workloadId),
+	}
+	result, err := script.Run(ctx, rdb, keys, assignmentId, leaseToken, assignmentJson).Int()
+	return result == 1, err
+}
+
+func requeueExpiredLeases(
+	ctx context.Context,
+	rdb *redis.Client,
+	workloadId string,
+) (int64, error) {
+	script := redis.NewScript(luaNowMillis + `
+		local expired = redis.call("ZRANGEBYSCORE", KEYS[3], "-inf", now_ms)
+		for i, id in ipairs(expired) do
+			local record = redis.call("HGET", KEYS[2], id)
+			if record then
+				redis.call("RPUSH", KEYS[1], cjson.decode(record)["assignment"])
+				redis.call("HDEL", KEYS[2], id)
+			end
+			redis.call("ZREM", KEYS[3], id)
+		end
+
+		return #expired
+	`)
+	keys := []string{
+		assignmentsOfWorkloadKey(workloadId),
+		assignmentLeasesKey(workloadId),
+		assignmentLeaseExpiriesKey(workloadId),
+	}
+	return script.Run(ctx, rdb, keys).Int64()
+}
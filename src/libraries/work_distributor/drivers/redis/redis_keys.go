@@ -15,6 +15,14 @@ func assignmentsOfWorkloadKey(workloadId string) string {
 	return "work_distributor:workload_assignments:" + workloadId
 }
 
+func assignmentLeasesKey(workloadId string) string {
+	return "work_distributor:workload_leases:" + workloadId
+}
+
+func assignmentLeaseExpiriesKey(workloadId string) string {
+	return "work_distributor:workload_lease_expiries:" + workloadId
+}
+
 func errOrRedisNilAlias(err error, ifNilErr error) error {
 	if err == redis.Nil {
 		return ifNilErr
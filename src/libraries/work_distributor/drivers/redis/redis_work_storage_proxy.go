@@ -6,7 +6,9 @@ import (
 	events "duolingo/libraries/events/facade"
 	distributor "duolingo/libraries/work_distributor"
 	"encoding/json"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -19,6 +21,8 @@ const (
 ### Notions:
  1. Some of the operations use transactions without retry on "redis.TxFailedErr",
     due to the ExecuteClosureWithLocks() already ensures atomicity with distributed-lock.
+ 2. The lease operations run as Lua scripts, which are atomic by themselves,
+    hence they do not acquire the distributed-lock.
 */
 type RedisWorkStorageProxy struct {
 	connection.RedisClient
@@ -237,6 +241,8 @@ func (proxy *RedisWorkStorageProxy) DeleteWorkloadAndAssignments(
 			_, pipelineErr := tx.TxPipelined(timeoutCtx, func(pipe redis.Pipeliner) error {
 				pipe.Del(timeoutCtx, workloadKey(workloadId))
 				pipe.Del(timeoutCtx, assignmentsOfWorkloadKey(workloadId))
+				pipe.Del(timeoutCtx, assignmentLeasesKey(workloadId))
+				pipe.Del(timeoutCtx, assignmentLeaseExpiriesKey(workloadId))
 				return nil
 			})
 			return pipelineErr
@@ -245,3 +251,184 @@ func (proxy *RedisWorkStorageProxy) DeleteWorkloadAndAssignments(
 
 	return err
 }
+
+func (proxy *RedisWorkStorageProxy) LeaseAssignmentFromQueue(
+	ctx context.Context,
+	workloadId string,
+	leaseDuration time.Duration,
+) (*distributor.Assignment, error) {
+	var err error
+
+	evt := events.Start(ctx, "work_dist.proxy.redis.lease_assignment", nil)
+	defer events.End(evt, true, err, nil)
+
+	var assignmentJson string
+	var leaseToken = uuid.NewString()
+	err = proxy.ExecuteClosure(evt.Context(), proxy.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		var scriptErr error
+		assignmentJson, scriptErr = leaseAssignment(timeoutCtx, rdb, workloadId, leaseToken, leaseDuration)
+		return scriptErr
+	})
+	if err != nil {
+		// return no error instead of RedisNil (indicates the queue is empty)
+		err = errOrRedisNilAlias(err, nil)
+		return nil, err
+	}
+
+	assignment := new(distributor.Assignment)
+	if err = json.Unmarshal([]byte(assignmentJson), assignment); err != nil {
+		return nil, err
+	}
+	assignment.LeaseToken = leaseToken
+
+	return assignment, nil
+}
+
+func (proxy *RedisWorkStorageProxy) ExtendAssignmentLease(
+	ctx context.Context,
+	assignment *distributor.Assignment,
+	leaseDuration time.Duration,
+) error {
+	var err error
+
+	evt := events.Start(ctx, "work_dist.proxy.redis.extend_assignment_lease", nil)
+	defer events.End(evt, true, err, nil)
+
+	err = proxy.ExecuteClosure(evt.Context(), proxy.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		extended, scriptErr := extendLease(
+			timeoutCtx,
+			rdb,
+			assignment.WorkloadId,
+			assignment.Id,
+			assignment.LeaseToken,
+			leaseDuration,
+		)
+		if scriptErr == nil && !extended {
+			return distributor.ErrAssignmentLeaseLost
+		}
+		return scriptErr
+	})
+
+	return err
+}
+
+func (proxy *RedisWorkStorageProxy) RequeueLeasedAssignment(
+	ctx context.Context,
+	assignment *distributor.Assignment,
+) error {
+	var err error
+
+	evt := events.Start(ctx, "work_dist.proxy.redis.requeue_leased_assignment", nil)
+	defer events.End(evt, true, err, nil)
+
+	if err = assignment.Validate(); err != nil {
+		return err
+	}
+	assignmentJson, marshalErr := json.Marshal(assignment)
+	if marshalErr != nil {
+		err = marshalErr
+		return err
+	}
+
+	err = proxy.ExecuteClosure(evt.Context(), proxy.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		requeued, scriptErr := requeueLease(
+			timeoutCtx,
+			rdb,
+			assignment.WorkloadId,
+			assignment.Id,
+			assignment.LeaseToken,
+			string(assignmentJson),
+		)
+		if scriptErr == nil && !requeued {
+			return distributor.ErrAssignmentLeaseLost
+		}
+		return scriptErr
+	})
+
+	return err
+}
+
+func (proxy *RedisWorkStorageProxy) CommitLeasedAssignment(
+	ctx context.Context,
+	assignment *distributor.Assignment,
+	modifier func(*distributor.Workload) error,
+) error {
+	var err error
+
+	evt := events.Start(ctx, "work_dist.proxy.redis.commit_leased_assignment", nil)
+	defer events.End(evt, true, err, nil)
+
+	workloadId := assignment.WorkloadId
+	lockKeys := []string{
+		workloadKey(workloadId),
+	}
+	err = proxy.ExecuteClosureWithLocks(evt.Context(), lockKeys, proxy.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		leasesKey := assignmentLeasesKey(workloadId)
+		// ensure the lease is still held while the workload is updated,
+		// the lease can be reclaimed concurrently without the workload lock
+		return rdb.Watch(timeoutCtx, func(tx *redis.Tx) error {
+			recordJson, leaseErr := tx.HGet(timeoutCtx, leasesKey, assignment.Id).Result()
+			if leaseErr != nil {
+				return errOrRedisNilAlias(leaseErr, distributor.ErrAssignmentLeaseLost)
+			}
+			record := new(leaseRecord)
+			if json.Unmarshal([]byte(recordJson), record) != nil || record.Token != assignment.LeaseToken {
+				return distributor.ErrAssignmentLeaseLost
+			}
+			// get workload from storage, then call the modifier
+			workloadJson, readErr := tx.Get(timeoutCtx, workloadKey(workloadId)).Result()
+			if readErr != nil {
+				return errOrRedisNilAlias(readErr, distributor.ErrWorkloadNotExists)
+			}
+			workload := unmarshalWorkloadIgnoreErr(workloadJson)
+			if modifyErr := modifier(workload); modifyErr != nil {
+				return modifyErr
+			}
+			updated := marshalWorkloadIgnoreErr(workload)
+			// store the workload and release the lease altogether
+			_, pipelineErr := tx.TxPipelined(timeoutCtx, func(pipe redis.Pipeliner) error {
+				pipe.Set(timeoutCtx, workloadKey(workloadId), updated, 0)
+				pipe.HDel(timeoutCtx, leasesKey, assignment.Id)
+				pipe.ZRem(timeoutCtx, assignmentLeaseExpiriesKey(workloadId), assignment.Id)
+				return nil
+			})
+			return pipelineErr
+		}, leasesKey, workloadKey(workloadId))
+	})
+
+	return err
+}
+
+func (proxy *RedisWorkStorageProxy) RequeueExpiredLeases(
+	ctx context.Context,
+	workloadId string,
+) (int64, error) {
+	var total int64
+	var err error
+
+	evt := events.Start(ctx, "work_dist.proxy.redis.requeue_expired_leases", nil)
+	defer events.End(evt, true, err, nil)
+
+	err = proxy.ExecuteClosure(evt.Context(), proxy.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		var scriptErr error
+		total, scriptErr = requeueExpiredLeases(timeoutCtx, rdb, workloadId)
+		return scriptErr
+	})
+
+	return total, err
+}
@@ -40,3 +40,23 @@ func (s *AssignmentTestSuite) Test_WorkStartAt_WorkEndAt() {
 
 	s.Assert().Equal(int64(51), assignment.WorkStartAt())
 }
+
+func (s *AssignmentTestSuite) Test_NewKeyRangeAssignment() {
+	a1, err1 := work_distributor.NewKeyRangeAssignment("A1", "W1", 1, "user_9", "user_1") // until < after
+	s.Assert().Nil(a1)
+	s.Assert().Error(err1)
+
+	a2, err2 := work_distributor.NewKeyRangeAssignment("A2", "W1", 1, "", "user_5")
+	a3, err3 := work_distributor.NewKeyRangeAssignment("A3", "W1", 2, "user_5", "")
+	s.Assert().NoError(err2)
+	s.Assert().NoError(err3)
+	s.Assert().True(a2.IsKeyRanged())
+	s.Assert().Equal(&work_distributor.KeyRange{After: "user_5", Until: ""}, a3.KeyRange)
+
+	// the key range has a single index, completed once committed
+	a3.Progress = a3.WorkEndAt()
+	s.Assert().True(a3.IsCompleted())
+
+	a4, _ := work_distributor.NewAssignment("A4", "W1", 1, 10)
+	s.Assert().False(a4.IsKeyRanged())
+}
@@ -22,9 +22,9 @@ func NewWorkDistributorTestSuite(distributor *distributor.WorkDistributor) *Work
 }
 
 func (s *WorkDistributorTestSuite) Test_CreateWorkload_GetWorkload_And_AssignAll() {
-	workload, err := s.distributor.CreateWorkload(100)
-	getResult, getErr := s.distributor.GetWorkload(workload.Id)
-	defer s.distributor.DeleteWorkloadAndAssignments(workload.Id)
+	workload, err := s.distributor.CreateWorkload(context.Background(), 100)
+	getResult, getErr := s.distributor.GetWorkload(context.Background(), workload.Id)
+	defer s.distributor.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
 
 	s.Assert().NotNil(workload)
 	s.Assert().NoError(err)
@@ -34,11 +34,11 @@ func (s *WorkDistributorTestSuite) Test_CreateWorkload_GetWorkload_And_AssignAll
 	total := workload.GetExpectTotalAssignments()
 	assignments := []*distributor.Assignment{}
 	for {
-		assigned, assignErr := s.distributor.Assign(workload.Id)
+		assigned, assignErr := s.distributor.Assign(context.Background(), workload.Id)
 		if assigned == nil || assignErr != nil {
 			break
 		}
-		s.distributor.Commit(assigned)
+		s.distributor.Commit(context.Background(), assigned)
 		assignments = append(assignments, assigned)
 	}
 
@@ -58,14 +58,14 @@ func (s *WorkDistributorTestSuite) Test_CreateWorkload_GetWorkload_And_AssignAll
 }
 
 func (s *WorkDistributorTestSuite) Test_CommitAssignment_And_HasWorkloadFulfilled() {
-	workload, _ := s.distributor.CreateWorkload(100)
-	defer s.distributor.DeleteWorkloadAndAssignments(workload.Id)
+	workload, _ := s.distributor.CreateWorkload(context.Background(), 100)
+	defer s.distributor.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
 
 	total := workload.GetExpectTotalAssignments()
 	assignments := []*distributor.Assignment{}
 	for {
 		// if HasWorkloadFulfilled not work, later assertion would fail
-		isFulfilled, isFulfilledErr := s.distributor.HasWorkloadFulfilled(workload.Id)
+		isFulfilled, isFulfilledErr := s.distributor.HasWorkloadFulfilled(context.Background(), workload.Id)
 		if isFulfilled {
 			break
 		}
@@ -73,40 +73,118 @@ func (s *WorkDistributorTestSuite) Test_CommitAssignment_And_HasWorkloadFulfille
 			break
 		}
 		// store the assignment
-		assigned, assignErr := s.distributor.Assign(workload.Id)
+		assigned, assignErr := s.distributor.Assign(context.Background(), workload.Id)
 		if assigned == nil && assignErr == distributor.ErrWorkloadHasAlreadyFulfilled {
 			break
 		}
 		assignments = append(assignments, assigned)
 		// commit the assignment
-		commitErr := s.distributor.Commit(assigned)
+		commitErr := s.distributor.Commit(context.Background(), assigned)
 		s.Assert().NoError(commitErr)
 	}
 
 	if s.Assert().Equal(total, int64(len(assignments))) {
-		isFulfilled, isFulfilledErr := s.distributor.HasWorkloadFulfilled(workload.Id)
+		isFulfilled, isFulfilledErr := s.distributor.HasWorkloadFulfilled(context.Background(), workload.Id)
 		s.Assert().True(isFulfilled)
 		s.Assert().NoError(isFulfilledErr)
 	}
 }
 
+func (s *WorkDistributorTestSuite) Test_CreateKeyedWorkload_And_AssignAll() {
+	boundaries := []string{"user_3", "user_6"}
+	workload, err := s.distributor.CreateKeyedWorkload(context.Background(), 100, boundaries)
+	if !s.Assert().NoError(err) {
+		return
+	}
+	defer s.distributor.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
+
+	s.Assert().Equal(int64(3), workload.GetExpectTotalAssignments())
+
+	ranges := []distributor.KeyRange{}
+	for {
+		assigned, assignErr := s.distributor.Assign(context.Background(), workload.Id)
+		if assigned == nil || assignErr != nil {
+			break
+		}
+		if s.Assert().True(assigned.IsKeyRanged()) {
+			ranges = append(ranges, *assigned.KeyRange)
+		}
+		s.Assert().NoError(s.distributor.Commit(context.Background(), assigned))
+	}
+
+	s.Assert().Equal([]distributor.KeyRange{
+		{After: "", Until: "user_3"},
+		{After: "user_3", Until: "user_6"},
+		{After: "user_6", Until: ""},
+	}, ranges)
+	isFulfilled, _ := s.distributor.HasWorkloadFulfilled(context.Background(), workload.Id)
+	s.Assert().True(isFulfilled)
+}
+
+func (s *WorkDistributorTestSuite) Test_AssignAny_Interleaves_Workloads() {
+	size := s.distributor.GetDistributionSize()
+	large, _ := s.distributor.CreateWorkload(context.Background(), 10*size)
+	small, _ := s.distributor.CreateWorkload(context.Background(), 2*size)
+	defer s.distributor.DeleteWorkloadAndAssignments(context.Background(), large.Id)
+	defer s.distributor.DeleteWorkloadAndAssignments(context.Background(), small.Id)
+
+	registry := s.distributor.GetRegistry()
+	registry.Register(large.Id, 1)
+	registry.Register(small.Id, 2)
+	defer registry.Unregister(large.Id)
+	defer registry.Unregister(small.Id)
+
+	assigned := []string{}
+	fulfilled := []string{}
+	for range 20 {
+		if registry.Len() == 0 {
+			break
+		}
+		assignment, err := s.distributor.AssignAny(context.Background())
+		if err != nil {
+			var workloadErr *distributor.WorkloadError
+			if s.Assert().ErrorAs(err, &workloadErr) && s.Assert().ErrorIs(err, distributor.ErrWorkloadHasAlreadyFulfilled) {
+				fulfilled = append(fulfilled, workloadErr.WorkloadId)
+			}
+			continue
+		}
+		if s.Assert().NotNil(assignment) {
+			assigned = append(assigned, assignment.WorkloadId)
+			s.Assert().NoError(s.distributor.Commit(context.Background(), assignment))
+		}
+	}
+
+	// the small workload is not held back until the large one is drained
+	s.Assert().Equal([]string{small.Id, large.Id, small.Id}, assigned[:3])
+	s.Assert().Len(assigned, 12)
+	s.Assert().Equal([]string{small.Id, large.Id}, fulfilled)
+	s.Assert().Zero(registry.Len())
+}
+
+func (s *WorkDistributorTestSuite) Test_AssignAny_Without_Workloads() {
+	assignment, err := s.distributor.AssignAny(context.Background())
+
+	s.Assert().Nil(assignment)
+	s.Assert().NoError(err)
+}
+
 func (s *WorkDistributorTestSuite) Test_CommitProgress_And_Rollback() {
-	workload, _ := s.distributor.CreateWorkload(100)
-	defer s.distributor.DeleteWorkloadAndAssignments(workload.Id)
+	workload, _ := s.distributor.CreateWorkload(context.Background(), 100)
+	defer s.distributor.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
 
-	targetAssigment, _ := s.distributor.Assign(workload.Id)
+	targetAssigment, _ := s.distributor.Assign(context.Background(), workload.Id)
 	currentProgress := targetAssigment.Progress
 	newProgress := currentProgress + 1
 
-	commitErr := s.distributor.CommitProgress(targetAssigment, newProgress)
-	rollbackErr := s.distributor.Rollback(targetAssigment)
+	commitErr := s.distributor.CommitProgress(context.Background(), targetAssigment, newProgress)
+	rollbackErr := s.distributor.Rollback(context.Background(), targetAssigment)
 
 	s.Assert().NoError(commitErr)
 	s.Assert().NoError(rollbackErr)
 
 	var rollbackedFound *distributor.Assignment
 	for {
-		assigned, assignErr := s.distributor.Assign(workload.Id)
+		assigned, assignErr := s.distributor.Assign(context.Background(), workload.Id)
 		if assigned == nil || assignErr != nil {
 			break
 		}
@@ -121,13 +199,13 @@ func (s *WorkDistributorTestSuite) Test_CommitProgress_And_Rollback() {
 }
 
 func (s *WorkDistributorTestSuite) Test_WaitForAssignment_WaitUntilOneRollbacked() {
-	workload, _ := s.distributor.CreateWorkload(100)
-	defer s.distributor.DeleteWorkloadAndAssignments(workload.Id)
+	workload, _ := s.distributor.CreateWorkload(context.Background(), 100)
+	defer s.distributor.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
 
 	// Distribute all assignments
 	var assigned *distributor.Assignment
 	for {
-		newAssignment, _ := s.distributor.Assign(workload.Id)
+		newAssignment, _ := s.distributor.Assign(context.Background(), workload.Id)
 		if newAssignment == nil {
 			break
 		}
@@ -162,7 +240,7 @@ func (s *WorkDistributorTestSuite) Test_WaitForAssignment_WaitUntilOneRollbacked
 		for {
 			select {
 			case <-rollbackTimer:
-				s.distributor.Rollback(assigned)
+				s.distributor.Rollback(context.Background(), assigned)
 			case <-waitCtx.Done():
 				s.Assert().NoError(assignErr)
 				s.Assert().NotNil(assignedByWait)
@@ -175,13 +253,13 @@ func (s *WorkDistributorTestSuite) Test_WaitForAssignment_WaitUntilOneRollbacked
 }
 
 func (s *WorkDistributorTestSuite) Test_WaitForAssignment_WaitWillFailOnFulfilled() {
-	workload, _ := s.distributor.CreateWorkload(100)
-	defer s.distributor.DeleteWorkloadAndAssignments(workload.Id)
+	workload, _ := s.distributor.CreateWorkload(context.Background(), 100)
+	defer s.distributor.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
 
 	// Distribute all assignments
 	assignments := []*distributor.Assignment{}
 	for {
-		newAssignment, _ := s.distributor.Assign(workload.Id)
+		newAssignment, _ := s.distributor.Assign(context.Background(), workload.Id)
 		if newAssignment == nil {
 			break
 		}
@@ -213,7 +291,7 @@ func (s *WorkDistributorTestSuite) Test_WaitForAssignment_WaitWillFailOnFulfille
 	go func() {
 		defer wg.Done()
 		for i := range assignments {
-			s.distributor.Commit(assignments[i])
+			s.distributor.Commit(context.Background(), assignments[i])
 		}
 		time.Sleep(10 * time.Millisecond)
 		<-waitCtx.Done()
@@ -225,8 +303,8 @@ func (s *WorkDistributorTestSuite) Test_WaitForAssignment_WaitWillFailOnFulfille
 }
 
 func (s *WorkDistributorTestSuite) Test_HandleAssignment() {
-	workload, _ := s.distributor.CreateWorkload(20)
-	defer s.distributor.DeleteWorkloadAndAssignments(workload.Id)
+	workload, _ := s.distributor.CreateWorkload(context.Background(), 20)
+	defer s.distributor.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
 
 	// Steps:
 	// 1. First assignment             - Remains: assignment2
@@ -236,21 +314,80 @@ func (s *WorkDistributorTestSuite) Test_HandleAssignment() {
 	// 5. First assignment re-assigned - Remains: empty
 	// 6. Handle succeeded, workload fulfilled
 
-	assignment1, _ := s.distributor.Assign(workload.Id)
-	s.distributor.HandleAssignment(assignment1, func() error {
+	assignment1, _ := s.distributor.Assign(context.Background(), workload.Id)
+	s.distributor.HandleAssignment(context.Background(), assignment1, func(ctx context.Context) error {
 		return errors.New("stimulate failure")
 	})
-	assignment2, _ := s.distributor.Assign(workload.Id)
-	s.distributor.HandleAssignment(assignment2, func() error {
+	assignment2, _ := s.distributor.Assign(context.Background(), workload.Id)
+	s.distributor.HandleAssignment(context.Background(), assignment2, func(ctx context.Context) error {
 		return nil
 	})
-	assignment3, _ := s.distributor.Assign(workload.Id)
-	s.distributor.HandleAssignment(assignment3, func() error {
+	assignment3, _ := s.distributor.Assign(context.Background(), workload.Id)
+	s.distributor.HandleAssignment(context.Background(), assignment3, func(ctx context.Context) error {
 		return nil
 	})
 
 	// ensure first assignment rollbacked
 	s.Assert().True(assignment3.Equal(assignment1))
 	// ensure all assignments committed
-	s.Assert().True(s.distributor.HasWorkloadFulfilled(workload.Id))
+	s.Assert().True(s.distributor.HasWorkloadFulfilled(context.Background(), workload.Id))
+}
+
+func (s *WorkDistributorTestSuite) Test_WaitForAssignment_ReclaimExpiredLease() {
+	leaseDuration := s.distributor.GetLeaseDuration()
+	s.distributor.SetLeaseDuration(50 * time.Millisecond)
+	defer s.distributor.SetLeaseDuration(leaseDuration)
+
+	workload, _ := s.distributor.CreateWorkload(context.Background(), 10)
+	defer s.distributor.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
+
+	// the worker holding the only assignment crashed, and never commits
+	abandoned, _ := s.distributor.Assign(context.Background(), workload.Id)
+	if !s.Assert().NotNil(abandoned) {
+		return
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer waitCancel()
+
+	reclaimed, waitErr := s.distributor.WaitForAssignment(waitCtx, 5*time.Millisecond, workload.Id)
+	if !s.Assert().NoError(waitErr) {
+		return
+	}
+	s.Assert().True(abandoned.Equal(reclaimed))
+
+	// the abandoned lease is no longer valid
+	s.Assert().Equal(distributor.ErrAssignmentLeaseLost, s.distributor.Commit(context.Background(), abandoned))
+	s.Assert().NoError(s.distributor.Commit(context.Background(), reclaimed))
+	s.Assert().True(s.distributor.HasWorkloadFulfilled(context.Background(), workload.Id))
+}
+
+func (s *WorkDistributorTestSuite) Test_SetLeaseDuration_Ignores_Non_Positive() {
+	leaseDuration := s.distributor.GetLeaseDuration()
+	defer s.distributor.SetLeaseDuration(leaseDuration)
+
+	s.distributor.SetLeaseDuration(0)
+	s.Assert().Equal(leaseDuration, s.distributor.GetLeaseDuration())
+	s.distributor.SetLeaseDuration(-time.Second)
+	s.Assert().Equal(leaseDuration, s.distributor.GetLeaseDuration())
+}
+
+func (s *WorkDistributorTestSuite) Test_HandleAssignment_KeepLeaseAlive() {
+	leaseDuration := s.distributor.GetLeaseDuration()
+	s.distributor.SetLeaseDuration(30 * time.Millisecond)
+	defer s.distributor.SetLeaseDuration(leaseDuration)
+
+	workload, _ := s.distributor.CreateWorkload(context.Background(), 10)
+	defer s.distributor.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
+
+	assignment, _ := s.distributor.Assign(context.Background(), workload.Id)
+	handleErr := s.distributor.HandleAssignment(context.Background(), assignment, func(ctx context.Context) error {
+		// outlive the lease duration, the lease must have been extended
+		time.Sleep(100 * time.Millisecond)
+		s.distributor.ReclaimExpiredAssignments(ctx, workload.Id)
+		return ctx.Err()
+	})
+
+	s.Assert().NoError(handleErr)
+	s.Assert().True(s.distributor.HasWorkloadFulfilled(context.Background(), workload.Id))
 }
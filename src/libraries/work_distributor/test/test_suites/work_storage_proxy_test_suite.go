@@ -1,7 +1,9 @@
 package test_suites
 
 import (
+	"context"
 	"duolingo/libraries/work_distributor"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/suite"
@@ -20,9 +22,9 @@ func NewWorkStorageProxyTestSuite(proxy work_distributor.WorkStorageProxy) *Work
 
 func (s *WorkStorageProxyTestSuite) Test_SaveWorkload() {
 	workload, _ := work_distributor.NewWorkload(uuid.NewString(), 100, 10)
-	saveErr := s.proxy.SaveWorkload(workload)
-	getResult, _ := s.proxy.GetWorkload(workload.Id)
-	defer s.proxy.DeleteWorkloadAndAssignments(workload.Id)
+	saveErr := s.proxy.SaveWorkload(context.Background(), workload)
+	getResult, _ := s.proxy.GetWorkload(context.Background(), workload.Id)
+	defer s.proxy.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
 
 	s.Assert().NoError(saveErr)
 	s.Assert().True(workload.Equal(getResult))
@@ -30,11 +32,11 @@ func (s *WorkStorageProxyTestSuite) Test_SaveWorkload() {
 
 func (s *WorkStorageProxyTestSuite) Test_GetWorkload() {
 	workload, _ := work_distributor.NewWorkload(uuid.NewString(), 100, 10)
-	s.proxy.SaveWorkload(workload)
-	defer s.proxy.DeleteWorkloadAndAssignments(workload.Id)
+	s.proxy.SaveWorkload(context.Background(), workload)
+	defer s.proxy.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
 
-	getResult1, getErr1 := s.proxy.GetWorkload("not_exist_id")
-	getResult2, getErr2 := s.proxy.GetWorkload(workload.Id)
+	getResult1, getErr1 := s.proxy.GetWorkload(context.Background(), "not_exist_id")
+	getResult2, getErr2 := s.proxy.GetWorkload(context.Background(), workload.Id)
 
 	s.Assert().Nil(getResult1)
 	s.Assert().Equal(work_distributor.ErrWorkloadNotExists, getErr1)
@@ -46,59 +48,149 @@ func (s *WorkStorageProxyTestSuite) Test_GetWorkload() {
 func (s *WorkStorageProxyTestSuite) Test_GetAndUpdateWorkload() {
 
 	workload, _ := work_distributor.NewWorkload(uuid.NewString(), 100, 10)
-	s.proxy.SaveWorkload(workload)
-	defer s.proxy.DeleteWorkloadAndAssignments(workload.Id)
+	s.proxy.SaveWorkload(context.Background(), workload)
+	defer s.proxy.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
 
-	saveErr := s.proxy.GetAndUpdateWorkload(workload.Id, func(w *work_distributor.Workload) error {
+	saveErr := s.proxy.GetAndUpdateWorkload(context.Background(), workload.Id, func(w *work_distributor.Workload) error {
 		w.TotalCommittedAssignments = w.GetExpectTotalAssignments()
 		return nil
 	})
-	getResult, _ := s.proxy.GetWorkload(workload.Id)
+	getResult, _ := s.proxy.GetWorkload(context.Background(), workload.Id)
 	s.Assert().NoError(saveErr)
 	s.Assert().True(getResult.HasWorkloadFulfilled())
 }
 
 func (s *WorkStorageProxyTestSuite) Test_PushAndPop_AssignmentToQueue() {
 	workload, _ := work_distributor.NewWorkload(uuid.NewString(), 100, 10)
-	s.proxy.SaveWorkload(workload)
-	defer s.proxy.DeleteWorkloadAndAssignments(workload.Id)
+	s.proxy.SaveWorkload(context.Background(), workload)
+	defer s.proxy.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
 
 	assignment1, _ := work_distributor.NewAssignment("a1", workload.Id, 1, 10)
 	assignment2, _ := work_distributor.NewAssignment("a2", workload.Id, 1, 10)
 
-	pushErr1 := s.proxy.PushAssignmentToQueue(assignment1)
-	pushErr2 := s.proxy.PushAssignmentToQueue(assignment2)
+	pushErr1 := s.proxy.PushAssignmentToQueue(context.Background(), assignment1)
+	pushErr2 := s.proxy.PushAssignmentToQueue(context.Background(), assignment2)
 	if !s.Assert().NoError(pushErr1) || !s.Assert().NoError(pushErr2) {
 		return
 	}
 
-	poppedResult1, popErr1 := s.proxy.PopAssignmentFromQueue(workload.Id)
-	poppedResult2, popErr2 := s.proxy.PopAssignmentFromQueue(workload.Id)
+	poppedResult1, popErr1 := s.proxy.PopAssignmentFromQueue(context.Background(), workload.Id)
+	poppedResult2, popErr2 := s.proxy.PopAssignmentFromQueue(context.Background(), workload.Id)
 	if !s.Assert().NoError(popErr1) || !s.Assert().NoError(popErr2) {
 		return
 	}
 	s.Assert().True(assignment1.Equal(poppedResult1))
 	s.Assert().True(assignment2.Equal(poppedResult2))
 
-	poppedResult3, popErr3 := s.proxy.PopAssignmentFromQueue(workload.Id)
+	poppedResult3, popErr3 := s.proxy.PopAssignmentFromQueue(context.Background(), workload.Id)
 	s.Assert().Nil(poppedResult3)
 	s.Assert().NoError(popErr3)
 }
 
 func (s *WorkStorageProxyTestSuite) Test_DeleteWorkloadAndAssignments() {
 	workload, _ := work_distributor.NewWorkload(uuid.NewString(), 100, 10)
-	s.proxy.SaveWorkload(workload)
+	s.proxy.SaveWorkload(context.Background(), workload)
 
 	assignment1, _ := work_distributor.NewAssignment("a1", workload.Id, 1, 10)
 	assignment2, _ := work_distributor.NewAssignment("a2", workload.Id, 1, 10)
-	s.proxy.PushAssignmentToQueue(assignment1)
-	s.proxy.PushAssignmentToQueue(assignment2)
+	s.proxy.PushAssignmentToQueue(context.Background(), assignment1)
+	s.proxy.PushAssignmentToQueue(context.Background(), assignment2)
 
-	delErr := s.proxy.DeleteWorkloadAndAssignments(workload.Id)
-	_, getErr := s.proxy.GetWorkload(workload.Id)
-	_, popErr := s.proxy.PopAssignmentFromQueue(workload.Id)
+	delErr := s.proxy.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
+	_, getErr := s.proxy.GetWorkload(context.Background(), workload.Id)
+	_, popErr := s.proxy.PopAssignmentFromQueue(context.Background(), workload.Id)
 
 	s.Assert().NoError(delErr)
 	s.Assert().Equal(work_distributor.ErrWorkloadNotExists, getErr)
 	s.Assert().Error(work_distributor.ErrWorkloadNotExists, popErr)
 }
+
+func (s *WorkStorageProxyTestSuite) Test_LeaseAssignment_And_RequeueExpiredLeases() {
+	workload, _ := work_distributor.NewWorkload(uuid.NewString(), 100, 10)
+	s.proxy.SaveWorkload(context.Background(), workload)
+	defer s.proxy.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
+
+	assignment, _ := work_distributor.NewAssignment("a1", workload.Id, 1, 10)
+	s.proxy.PushAssignmentToQueue(context.Background(), assignment)
+
+	leased, leaseErr := s.proxy.LeaseAssignmentFromQueue(context.Background(), workload.Id, 50*time.Millisecond)
+	if !s.Assert().NoError(leaseErr) || !s.Assert().NotNil(leased) {
+		return
+	}
+	s.Assert().True(assignment.Equal(leased))
+	s.Assert().True(leased.IsLeased())
+
+	// the lease is not yet expired
+	total, requeueErr := s.proxy.RequeueExpiredLeases(context.Background(), workload.Id)
+	s.Assert().NoError(requeueErr)
+	s.Assert().Zero(total)
+
+	// the lease expired, the assignment is back to the queue
+	time.Sleep(100 * time.Millisecond)
+	total, requeueErr = s.proxy.RequeueExpiredLeases(context.Background(), workload.Id)
+	s.Assert().NoError(requeueErr)
+	s.Assert().Equal(int64(1), total)
+
+	reclaimed, _ := s.proxy.PopAssignmentFromQueue(context.Background(), workload.Id)
+	s.Assert().True(assignment.Equal(reclaimed))
+	s.Assert().False(reclaimed.IsLeased())
+
+	// the expired lease can not be committed nor extended
+	extendErr := s.proxy.ExtendAssignmentLease(context.Background(), leased, time.Second)
+	commitErr := s.proxy.CommitLeasedAssignment(context.Background(), leased, func(w *work_distributor.Workload) error {
+		return w.IncreaseTotalCommittedAssignments()
+	})
+	s.Assert().Equal(work_distributor.ErrAssignmentLeaseLost, extendErr)
+	s.Assert().Equal(work_distributor.ErrAssignmentLeaseLost, commitErr)
+}
+
+func (s *WorkStorageProxyTestSuite) Test_ExtendAssignmentLease() {
+	workload, _ := work_distributor.NewWorkload(uuid.NewString(), 100, 10)
+	s.proxy.SaveWorkload(context.Background(), workload)
+	defer s.proxy.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
+
+	assignment, _ := work_distributor.NewAssignment("a1", workload.Id, 1, 10)
+	s.proxy.PushAssignmentToQueue(context.Background(), assignment)
+	leased, _ := s.proxy.LeaseAssignmentFromQueue(context.Background(), workload.Id, 50*time.Millisecond)
+	if !s.Assert().NotNil(leased) {
+		return
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	extendErr := s.proxy.ExtendAssignmentLease(context.Background(), leased, time.Second)
+	time.Sleep(50 * time.Millisecond)
+	total, _ := s.proxy.RequeueExpiredLeases(context.Background(), workload.Id)
+
+	s.Assert().NoError(extendErr)
+	s.Assert().Zero(total)
+}
+
+func (s *WorkStorageProxyTestSuite) Test_RequeueLeasedAssignment_And_CommitLeasedAssignment() {
+	workload, _ := work_distributor.NewWorkload(uuid.NewString(), 10, 10)
+	s.proxy.SaveWorkload(context.Background(), workload)
+	defer s.proxy.DeleteWorkloadAndAssignments(context.Background(), workload.Id)
+
+	assignment, _ := work_distributor.NewAssignment("a1", workload.Id, 1, 10)
+	s.proxy.PushAssignmentToQueue(context.Background(), assignment)
+
+	// requeue releases the lease
+	leased, _ := s.proxy.LeaseAssignmentFromQueue(context.Background(), workload.Id, time.Second)
+	requeueErr := s.proxy.RequeueLeasedAssignment(context.Background(), leased)
+	s.Assert().NoError(requeueErr)
+	s.Assert().Equal(
+		work_distributor.ErrAssignmentLeaseLost,
+		s.proxy.RequeueLeasedAssignment(context.Background(), leased),
+	)
+
+	// commit releases the lease, and updates the workload
+	leased, _ = s.proxy.LeaseAssignmentFromQueue(context.Background(), workload.Id, time.Second)
+	if !s.Assert().NotNil(leased) {
+		return
+	}
+	commitErr := s.proxy.CommitLeasedAssignment(context.Background(), leased, func(w *work_distributor.Workload) error {
+		return w.IncreaseTotalCommittedAssignments()
+	})
+	getResult, _ := s.proxy.GetWorkload(context.Background(), workload.Id)
+	s.Assert().NoError(commitErr)
+	s.Assert().True(getResult.HasWorkloadFulfilled())
+}
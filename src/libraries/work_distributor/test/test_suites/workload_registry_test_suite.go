@@ -0,0 +1,62 @@
+package test_suites
+
+import (
+	distributor "duolingo/libraries/work_distributor"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type WorkloadRegistryTestSuite struct {
+	suite.Suite
+}
+
+func NewWorkloadRegistryTestSuite() *WorkloadRegistryTestSuite {
+	return &WorkloadRegistryTestSuite{}
+}
+
+func (s *WorkloadRegistryTestSuite) Test_Register_And_Unregister() {
+	registry := distributor.NewWorkloadRegistry()
+
+	s.Assert().True(registry.Register("W1", 1))
+	s.Assert().True(registry.Register("W2", 1))
+	s.Assert().False(registry.Register("W1", 5)) // already registered
+	s.Assert().Equal(2, registry.Len())
+
+	registry.Unregister("W1")
+	registry.Unregister("W3") // not registered, ignored
+
+	s.Assert().False(registry.IsRegistered("W1"))
+	s.Assert().True(registry.IsRegistered("W2"))
+	s.Assert().Equal([]string{"W2"}, registry.Turns())
+
+	registry.Unregister("W2")
+
+	s.Assert().Zero(registry.Len())
+	s.Assert().Empty(registry.Turns())
+}
+
+func (s *WorkloadRegistryTestSuite) Test_Turns_Weighted_Round_Robin() {
+	registry := distributor.NewWorkloadRegistry()
+	registry.Register("W1", 3)
+	registry.Register("W2", 1)
+	registry.Register("W3", 0) // counts as one
+
+	taken := []string{}
+	for range 10 {
+		taken = append(taken, registry.Turns()[0])
+	}
+
+	// the turns of the heavier workload are spread across the round
+	s.Assert().Equal([]string{"W1", "W2", "W1", "W3", "W1", "W1", "W2", "W1", "W3", "W1"}, taken)
+}
+
+func (s *WorkloadRegistryTestSuite) Test_Turns_Lists_Others_After_The_Taker() {
+	registry := distributor.NewWorkloadRegistry()
+	registry.Register("W1", 1)
+	registry.Register("W2", 1)
+	registry.Register("W3", 1)
+
+	s.Assert().Equal([]string{"W1", "W2", "W3"}, registry.Turns())
+	s.Assert().Equal([]string{"W2", "W3", "W1"}, registry.Turns())
+	s.Assert().Equal([]string{"W3", "W1", "W2"}, registry.Turns())
+}
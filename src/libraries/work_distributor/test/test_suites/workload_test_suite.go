@@ -42,6 +42,16 @@ func (s *WorkloadTestSuite) Test_GetExpectTotalAssignments() {
 	s.Assert().Equal(int64(34), w2.GetExpectTotalAssignments())
 }
 
+func (s *WorkloadTestSuite) Test_GetExpectTotalAssignments_KeyRanges() {
+	w1, _ := distributor.NewWorkload("W1", 100, 10)
+	w1.TotalAssignments = 7
+
+	s.Assert().Equal(int64(7), w1.GetExpectTotalAssignments())
+
+	w1.TotalCommittedAssignments = 7
+	s.Assert().True(w1.HasWorkloadFulfilled())
+}
+
 func (s *WorkloadTestSuite) Test_HasWorkloadFulfilled() {
 	w1, _ := distributor.NewWorkload("W1", 100, 10)
 
@@ -14,23 +14,70 @@ var (
 	ErrWorkloadHasAlreadyFulfilled = errors.New("workload has already fulfilled as all assignments commited")
 )
 
+// WorkloadError is returned by the operations working on several workloads,
+// telling the workload the error belongs to.
+type WorkloadError struct {
+	WorkloadId string
+	Err        error
+}
+
+func (e *WorkloadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *WorkloadError) Unwrap() error {
+	return e.Err
+}
+
+/*
+### Notions:
+ 1. Every assignment handed out by Assign() is leased for "leaseDuration".
+    The holder must keep the lease alive (HandleAssignment() heartbeats it
+    automatically) until it commits or rollbacks the assignment.
+ 2. A lease that is not renewed in time (e.g, the worker has crashed) is
+    requeued by any worker waiting for the same workload, so that the workload
+    can still be fulfilled.
+ 3. The workloads registered to the distributor are worked on together,
+    AssignAny() hands out their assignments in turns by the workload weights.
+*/
 type WorkDistributor struct {
-	proxy WorkStorageProxy
+	proxy    WorkStorageProxy
+	registry *WorkloadRegistry
 
 	unitsPerAssignment int64
+	leaseDuration      time.Duration
 }
 
 func NewWorkDistributor(proxy WorkStorageProxy, distributionSize int64) *WorkDistributor {
 	return &WorkDistributor{
 		proxy:              proxy,
+		registry:           NewWorkloadRegistry(),
 		unitsPerAssignment: distributionSize,
+		leaseDuration:      30 * time.Second,
 	}
 }
 
+// SetLeaseDuration ignores the non-positive durations (e.g. missing from the
+// config), the default lease duration is kept.
+func (dist *WorkDistributor) SetLeaseDuration(duration time.Duration) *WorkDistributor {
+	if duration > 0 {
+		dist.leaseDuration = duration
+	}
+	return dist
+}
+
 func (dist *WorkDistributor) GetDistributionSize() int64 {
 	return dist.unitsPerAssignment
 }
 
+func (dist *WorkDistributor) GetLeaseDuration() time.Duration {
+	return dist.leaseDuration
+}
+
+func (dist *WorkDistributor) GetRegistry() *WorkloadRegistry {
+	return dist.registry
+}
+
 func (dist *WorkDistributor) CreateWorkload(
 	ctx context.Context,
 	totalWorkUnits int64,
@@ -52,6 +99,7 @@ func (dist *WorkDistributor) CreateWorkload(
 
 	// Create assignments, and push assignments to the queue
 	var total = workload.GetExpectTotalAssignments()
+	var assignments = make([]*Assignment, total)
 	for i := range total {
 		start := i*dist.unitsPerAssignment + 1
 		end := start + dist.unitsPerAssignment - 1
@@ -65,17 +113,67 @@ func (dist *WorkDistributor) CreateWorkload(
 			events.Failed(evt, validationErr, nil)
 			return nil, validationErr
 		}
-		pushErr := dist.proxy.PushAssignmentToQueue(evt.Context(), assignment)
-		if pushErr != nil {
-			events.Failed(evt, pushErr, nil)
-			return nil, pushErr
+		assignments[i] = assignment
+	}
+	if queueErr := dist.queueWorkload(evt.Context(), workload, assignments); queueErr != nil {
+		events.Failed(evt, queueErr, nil)
+		return nil, queueErr
+	}
+
+	events.Succeeded(evt, nil)
+
+	return workload, nil
+}
+
+// CreateKeyedWorkload splits the workload by the sorted key boundaries rather
+// than by indexes, so that each assignment can be worked on by seeking its keys.
+// The boundaries close the ranges, the last range is unbounded.
+func (dist *WorkDistributor) CreateKeyedWorkload(
+	ctx context.Context,
+	totalWorkUnits int64,
+	boundaries []string,
+) (*Workload, error) {
+
+	evt := events.Start(ctx, "work_dist.create_keyed_workload", map[string]any{
+		"operation_name": "create_keyed_workload",
+	})
+
+	workload, validateErr := NewWorkload(
+		uuid.NewString(),
+		totalWorkUnits,
+		dist.unitsPerAssignment,
+	)
+	if validateErr != nil {
+		events.Failed(evt, validateErr, nil)
+		return nil, validateErr
+	}
+	workload.TotalAssignments = int64(len(boundaries) + 1)
+
+	var assignments = make([]*Assignment, workload.TotalAssignments)
+	for i := range assignments {
+		after, until := "", ""
+		if i > 0 {
+			after = boundaries[i-1]
+		}
+		if i < len(boundaries) {
+			until = boundaries[i]
 		}
+		assignment, validationErr := NewKeyRangeAssignment(
+			uuid.NewString(),
+			workload.Id,
+			int64(i+1),
+			after,
+			until,
+		)
+		if validationErr != nil {
+			events.Failed(evt, validationErr, nil)
+			return nil, validationErr
+		}
+		assignments[i] = assignment
 	}
-	// Save workload only after queuing all assignments
-	saveErr := dist.proxy.SaveWorkload(ctx, workload)
-	if saveErr != nil {
-		events.Failed(evt, saveErr, nil)
-		return nil, saveErr
+	if queueErr := dist.queueWorkload(evt.Context(), workload, assignments); queueErr != nil {
+		events.Failed(evt, queueErr, nil)
+		return nil, queueErr
 	}
 
 	events.Succeeded(evt, nil)
@@ -83,6 +181,21 @@ func (dist *WorkDistributor) CreateWorkload(
 	return workload, nil
 }
 
+// queueWorkload pushes the assignments to the queue, the workload is saved
+// only after queuing all assignments.
+func (dist *WorkDistributor) queueWorkload(
+	ctx context.Context,
+	workload *Workload,
+	assignments []*Assignment,
+) error {
+	for _, assignment := range assignments {
+		if pushErr := dist.proxy.PushAssignmentToQueue(ctx, assignment); pushErr != nil {
+			return pushErr
+		}
+	}
+	return dist.proxy.SaveWorkload(ctx, workload)
+}
+
 func (dist *WorkDistributor) GetWorkload(
 	ctx context.Context,
 	workloadId string,
@@ -127,7 +240,33 @@ func (dist *WorkDistributor) Assign(ctx context.Context, workloadId string) (*As
 	if isFullfilled {
 		return nil, ErrWorkloadHasAlreadyFulfilled
 	}
-	return dist.proxy.PopAssignmentFromQueue(ctx, workloadId)
+	return dist.proxy.LeaseAssignmentFromQueue(ctx, workloadId, dist.leaseDuration)
+}
+
+// AssignAny leases an assignment of the registered workloads, the workload
+// taking the turn is tried first, then the others. The expired leases of the
+// workloads with an empty queue are requeued for the next turns. It returns
+// nil if none of the workloads has an assignment to hand out.
+//
+// The workload found fulfilled is unregistered, the error is returned as
+// a WorkloadError telling the workload it belongs to.
+func (dist *WorkDistributor) AssignAny(ctx context.Context) (*Assignment, error) {
+	for _, workloadId := range dist.registry.Turns() {
+		assignment, err := dist.Assign(ctx, workloadId)
+		if assignment == nil && err == nil {
+			_, err = dist.ReclaimExpiredAssignments(ctx, workloadId)
+		}
+		if err == ErrWorkloadHasAlreadyFulfilled {
+			dist.registry.Unregister(workloadId)
+		}
+		if err != nil {
+			return nil, &WorkloadError{WorkloadId: workloadId, Err: err}
+		}
+		if assignment != nil {
+			return assignment, nil
+		}
+	}
+	return nil, nil
 }
 
 func (dist *WorkDistributor) WaitForAssignment(
@@ -159,8 +298,12 @@ func (dist *WorkDistributor) WaitForAssignment(
 			return nil, err
 		default:
 			assignment, err = dist.Assign(evt.Context(), workloadId)
-			// the queue is empty, but the workload not yet fulfilled
+			// the queue is empty, but the workload not yet fulfilled,
+			// requeue the leases abandoned by other workers (if any)
 			if assignment == nil && err == nil {
+				if _, err = dist.ReclaimExpiredAssignments(evt.Context(), workloadId); err != nil {
+					return nil, err
+				}
 				time.Sleep(retryWait)
 				continue
 			}
@@ -185,7 +328,16 @@ func (dist *WorkDistributor) HandleAssignment(
 	})
 	defer events.End(evt, true, err, nil)
 
-	if err = handler(evt.Context()); err != nil {
+	// keep the lease alive while handling, the handler context is canceled
+	// if the lease is lost, since the assignment might be handled by another worker
+	handleCtx, stopHandling := context.WithCancel(evt.Context())
+	if assignment.IsLeased() {
+		go dist.keepLeaseAlive(handleCtx, stopHandling, assignment)
+	}
+	err = handler(handleCtx)
+	stopHandling()
+
+	if err != nil {
 		dist.Rollback(evt.Context(), assignment)
 	} else {
 		err = dist.Commit(evt.Context(), assignment)
@@ -194,6 +346,34 @@ func (dist *WorkDistributor) HandleAssignment(
 	return err
 }
 
+func (dist *WorkDistributor) ExtendLease(ctx context.Context, assignment *Assignment) error {
+	var err error
+
+	evt := events.Start(ctx, "work_dist.extend_lease", map[string]any{
+		"operation_name": "extend_lease",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = dist.proxy.ExtendAssignmentLease(evt.Context(), assignment, dist.leaseDuration)
+
+	return err
+}
+
+func (dist *WorkDistributor) ReclaimExpiredAssignments(ctx context.Context, workloadId string) (int64, error) {
+	var total int64
+	var err error
+
+	evt := events.Start(ctx, "work_dist.reclaim_expired_assignments", map[string]any{
+		"operation_name": "reclaim_expired_assignments",
+	})
+	defer events.End(evt, true, err, nil)
+
+	total, err = dist.proxy.RequeueExpiredLeases(evt.Context(), workloadId)
+	evt.SetData("reclaimed_total", total)
+
+	return total, err
+}
+
 func (dist *WorkDistributor) Commit(ctx context.Context, assignment *Assignment) error {
 	var err error
 
@@ -202,11 +382,17 @@ func (dist *WorkDistributor) Commit(ctx context.Context, assignment *Assignment)
 	})
 	defer events.End(evt, true, err, nil)
 
-	err = dist.proxy.GetAndUpdateWorkload(evt.Context(), assignment.WorkloadId, func(
-		w *Workload,
-	) error {
+	increaseCommitted := func(w *Workload) error {
 		return w.IncreaseTotalCommittedAssignments()
-	})
+	}
+	if assignment.IsLeased() {
+		err = dist.proxy.CommitLeasedAssignment(evt.Context(), assignment, increaseCommitted)
+	} else {
+		err = dist.proxy.GetAndUpdateWorkload(evt.Context(), assignment.WorkloadId, increaseCommitted)
+	}
+	if err == nil {
+		assignment.LeaseToken = ""
+	}
 
 	return err
 }
@@ -219,7 +405,7 @@ func (dist *WorkDistributor) Rollback(ctx context.Context, assignment *Assignmen
 	})
 	defer events.End(evt, true, err, nil)
 
-	err = dist.proxy.PushAssignmentToQueue(ctx, assignment)
+	err = dist.requeue(evt.Context(), assignment)
 
 	return err
 }
@@ -233,7 +419,7 @@ func (dist *WorkDistributor) CommitProgress(
 	if assignment.IsCompleted() {
 		return dist.Commit(ctx, assignment)
 	}
-	return dist.proxy.PushAssignmentToQueue(ctx, assignment)
+	return dist.requeue(ctx, assignment)
 }
 
 func (dist *WorkDistributor) DeleteWorkloadAndAssignments(
@@ -242,3 +428,34 @@ func (dist *WorkDistributor) DeleteWorkloadAndAssignments(
 ) error {
 	return dist.proxy.DeleteWorkloadAndAssignments(ctx, workloadId)
 }
+
+func (dist *WorkDistributor) requeue(ctx context.Context, assignment *Assignment) error {
+	if !assignment.IsLeased() {
+		return dist.proxy.PushAssignmentToQueue(ctx, assignment)
+	}
+	err := dist.proxy.RequeueLeasedAssignment(ctx, assignment)
+	if err == nil {
+		assignment.LeaseToken = ""
+	}
+	return err
+}
+
+func (dist *WorkDistributor) keepLeaseAlive(
+	ctx context.Context,
+	stopHandling context.CancelFunc,
+	assignment *Assignment,
+) {
+	heartbeat := time.NewTicker(dist.leaseDuration / 3)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if err := dist.ExtendLease(ctx, assignment); err == ErrAssignmentLeaseLost {
+				stopHandling()
+				return
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package work_distributor
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 var (
@@ -16,4 +17,10 @@ type WorkStorageProxy interface {
 	DeleteWorkloadAndAssignments(ctx context.Context, workloadId string) error
 	PushAssignmentToQueue(ctx context.Context, assignment *Assignment) error
 	PopAssignmentFromQueue(ctx context.Context, workloadId string) (*Assignment, error)
+
+	LeaseAssignmentFromQueue(ctx context.Context, workloadId string, leaseDuration time.Duration) (*Assignment, error)
+	ExtendAssignmentLease(ctx context.Context, assignment *Assignment, leaseDuration time.Duration) error
+	RequeueLeasedAssignment(ctx context.Context, assignment *Assignment) error
+	CommitLeasedAssignment(ctx context.Context, assignment *Assignment, modifier func(*Workload) error) error
+	RequeueExpiredLeases(ctx context.Context, workloadId string) (int64, error)
 }
@@ -15,6 +15,10 @@ type Workload struct {
 	TotalUnitsPerAssignment   int64  `json:"dist_size"`
 	TotalCommittedAssignments int64  `json:"total_commited"`
 
+	// The number of the assignments when the workload is split by key
+	// boundaries, the ranges do not hold the same number of units.
+	TotalAssignments int64 `json:"total_assignments,omitempty"`
+
 	CreatedAt time.Time
 }
 
@@ -44,6 +48,9 @@ func (w *Workload) Validate() error {
 }
 
 func (w *Workload) GetExpectTotalAssignments() int64 {
+	if w.TotalAssignments > 0 {
+		return w.TotalAssignments
+	}
 	size := w.TotalUnitsPerAssignment
 	return (w.TotalWorkUnits + size - 1) / size // round up division
 }
@@ -0,0 +1,98 @@
+package work_distributor
+
+import (
+	"sync"
+)
+
+// WorkloadRegistry keeps the active workloads a worker takes the assignments
+// from. The workloads take turns by smooth weighted round robin, among the
+// turns of a round, a workload of weight "w" takes "w" turns spread across
+// the round, rather than in a row.
+type WorkloadRegistry struct {
+	mu        sync.Mutex
+	workloads []*registeredWorkload
+}
+
+type registeredWorkload struct {
+	workloadId string
+	weight     int
+	current    int
+}
+
+func NewWorkloadRegistry() *WorkloadRegistry {
+	return &WorkloadRegistry{
+		workloads: []*registeredWorkload{},
+	}
+}
+
+// Register adds the workload with the weight, a weight below one counts as one.
+// It returns false if the workload is already registered.
+func (r *WorkloadRegistry) Register(workloadId string, weight int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.workloads {
+		if r.workloads[i].workloadId == workloadId {
+			return false
+		}
+	}
+	r.workloads = append(r.workloads, &registeredWorkload{
+		workloadId: workloadId,
+		weight:     max(1, weight),
+	})
+	return true
+}
+
+func (r *WorkloadRegistry) Unregister(workloadId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.workloads {
+		if r.workloads[i].workloadId == workloadId {
+			r.workloads = append(r.workloads[:i], r.workloads[i+1:]...)
+			return
+		}
+	}
+}
+
+func (r *WorkloadRegistry) IsRegistered(workloadId string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.workloads {
+		if r.workloads[i].workloadId == workloadId {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *WorkloadRegistry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.workloads)
+}
+
+// Turns takes a turn and returns the workloads to try for it, the workload
+// taking the turn comes first, followed by the others rotating from it (the
+// ones registered after it, then the ones registered before it), so that the
+// turn is not wasted when the first one has nothing left to hand out.
+func (r *WorkloadRegistry) Turns() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.workloads) == 0 {
+		return []string{}
+	}
+	selected, total := 0, 0
+	for i, w := range r.workloads {
+		w.current += w.weight
+		total += w.weight
+		if w.current > r.workloads[selected].current {
+			selected = i
+		}
+	}
+	r.workloads[selected].current -= total
+
+	turns := make([]string, 0, len(r.workloads))
+	for i := range r.workloads {
+		turns = append(turns, r.workloads[(selected+i)%len(r.workloads)].workloadId)
+	}
+	return turns
+}
@@ -0,0 +1,15 @@
+package models
+
+// AudienceFilter narrows the campaign receivers, an empty list matches all
+type AudienceFilter struct {
+	Memberships     []Membership     `json:"memberships,omitempty"`
+	NativeLanguages []NativeLanguage `json:"native_languages,omitempty"`
+	Platforms       []string         `json:"platforms,omitempty"`
+}
+
+func (f *AudienceFilter) IsEmpty() bool {
+	return f == nil ||
+		(len(f.Memberships) == 0 &&
+			len(f.NativeLanguages) == 0 &&
+			len(f.Platforms) == 0)
+}
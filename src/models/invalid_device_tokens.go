@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+)
+
+// InvalidDeviceTokens are the tokens rejected permanently by the push
+// service, the devices of these tokens should be removed.
+type InvalidDeviceTokens struct {
+	MessageId string   `json:"message_id"`
+	Tokens    []string `json:"tokens"`
+}
+
+func NewInvalidDeviceTokens(messageId string, tokens []string) *InvalidDeviceTokens {
+	return &InvalidDeviceTokens{
+		MessageId: messageId,
+		Tokens:    tokens,
+	}
+}
+
+func (m *InvalidDeviceTokens) Encode() []byte {
+	marshalled, err := json.Marshal(m)
+	if err != nil {
+		panic(err)
+	}
+	return marshalled
+}
+
+func InvalidDeviceTokensDecode(data []byte) *InvalidDeviceTokens {
+	tokens := new(InvalidDeviceTokens)
+	err := json.Unmarshal(data, tokens)
+	if err != nil {
+		panic(err)
+	}
+	return tokens
+}
@@ -11,6 +11,29 @@ type MessageInput struct {
 	Campaign string `json:"campaign"`
 	Title    string `json:"title"`
 	Body     string `json:"body"`
+
+	// Title and body variants by the recipient native language, the
+	// default title and body are used for the languages not listed.
+	Localizations map[NativeLanguage]*MessageLocalization `json:"localizations,omitempty"`
+
+	// The campaign receivers are narrowed down by the filter if specified
+	Audience *AudienceFilter `json:"audience,omitempty"`
+
+	// The message is delivered when the clock of each recipient reads the local
+	// send time (e.g. "09:00"), rolling out across the timezones.
+	LocalSendAt string `json:"local_send_at,omitempty"`
+
+	// The authenticated client who submitted the message, for auditing
+	SubmittedBy string `json:"submitted_by,omitempty"`
+}
+
+// MessageContent is the comparable part of a message input, which is
+// delivered as-is to the devices once the message has been rendered.
+type MessageContent struct {
+	Id       string
+	Campaign string
+	Title    string
+	Body     string
 }
 
 func NewMessageInput(campaign string, title string, body string) *MessageInput {
@@ -22,6 +45,25 @@ func NewMessageInput(campaign string, title string, body string) *MessageInput {
 	}
 }
 
+func (m *MessageInput) SetLocalization(lang NativeLanguage, title string, body string) {
+	if m.Localizations == nil {
+		m.Localizations = make(map[NativeLanguage]*MessageLocalization)
+	}
+	m.Localizations[lang] = &MessageLocalization{
+		Title: title,
+		Body:  body,
+	}
+}
+
+func (m *MessageInput) Content() MessageContent {
+	return MessageContent{
+		Id:       m.Id,
+		Campaign: m.Campaign,
+		Title:    m.Title,
+		Body:     m.Body,
+	}
+}
+
 func (m *MessageInput) Encode() []byte {
 	marshalled, err := json.Marshal(m)
 	if err != nil {
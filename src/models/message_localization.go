@@ -0,0 +1,58 @@
+package models
+
+import (
+	"regexp"
+)
+
+// Matches the placeholders such as "{{firstname}}", or "{{firstname|there}}"
+// where "there" is the default value when the recipient firstname is empty.
+var placeholderRegex = regexp.MustCompile(`\{\{\s*(\w+)\s*(?:\|([^}]*))?\}\}`)
+
+type MessageLocalization struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Render localizes the message for the recipient native language, and fills the
+// recipient placeholders. The rendered message has no localizations left.
+func (m *MessageInput) Render(recipient *Recipient) *MessageInput {
+	title, body := m.Title, m.Body
+	if recipient == nil {
+		recipient = &Recipient{}
+	}
+	if variant, ok := m.Localizations[recipient.NativeLanguage]; ok && variant != nil {
+		title, body = variant.Title, variant.Body
+	}
+	return &MessageInput{
+		Id:       m.Id,
+		Campaign: m.Campaign,
+		Title:    recipient.fill(title),
+		Body:     recipient.fill(body),
+	}
+}
+
+func (recipient *Recipient) fill(template string) string {
+	return placeholderRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		parts := placeholderRegex.FindStringSubmatch(placeholder)
+		value, known := recipient.placeholderValue(parts[1])
+		if !known {
+			return placeholder
+		}
+		if value == "" {
+			return parts[2]
+		}
+		return value
+	})
+}
+
+func (recipient *Recipient) placeholderValue(name string) (string, bool) {
+	switch name {
+	case "firstname":
+		return recipient.Firstname, true
+	case "lastname":
+		return recipient.Lastname, true
+	case "username":
+		return recipient.Username, true
+	}
+	return "", false
+}
@@ -0,0 +1,46 @@
+package models
+
+// MessagePreview describes how a message would be delivered, without sending it.
+// The token owned by several users is counted once, as the builders deliver it
+// once, and its other owners are reported as the duplicate tokens.
+type MessagePreview struct {
+	Campaign            string           `json:"campaign"`
+	TotalDevices        int64            `json:"total_devices"`
+	DuplicateTokens     int64            `json:"duplicate_tokens"`
+	DevicesByPlatform   map[string]int64 `json:"devices_by_platform"`
+	DistributionSize    int64            `json:"distribution_size"`
+	ExpectedAssignments int64            `json:"expected_assignments"`
+	Samples             []*MessageSample `json:"samples"`
+}
+
+// MessageSample is the message rendered for one of the campaign devices
+type MessageSample struct {
+	Platform string `json:"platform"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+}
+
+func NewMessagePreview(campaign string) *MessagePreview {
+	return &MessagePreview{
+		Campaign:          campaign,
+		DevicesByPlatform: make(map[string]int64),
+		Samples:           []*MessageSample{},
+	}
+}
+
+// AddSample keeps distinct samples only, as most of the devices share
+// the same rendered content.
+func (p *MessagePreview) AddSample(platform string, rendered *MessageInput) {
+	for _, sample := range p.Samples {
+		if sample.Platform == platform &&
+			sample.Title == rendered.Title &&
+			sample.Body == rendered.Body {
+			return
+		}
+	}
+	p.Samples = append(p.Samples, &MessageSample{
+		Platform: platform,
+		Title:    rendered.Title,
+		Body:     rendered.Body,
+	})
+}
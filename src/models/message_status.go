@@ -0,0 +1,65 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+type MessageState string
+
+const (
+	MessageScheduled MessageState = "scheduled"
+	MessageQueued    MessageState = "queued"
+	MessageBuilding  MessageState = "building"
+	MessageSending   MessageState = "sending"
+	MessageCompleted MessageState = "completed"
+	MessageFailed    MessageState = "failed"
+	MessageCanceled  MessageState = "canceled"
+)
+
+// The states the message never leaves once reached
+var FinishedMessageStates = []MessageState{
+	MessageCompleted,
+	MessageFailed,
+	MessageCanceled,
+}
+
+type MessageStatus struct {
+	MessageId string       `json:"message_id"`
+	Campaign  string       `json:"campaign"`
+	State     MessageState `json:"state"`
+	Error     string       `json:"error,omitempty"`
+
+	WorkloadId           string `json:"workload_id,omitempty"`
+	TotalDevices         int64  `json:"total_devices"`
+	TotalAssignments     int64  `json:"total_assignments"`
+	CommittedAssignments int64  `json:"committed_assignments"`
+
+	SuccessCount int64 `json:"success_count"`
+	FailureCount int64 `json:"failure_count"`
+
+	// The devices not sent to, as their users have reached the frequency cap
+	SuppressedCount int64 `json:"suppressed_count"`
+	// The devices not sent to, as another device of the message has the same token
+	DuplicateCount int64 `json:"duplicate_count"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewMessageStatus(input *MessageInput, state MessageState) *MessageStatus {
+	return &MessageStatus{
+		MessageId: input.Id,
+		Campaign:  input.Campaign,
+		State:     state,
+		UpdatedAt: time.Now(),
+	}
+}
+
+func (s *MessageStatus) IsFinished() bool {
+	return slices.Contains(FinishedMessageStates, s.State)
+}
+
+// Total of the devices the delivery result has been reported for
+func (s *MessageStatus) TotalReported() int64 {
+	return s.SuccessCount + s.FailureCount + s.SuppressedCount + s.DuplicateCount
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OutboxState string
+
+const (
+	OutboxPending OutboxState = "pending"
+	OutboxSent    OutboxState = "sent"
+	// The message can never be relayed (e.g. its topic is unknown), it is
+	// kept for the inspection rather than retried.
+	OutboxFailed OutboxState = "failed"
+)
+
+// OutboxMessage is a message accepted durably, which is waiting to be relayed
+// to its message queue topic.
+type OutboxMessage struct {
+	Id       string      `json:"id" bson:"_id"`
+	Topic    string      `json:"topic" bson:"topic"`
+	Payload  string      `json:"payload" bson:"payload"`
+	State    OutboxState `json:"state" bson:"state"`
+	Attempts int         `json:"attempts" bson:"attempts"`
+	Error    string      `json:"error,omitempty" bson:"error,omitempty"`
+
+	// The message is not claimed by the relays before this time, it is
+	// pushed back on each claim and on each failed attempt.
+	NextAttemptAt time.Time `json:"next_attempt_at" bson:"next_attempt_at"`
+
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	SentAt    *time.Time `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+}
+
+func NewOutboxMessage(topic string, payload string) *OutboxMessage {
+	now := time.Now()
+	return &OutboxMessage{
+		Id:            uuid.NewString(),
+		Topic:         topic,
+		Payload:       payload,
+		State:         OutboxPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PushNotiDeadLetter records the tokens which have not been delivered
+// after the last retry attempt.
+type PushNotiDeadLetter struct {
+	MessageId string    `json:"message_id"`
+	Campaign  string    `json:"campaign"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Tokens    []string  `json:"tokens"`
+	Attempts  int       `json:"attempts"`
+	Reason    string    `json:"reason"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+func NewPushNotiDeadLetter(
+	content MessageContent,
+	tokens []string,
+	attempts int,
+	reason string,
+) *PushNotiDeadLetter {
+	return &PushNotiDeadLetter{
+		MessageId: content.Id,
+		Campaign:  content.Campaign,
+		Title:     content.Title,
+		Body:      content.Body,
+		Tokens:    tokens,
+		Attempts:  attempts,
+		Reason:    reason,
+		FailedAt:  time.Now(),
+	}
+}
+
+func (m *PushNotiDeadLetter) Encode() []byte {
+	marshalled, err := json.Marshal(m)
+	if err != nil {
+		panic(err)
+	}
+	return marshalled
+}
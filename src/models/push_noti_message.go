@@ -30,14 +30,67 @@ func (m *PushNotiMessage) Validate() error {
 
 func (m *PushNotiMessage) GetTargetTokens(platforms []string) []string {
 	tokens := []string{}
+	for _, device := range m.GetTargetDevices(platforms) {
+		tokens = append(tokens, device.Token)
+	}
+	return tokens
+}
+
+// GetTargetDevices returns the deliverable devices of the platforms
+func (m *PushNotiMessage) GetTargetDevices(platforms []string) []*UserDevice {
+	devices := []*UserDevice{}
 	for i := range m.TargetDevices {
-		if slices.Contains(platforms, m.TargetDevices[i].Platform) {
-			if m.TargetDevices[i].Token != "" {
-				tokens = append(tokens, m.TargetDevices[i].Token)
-			}
+		if slices.Contains(platforms, m.TargetDevices[i].Platform) && m.TargetDevices[i].IsDeliverable() {
+			devices = append(devices, m.TargetDevices[i])
 		}
 	}
-	return tokens
+	return devices
+}
+
+// GetTargetUserIds returns the distinct owners of the target devices
+func (m *PushNotiMessage) GetTargetUserIds() []string {
+	userIds := []string{}
+	for i := range m.TargetDevices {
+		userId := m.TargetDevices[i].UserId
+		if userId != "" && !slices.Contains(userIds, userId) {
+			userIds = append(userIds, userId)
+		}
+	}
+	return userIds
+}
+
+// FilterUsers removes the target devices of the users not listed, the devices
+// without owner are kept. It returns the number of the removed devices.
+func (m *PushNotiMessage) FilterUsers(userIds []string) int {
+	kept := []*UserDevice{}
+	for i := range m.TargetDevices {
+		userId := m.TargetDevices[i].UserId
+		if userId == "" || slices.Contains(userIds, userId) {
+			kept = append(kept, m.TargetDevices[i])
+		}
+	}
+	removed := len(m.TargetDevices) - len(kept)
+	m.TargetDevices = kept
+	return removed
+}
+
+// DedupeTokens removes the target devices whose token is already targeted,
+// e.g. the device shared by several accounts. It returns the number of the
+// removed devices.
+func (m *PushNotiMessage) DedupeTokens() int {
+	seen := make(map[string]bool, len(m.TargetDevices))
+	kept := []*UserDevice{}
+	for i := range m.TargetDevices {
+		token := m.TargetDevices[i].Token
+		if token != "" && seen[token] {
+			continue
+		}
+		seen[token] = true
+		kept = append(kept, m.TargetDevices[i])
+	}
+	removed := len(m.TargetDevices) - len(kept)
+	m.TargetDevices = kept
+	return removed
 }
 
 func (m *PushNotiMessage) Encode() []byte {
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const LocalClockLayout = "15:04"
+
+var (
+	ErrInvalidLocalClock = errors.New("local clock must be formatted as HH:MM")
+)
+
+// QuietHours is the local time window in which the recipients are not disturbed,
+// the window may span midnight (e.g. from "22:00" to "08:00"). The window is
+// empty if it starts and ends at the same time.
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+func NewQuietHours(start string, end string) *QuietHours {
+	return &QuietHours{
+		Start: start,
+		End:   end,
+	}
+}
+
+func (q *QuietHours) Validate() error {
+	if _, err := time.Parse(LocalClockLayout, q.Start); err != nil {
+		return ErrInvalidLocalClock
+	}
+	if _, err := time.Parse(LocalClockLayout, q.End); err != nil {
+		return ErrInvalidLocalClock
+	}
+	return nil
+}
+
+// DeferUntil returns the end of the window if "local" is within the window,
+// "local" is the time in the recipient location.
+func (q *QuietHours) DeferUntil(local time.Time) (time.Time, bool) {
+	if q == nil || q.Start == q.End {
+		return local, false
+	}
+	end := NextLocalClock(local, q.End)
+	start := NextLocalClock(local, q.Start)
+	if end.Equal(local) {
+		return local, false
+	}
+	// within the window, the window end comes before the next window start
+	if end.Before(start) || start.Equal(local) {
+		return end, true
+	}
+	return local, false
+}
+
+// NextLocalClock returns the next time, at or after "local", the clock reads
+// "clock" in the location of "local". The invalid clock is read as midnight.
+func NextLocalClock(local time.Time, clock string) time.Time {
+	parsed, _ := time.Parse(LocalClockLayout, clock)
+	next := time.Date(
+		local.Year(), local.Month(), local.Day(),
+		parsed.Hour(), parsed.Minute(), 0, 0,
+		local.Location(),
+	)
+	if next.Before(local) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
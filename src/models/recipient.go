@@ -0,0 +1,38 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// Recipient holds the user fields needed to personalize and time a message
+type Recipient struct {
+	UserId         string         `json:"user_id" bson:"user_id"`
+	Firstname      string         `json:"firstname" bson:"firstname"`
+	Lastname       string         `json:"lastname" bson:"lastname"`
+	Username       string         `json:"username" bson:"username"`
+	NativeLanguage NativeLanguage `json:"native_lan_enum" bson:"native_lan_enum"`
+
+	// The IANA timezone name (e.g. "Asia/Ho_Chi_Minh"), UTC is assumed if empty
+	Timezone string `json:"timezone,omitempty" bson:"timezone,omitempty"`
+}
+
+// locations caches the loaded locations by the timezone name, as loading one
+// reads the timezone database for every recipient of the batches otherwise.
+var locations sync.Map
+
+// Location returns the recipient timezone, or UTC if it is unknown
+func (recipient *Recipient) Location() *time.Location {
+	if recipient == nil || recipient.Timezone == "" {
+		return time.UTC
+	}
+	if loc, ok := locations.Load(recipient.Timezone); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(recipient.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	locations.Store(recipient.Timezone, loc)
+	return loc
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+type ScheduledMessage struct {
+	*MessageInput
+	SendAt time.Time `json:"send_at"`
+}
+
+func NewScheduledMessage(input *MessageInput, sendAt time.Time) *ScheduledMessage {
+	return &ScheduledMessage{
+		MessageInput: input,
+		SendAt:       sendAt,
+	}
+}
@@ -0,0 +1,45 @@
+package test_suites
+
+import (
+	"duolingo/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MessageLocalizationTestSuite struct {
+	suite.Suite
+}
+
+func NewMessageLocalizationTestSuite() *MessageLocalizationTestSuite {
+	return &MessageLocalizationTestSuite{}
+}
+
+func (s *MessageLocalizationTestSuite) Test_Render_Placeholders() {
+	input := models.NewMessageInput("C1", "Hi {{firstname|there}}", "{{ username }} {{unknown}}")
+	recipient := &models.Recipient{Firstname: "Huu", Username: "nvhuu"}
+
+	rendered := input.Render(recipient)
+	renderedDefault := input.Render(&models.Recipient{})
+
+	s.Assert().Equal(input.Id, rendered.Id)
+	s.Assert().Equal("Hi Huu", rendered.Title)
+	s.Assert().Equal("nvhuu {{unknown}}", rendered.Body)
+	s.Assert().Equal("Hi there", renderedDefault.Title)
+	s.Assert().Equal(" {{unknown}}", renderedDefault.Body)
+}
+
+func (s *MessageLocalizationTestSuite) Test_Render_Localizations_Fallback() {
+	input := models.NewMessageInput("C1", "Hello {{firstname}}", "default body")
+	input.SetLocalization(models.LanguageVN, "Xin chào {{firstname}}", "vn body")
+
+	vn := input.Render(&models.Recipient{Firstname: "Huu", NativeLanguage: models.LanguageVN})
+	jp := input.Render(&models.Recipient{Firstname: "Huu", NativeLanguage: models.LanguageJP})
+	unknown := input.Render(nil)
+
+	s.Assert().Equal("Xin chào Huu", vn.Title)
+	s.Assert().Equal("vn body", vn.Body)
+	s.Assert().Equal("Hello Huu", jp.Title)
+	s.Assert().Equal("default body", jp.Body)
+	s.Assert().Equal("Hello ", unknown.Title)
+	s.Assert().Empty(vn.Localizations)
+}
@@ -0,0 +1,30 @@
+package test_suites
+
+import (
+	"duolingo/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MessagePreviewTestSuite struct {
+	suite.Suite
+}
+
+func NewMessagePreviewTestSuite() *MessagePreviewTestSuite {
+	return &MessagePreviewTestSuite{}
+}
+
+func (s *MessagePreviewTestSuite) Test_AddSample_Distinct() {
+	input := models.NewMessageInput("C1", "Hi {{firstname|there}}", "B1")
+	preview := models.NewMessagePreview("C1")
+
+	preview.AddSample("ios", input.Render(&models.Recipient{Firstname: "Huu"}))
+	preview.AddSample("ios", input.Render(&models.Recipient{Firstname: "Huu"}))
+	preview.AddSample("android", input.Render(&models.Recipient{Firstname: "Huu"}))
+	preview.AddSample("ios", input.Render(&models.Recipient{}))
+
+	s.Assert().Len(preview.Samples, 3)
+	s.Assert().Equal("Hi Huu", preview.Samples[0].Title)
+	s.Assert().Equal("android", preview.Samples[1].Platform)
+	s.Assert().Equal("Hi there", preview.Samples[2].Title)
+}
@@ -0,0 +1,71 @@
+package test_suites
+
+import (
+	"duolingo/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PushNotiMessageTestSuite struct {
+	suite.Suite
+}
+
+func NewPushNotiMessageTestSuite() *PushNotiMessageTestSuite {
+	return &PushNotiMessageTestSuite{}
+}
+
+func (s *PushNotiMessageTestSuite) Test_GetTargetUserIds_Distinct() {
+	msg := models.NewPushNotiMessage(models.NewMessageInput("C1", "T1", "B1"), []*models.UserDevice{
+		{Platform: "ios", Token: "T1", UserId: "U1"},
+		{Platform: "android", Token: "T2", UserId: "U1"},
+		{Platform: "ios", Token: "T3", UserId: "U2"},
+		{Platform: "ios", Token: "T4"},
+	})
+
+	s.Assert().Equal([]string{"U1", "U2"}, msg.GetTargetUserIds())
+}
+
+func (s *PushNotiMessageTestSuite) Test_FilterUsers() {
+	msg := models.NewPushNotiMessage(models.NewMessageInput("C1", "T1", "B1"), []*models.UserDevice{
+		{Platform: "ios", Token: "T1", UserId: "U1"},
+		{Platform: "android", Token: "T2", UserId: "U1"},
+		{Platform: "ios", Token: "T3", UserId: "U2"},
+		{Platform: "ios", Token: "T4"},
+	})
+
+	removed := msg.FilterUsers([]string{"U2"})
+
+	s.Assert().Equal(2, removed)
+	s.Assert().Equal([]string{"T3", "T4"}, msg.GetTargetTokens([]string{"ios", "android"}))
+}
+
+func (s *PushNotiMessageTestSuite) Test_GetTargetDevices_Web_Requires_Keys() {
+	msg := models.NewPushNotiMessage(models.NewMessageInput("C1", "T1", "B1"), []*models.UserDevice{
+		{Platform: "ios", Token: "T1"},
+		{Platform: "web", Token: "https://push.example.com/T2", WebPush: &models.WebPushSubscription{P256dh: "P", Auth: "A"}},
+		{Platform: "web", Token: "https://push.example.com/T3"},
+		{Platform: "web", Token: "https://push.example.com/T4", WebPush: &models.WebPushSubscription{P256dh: "P"}},
+		{Platform: "android", Token: ""},
+	})
+
+	s.Assert().Equal(
+		[]string{"T1", "https://push.example.com/T2"},
+		msg.GetTargetTokens([]string{"ios", "android", "web"}),
+	)
+	s.Assert().Equal([]string{"T1"}, msg.GetTargetTokens([]string{"ios", "android"}))
+}
+
+func (s *PushNotiMessageTestSuite) Test_DedupeTokens() {
+	msg := models.NewPushNotiMessage(models.NewMessageInput("C1", "T1", "B1"), []*models.UserDevice{
+		{Platform: "ios", Token: "T1", UserId: "U1"},
+		{Platform: "ios", Token: "T1", UserId: "U2"},
+		{Platform: "android", Token: "T2", UserId: "U2"},
+		{Platform: "ios", Token: "T1", UserId: "U3"},
+	})
+
+	removed := msg.DedupeTokens()
+
+	s.Assert().Equal(2, removed)
+	s.Assert().Equal([]string{"T1", "T2"}, msg.GetTargetTokens([]string{"ios", "android"}))
+	s.Assert().Equal("U1", msg.TargetDevices[0].UserId)
+}
@@ -0,0 +1,78 @@
+package test_suites
+
+import (
+	"time"
+
+	"duolingo/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type QuietHoursTestSuite struct {
+	suite.Suite
+}
+
+func NewQuietHoursTestSuite() *QuietHoursTestSuite {
+	return &QuietHoursTestSuite{}
+}
+
+func (s *QuietHoursTestSuite) Test_Validate() {
+	s.Assert().NoError(models.NewQuietHours("22:00", "08:00").Validate())
+	s.Assert().Equal(models.ErrInvalidLocalClock, models.NewQuietHours("22h", "08:00").Validate())
+	s.Assert().Equal(models.ErrInvalidLocalClock, models.NewQuietHours("22:00", "").Validate())
+}
+
+func (s *QuietHoursTestSuite) Test_DeferUntil_Overnight() {
+	quietHours := models.NewQuietHours("22:00", "08:00")
+	at := func(day int, hour int, min int) time.Time {
+		return time.Date(2024, 5, day, hour, min, 0, 0, time.UTC)
+	}
+
+	until, deferred := quietHours.DeferUntil(at(1, 23, 30))
+	s.Assert().True(deferred)
+	s.Assert().Equal(at(2, 8, 0), until)
+
+	until, deferred = quietHours.DeferUntil(at(2, 3, 0))
+	s.Assert().True(deferred)
+	s.Assert().Equal(at(2, 8, 0), until)
+
+	until, deferred = quietHours.DeferUntil(at(1, 22, 0))
+	s.Assert().True(deferred)
+	s.Assert().Equal(at(2, 8, 0), until)
+
+	_, deferred = quietHours.DeferUntil(at(1, 8, 0))
+	s.Assert().False(deferred)
+	_, deferred = quietHours.DeferUntil(at(1, 12, 0))
+	s.Assert().False(deferred)
+}
+
+func (s *QuietHoursTestSuite) Test_DeferUntil_Daytime() {
+	quietHours := models.NewQuietHours("12:00", "14:00")
+	at := func(hour int) time.Time {
+		return time.Date(2024, 5, 1, hour, 0, 0, 0, time.UTC)
+	}
+
+	until, deferred := quietHours.DeferUntil(at(13))
+	s.Assert().True(deferred)
+	s.Assert().Equal(at(14), until)
+
+	_, deferred = quietHours.DeferUntil(at(15))
+	s.Assert().False(deferred)
+	_, deferred = models.NewQuietHours("12:00", "12:00").DeferUntil(at(12))
+	s.Assert().False(deferred)
+}
+
+func (s *QuietHoursTestSuite) Test_NextLocalClock() {
+	loc, _ := time.LoadLocation("Asia/Ho_Chi_Minh")
+	local := time.Date(2024, 5, 1, 10, 0, 0, 0, loc)
+
+	s.Assert().Equal(time.Date(2024, 5, 2, 9, 0, 0, 0, loc), models.NextLocalClock(local, "09:00"))
+	s.Assert().Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, loc), models.NextLocalClock(local, "10:00"))
+	s.Assert().Equal(time.Date(2024, 5, 1, 21, 30, 0, 0, loc), models.NextLocalClock(local, "21:30"))
+}
+
+func (s *QuietHoursTestSuite) Test_Recipient_Location() {
+	s.Assert().Equal("Asia/Ho_Chi_Minh", (&models.Recipient{Timezone: "Asia/Ho_Chi_Minh"}).Location().String())
+	s.Assert().Equal(time.UTC, (&models.Recipient{Timezone: "Mars/Olympus"}).Location())
+	s.Assert().Equal(time.UTC, (&models.Recipient{}).Location())
+}
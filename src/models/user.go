@@ -16,6 +16,7 @@ type User struct {
 	Devices         []*UserDevice  `json:"user_devices" bson:"user_devices"`
 	NativeLanguage  NativeLanguage `json:"native_lan_enum" bson:"native_lan_enum"`
 	Membership      Membership     `json:"membership_enum" bson:"membership_enum"`
+	Timezone        string         `json:"timezone" bson:"timezone,omitempty"`
 	EmailVerifiedAt time.Time      `json:"email_verified_at" bson:"email_verified_at,omitempty"`
 }
 
@@ -33,6 +34,7 @@ func (u *User) Equal(target *User) bool {
 		u.Email != target.Email ||
 		u.NativeLanguage != target.NativeLanguage ||
 		u.Membership != target.Membership ||
+		u.Timezone != target.Timezone ||
 		t1 != t2 {
 		return false
 	}
@@ -50,7 +52,7 @@ func (u *User) Equal(target *User) bool {
 		return false
 	}
 	for i := range u.Devices {
-		if *u.Devices[i] != *target.Devices[i] {
+		if !u.Devices[i].Equal(target.Devices[i]) {
 			return false
 		}
 	}
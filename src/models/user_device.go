@@ -1,6 +1,46 @@
 package models
 
+const WebPlatform = "web"
+
 type UserDevice struct {
 	Platform string `json:"platform" bson:"platform"`
 	Token    string `json:"token" bson:"token"`
+
+	// The Web Push subscription of the web platform devices, whose token is the
+	// subscription endpoint.
+	WebPush *WebPushSubscription `json:"web_push,omitempty" bson:"web_push,omitempty"`
+
+	// The device owner id, carried along the delivery for the frequency capping
+	UserId string `json:"user_id,omitempty" bson:"-"`
+
+	// The device owner, only set when listing devices for delivery
+	Recipient *Recipient `json:"recipient,omitempty" bson:"recipient,omitempty"`
+}
+
+// WebPushSubscription holds the keys of the PushSubscription created by the browser
+type WebPushSubscription struct {
+	P256dh string `json:"p256dh" bson:"p256dh"`
+	Auth   string `json:"auth" bson:"auth"`
+}
+
+// IsDeliverable tells whether the device has what its platform needs to be
+// delivered, the web devices need their subscription keys.
+func (device *UserDevice) IsDeliverable() bool {
+	if device.Token == "" {
+		return false
+	}
+	if device.Platform == WebPlatform {
+		return device.WebPush != nil && device.WebPush.P256dh != "" && device.WebPush.Auth != ""
+	}
+	return true
+}
+
+func (device *UserDevice) Equal(target *UserDevice) bool {
+	if target == nil ||
+		device.Platform != target.Platform ||
+		device.Token != target.Token ||
+		(device.WebPush == nil) != (target.WebPush == nil) {
+		return false
+	}
+	return device.WebPush == nil || *device.WebPush == *target.WebPush
 }
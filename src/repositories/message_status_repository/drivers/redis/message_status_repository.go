@@ -0,0 +1,407 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"duolingo/models"
+	status_repo "duolingo/repositories/message_status_repository/external"
+
+	connection "duolingo/libraries/connection_manager/drivers/redis"
+	events "duolingo/libraries/events/facade"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+### Notions:
+ 1. Each message status is stored as a hash, so that the delivery results
+    reported concurrently by the senders can be increased atomically.
+ 2. The statuses expire after the retention duration since the last update.
+ 3. The updates are ignored if the message status does not exist (e.g. it has
+    expired, or has been deleted), to not leave partial statuses behind. The
+    existence check and the update run as a Lua script to be atomic.
+ 4. The state changes are refused once the message has finished, so that
+    a message canceled meanwhile is never moved back to building. The script
+    returns the fields before the change, 0 if the status does not exist, and
+    -1 if it has finished.
+*/
+var updateIfExistsScript = redis.NewScript(`
+	if redis.call("EXISTS", KEYS[1]) == 0 then
+		return 0
+	end
+	for i = 4, #ARGV, 2 do
+		redis.call(ARGV[1], KEYS[1], ARGV[i], ARGV[i + 1])
+	end
+	redis.call("HSET", KEYS[1], "updated_at", ARGV[2])
+	redis.call("PEXPIRE", KEYS[1], ARGV[3])
+	return 1
+`)
+
+var changeStateUnlessFinishedScript = redis.NewScript(`
+	local fields = redis.call("HGETALL", KEYS[1])
+	if #fields == 0 then
+		return 0
+	end
+	local state = redis.call("HGET", KEYS[1], "state")
+	local finished = tonumber(ARGV[3])
+	for i = 4, 3 + finished do
+		if state == ARGV[i] then
+			return -1
+		end
+	end
+	for i = 4 + finished, #ARGV, 2 do
+		redis.call("HSET", KEYS[1], ARGV[i], ARGV[i + 1])
+	end
+	redis.call("HSET", KEYS[1], "updated_at", ARGV[1])
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return fields
+`)
+
+type MessageStatusRepo struct {
+	connection.RedisClient
+
+	retention time.Duration
+}
+
+func NewMessageStatusRepo(client *connection.RedisClient, retention time.Duration) *MessageStatusRepo {
+	return &MessageStatusRepo{
+		RedisClient: *client,
+		retention:   retention,
+	}
+}
+
+func (repo *MessageStatusRepo) SaveMessageStatus(ctx context.Context, status *models.MessageStatus) error {
+	var err error
+
+	evt := events.Start(ctx, "message_status_repo.save_message_status", map[string]any{
+		"db_operation":   "hset",
+		"operation_name": "save_message_status",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.ExecuteClosure(evt.Context(), repo.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		_, txErr := rdb.TxPipelined(timeoutCtx, func(pipe redis.Pipeliner) error {
+			key := messageStatusKey(status.MessageId)
+			pipe.Del(timeoutCtx, key)
+			pipe.HSet(timeoutCtx, key, map[string]any{
+				"message_id":            status.MessageId,
+				"campaign":              status.Campaign,
+				"state":                 string(status.State),
+				"error":                 status.Error,
+				"workload_id":           status.WorkloadId,
+				"total_devices":         status.TotalDevices,
+				"total_assignments":     status.TotalAssignments,
+				"committed_assignments": status.CommittedAssignments,
+				"success_count":         status.SuccessCount,
+				"failure_count":         status.FailureCount,
+				"suppressed_count":      status.SuppressedCount,
+				"duplicate_count":       status.DuplicateCount,
+				"updated_at":            time.Now().UnixMilli(),
+			})
+			pipe.Expire(timeoutCtx, key, repo.retention)
+			return nil
+		})
+		return txErr
+	})
+
+	return err
+}
+
+func (repo *MessageStatusRepo) GetMessageStatus(ctx context.Context, messageId string) (
+	*models.MessageStatus,
+	error,
+) {
+	var err error
+	var fields map[string]string
+
+	evt := events.Start(ctx, "message_status_repo.get_message_status", map[string]any{
+		"db_operation":   "hgetall",
+		"operation_name": "get_message_status",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.ExecuteClosure(evt.Context(), repo.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		result, getErr := rdb.HGetAll(timeoutCtx, messageStatusKey(messageId)).Result()
+		fields = result
+		return getErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		err = status_repo.ErrMessageStatusNotExists
+		return nil, err
+	}
+
+	return decodeMessageStatus(fields), nil
+}
+
+func (repo *MessageStatusRepo) DeleteMessageStatus(ctx context.Context, messageId string) error {
+	var err error
+
+	evt := events.Start(ctx, "message_status_repo.delete_message_status", map[string]any{
+		"db_operation":   "del",
+		"operation_name": "delete_message_status",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.ExecuteClosure(evt.Context(), repo.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		return rdb.Del(timeoutCtx, messageStatusKey(messageId)).Err()
+	})
+
+	return err
+}
+
+func (repo *MessageStatusRepo) UpdateMessageState(
+	ctx context.Context,
+	messageId string,
+	state models.MessageState,
+	reason string,
+) error {
+	var err error
+
+	evt := events.Start(ctx, "message_status_repo.update_message_state", map[string]any{
+		"db_operation":   "hset",
+		"operation_name": "update_message_state",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.updateIfExists(evt.Context(), messageId, "HSET",
+		"state", string(state),
+		"error", reason,
+	)
+
+	return err
+}
+
+func (repo *MessageStatusRepo) UpdateMessageWorkload(
+	ctx context.Context,
+	messageId string,
+	workloadId string,
+	totalDevices int64,
+	totalAssignments int64,
+) error {
+	var err error
+
+	evt := events.Start(ctx, "message_status_repo.update_message_workload", map[string]any{
+		"db_operation":   "hset",
+		"operation_name": "update_message_workload",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.updateIfExists(evt.Context(), messageId, "HSET",
+		"workload_id", workloadId,
+		"total_devices", totalDevices,
+		"total_assignments", totalAssignments,
+	)
+
+	return err
+}
+
+func (repo *MessageStatusRepo) IncreaseDeliveryResults(
+	ctx context.Context,
+	messageId string,
+	successCount int64,
+	failureCount int64,
+) error {
+	var err error
+
+	evt := events.Start(ctx, "message_status_repo.increase_delivery_results", map[string]any{
+		"db_operation":   "hincrby",
+		"operation_name": "increase_delivery_results",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.updateIfExists(evt.Context(), messageId, "HINCRBY",
+		"success_count", successCount,
+		"failure_count", failureCount,
+	)
+
+	return err
+}
+
+func (repo *MessageStatusRepo) IncreaseSuppressedCount(
+	ctx context.Context,
+	messageId string,
+	suppressedCount int64,
+) error {
+	var err error
+
+	evt := events.Start(ctx, "message_status_repo.increase_suppressed_count", map[string]any{
+		"db_operation":   "hincrby",
+		"operation_name": "increase_suppressed_count",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.updateIfExists(evt.Context(), messageId, "HINCRBY",
+		"suppressed_count", suppressedCount,
+	)
+
+	return err
+}
+
+func (repo *MessageStatusRepo) IncreaseDuplicateCount(
+	ctx context.Context,
+	messageId string,
+	duplicateCount int64,
+) error {
+	var err error
+
+	evt := events.Start(ctx, "message_status_repo.increase_duplicate_count", map[string]any{
+		"db_operation":   "hincrby",
+		"operation_name": "increase_duplicate_count",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.updateIfExists(evt.Context(), messageId, "HINCRBY",
+		"duplicate_count", duplicateCount,
+	)
+
+	return err
+}
+
+func (repo *MessageStatusRepo) StartMessageBuilding(
+	ctx context.Context,
+	messageId string,
+	workloadId string,
+	totalDevices int64,
+	totalAssignments int64,
+) error {
+	var err error
+
+	evt := events.Start(ctx, "message_status_repo.start_message_building", map[string]any{
+		"db_operation":   "hset",
+		"operation_name": "start_message_building",
+	})
+	defer events.End(evt, true, err, nil)
+
+	_, err = repo.changeStateUnlessFinished(evt.Context(), messageId,
+		"state", string(models.MessageBuilding),
+		"error", "",
+		"workload_id", workloadId,
+		"total_devices", totalDevices,
+		"total_assignments", totalAssignments,
+	)
+	// same as the other updates, the status not exists is ignored
+	if err == status_repo.ErrMessageStatusNotExists {
+		err = nil
+	}
+
+	return err
+}
+
+func (repo *MessageStatusRepo) CancelMessage(ctx context.Context, messageId string) (
+	*models.MessageStatus,
+	error,
+) {
+	var status *models.MessageStatus
+	var err error
+
+	evt := events.Start(ctx, "message_status_repo.cancel_message", map[string]any{
+		"db_operation":   "hset",
+		"operation_name": "cancel_message",
+	})
+	defer events.End(evt, true, err, nil)
+
+	status, err = repo.changeStateUnlessFinished(evt.Context(), messageId,
+		"state", string(models.MessageCanceled),
+		"error", "",
+	)
+
+	return status, err
+}
+
+// changeStateUnlessFinished sets the field-value pairs unless the message has
+// finished, it returns the status before the change.
+func (repo *MessageStatusRepo) changeStateUnlessFinished(
+	ctx context.Context,
+	messageId string,
+	fieldValues ...any,
+) (*models.MessageStatus, error) {
+	var result any
+	err := repo.ExecuteClosure(ctx, repo.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		args := []any{time.Now().UnixMilli(), repo.retention.Milliseconds(), len(models.FinishedMessageStates)}
+		for _, state := range models.FinishedMessageStates {
+			args = append(args, string(state))
+		}
+		args = append(args, fieldValues...)
+		keys := []string{messageStatusKey(messageId)}
+		var runErr error
+		result, runErr = changeStateUnlessFinishedScript.Run(timeoutCtx, rdb, keys, args...).Result()
+		return runErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch result := result.(type) {
+	case []any:
+		fields := make(map[string]string, len(result)/2)
+		for i := 0; i+1 < len(result); i += 2 {
+			field, _ := result[i].(string)
+			fields[field], _ = result[i+1].(string)
+		}
+		return decodeMessageStatus(fields), nil
+	case int64:
+		if result == 0 {
+			return nil, status_repo.ErrMessageStatusNotExists
+		}
+	}
+	return nil, status_repo.ErrMessageStatusFinished
+}
+
+// updateIfExists runs the "command" (HSET or HINCRBY) for each field-value pair
+func (repo *MessageStatusRepo) updateIfExists(
+	ctx context.Context,
+	messageId string,
+	command string,
+	fieldValues ...any,
+) error {
+	return repo.ExecuteClosure(ctx, repo.GetDefaultTimeOut(), func(
+		timeoutCtx context.Context,
+		rdb *redis.Client,
+	) error {
+		args := append([]any{command, time.Now().UnixMilli(), repo.retention.Milliseconds()}, fieldValues...)
+		keys := []string{messageStatusKey(messageId)}
+		return updateIfExistsScript.Run(timeoutCtx, rdb, keys, args...).Err()
+	})
+}
+
+func messageStatusKey(messageId string) string {
+	return "message_status:" + messageId
+}
+
+func decodeMessageStatus(fields map[string]string) *models.MessageStatus {
+	parseInt := func(field string) int64 {
+		val, _ := strconv.ParseInt(fields[field], 10, 64)
+		return val
+	}
+	return &models.MessageStatus{
+		MessageId:            fields["message_id"],
+		Campaign:             fields["campaign"],
+		State:                models.MessageState(fields["state"]),
+		Error:                fields["error"],
+		WorkloadId:           fields["workload_id"],
+		TotalDevices:         parseInt("total_devices"),
+		TotalAssignments:     parseInt("total_assignments"),
+		CommittedAssignments: parseInt("committed_assignments"),
+		SuccessCount:         parseInt("success_count"),
+		FailureCount:         parseInt("failure_count"),
+		SuppressedCount:      parseInt("suppressed_count"),
+		DuplicateCount:       parseInt("duplicate_count"),
+		UpdatedAt:            time.UnixMilli(parseInt("updated_at")),
+	}
+}
@@ -0,0 +1,31 @@
+package external
+
+import (
+	"context"
+	"errors"
+
+	"duolingo/models"
+)
+
+var (
+	ErrMessageStatusNotExists = errors.New("message status not exists")
+	ErrMessageStatusFinished  = errors.New("message status has already finished")
+)
+
+type MessageStatusRepository interface {
+	SaveMessageStatus(ctx context.Context, status *models.MessageStatus) error
+	GetMessageStatus(ctx context.Context, messageId string) (*models.MessageStatus, error)
+	DeleteMessageStatus(ctx context.Context, messageId string) error
+
+	UpdateMessageState(ctx context.Context, messageId string, state models.MessageState, reason string) error
+	UpdateMessageWorkload(ctx context.Context, messageId string, workloadId string, totalDevices int64, totalAssignments int64) error
+	IncreaseDeliveryResults(ctx context.Context, messageId string, successCount int64, failureCount int64) error
+	IncreaseSuppressedCount(ctx context.Context, messageId string, suppressedCount int64) error
+	IncreaseDuplicateCount(ctx context.Context, messageId string, duplicateCount int64) error
+
+	// The state changes below are refused with ErrMessageStatusFinished once the
+	// message has finished, the check and the change are atomic. CancelMessage
+	// returns the status before canceled.
+	StartMessageBuilding(ctx context.Context, messageId string, workloadId string, totalDevices int64, totalAssignments int64) error
+	CancelMessage(ctx context.Context, messageId string) (*models.MessageStatus, error)
+}
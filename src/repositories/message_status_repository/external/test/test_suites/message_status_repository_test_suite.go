@@ -0,0 +1,162 @@
+package test_suites
+
+import (
+	"context"
+	"sync"
+
+	"duolingo/models"
+	status_repo "duolingo/repositories/message_status_repository/external"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MessageStatusRepositoryTestSuite struct {
+	suite.Suite
+	repo status_repo.MessageStatusRepository
+}
+
+func NewMessageStatusRepositoryTestSuite(repo status_repo.MessageStatusRepository) *MessageStatusRepositoryTestSuite {
+	return &MessageStatusRepositoryTestSuite{
+		repo: repo,
+	}
+}
+
+func (s *MessageStatusRepositoryTestSuite) Test_SaveMessageStatus_GetMessageStatus() {
+	input := models.NewMessageInput("testcampaign", "title", "body")
+	saveErr := s.repo.SaveMessageStatus(context.Background(), models.NewMessageStatus(input, models.MessageQueued))
+	defer s.repo.DeleteMessageStatus(context.Background(), input.Id)
+
+	status, getErr := s.repo.GetMessageStatus(context.Background(), input.Id)
+	notExists, notExistsErr := s.repo.GetMessageStatus(context.Background(), "not_exist_id")
+
+	s.Assert().NoError(saveErr)
+	s.Assert().NoError(getErr)
+	s.Assert().Equal(input.Id, status.MessageId)
+	s.Assert().Equal(input.Campaign, status.Campaign)
+	s.Assert().Equal(models.MessageQueued, status.State)
+	s.Assert().Nil(notExists)
+	s.Assert().Equal(status_repo.ErrMessageStatusNotExists, notExistsErr)
+}
+
+func (s *MessageStatusRepositoryTestSuite) Test_UpdateMessageState_And_Workload() {
+	input := models.NewMessageInput("testcampaign", "title", "body")
+	s.repo.SaveMessageStatus(context.Background(), models.NewMessageStatus(input, models.MessageQueued))
+	defer s.repo.DeleteMessageStatus(context.Background(), input.Id)
+
+	workloadErr := s.repo.UpdateMessageWorkload(context.Background(), input.Id, "W1", 100, 10)
+	stateErr := s.repo.UpdateMessageState(context.Background(), input.Id, models.MessageFailed, "reason")
+	status, _ := s.repo.GetMessageStatus(context.Background(), input.Id)
+
+	s.Assert().NoError(workloadErr)
+	s.Assert().NoError(stateErr)
+	s.Assert().Equal("W1", status.WorkloadId)
+	s.Assert().Equal(int64(100), status.TotalDevices)
+	s.Assert().Equal(int64(10), status.TotalAssignments)
+	s.Assert().Equal(models.MessageFailed, status.State)
+	s.Assert().Equal("reason", status.Error)
+}
+
+func (s *MessageStatusRepositoryTestSuite) Test_IncreaseDeliveryResults_Concurrently() {
+	input := models.NewMessageInput("testcampaign", "title", "body")
+	s.repo.SaveMessageStatus(context.Background(), models.NewMessageStatus(input, models.MessageBuilding))
+	defer s.repo.DeleteMessageStatus(context.Background(), input.Id)
+
+	wg := new(sync.WaitGroup)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.repo.IncreaseDeliveryResults(context.Background(), input.Id, 2, 1)
+		}()
+	}
+	wg.Wait()
+	status, _ := s.repo.GetMessageStatus(context.Background(), input.Id)
+
+	s.Assert().Equal(int64(20), status.SuccessCount)
+	s.Assert().Equal(int64(10), status.FailureCount)
+}
+
+func (s *MessageStatusRepositoryTestSuite) Test_IncreaseSuppressedCount() {
+	input := models.NewMessageInput("testcampaign", "title", "body")
+	s.repo.SaveMessageStatus(context.Background(), models.NewMessageStatus(input, models.MessageBuilding))
+	defer s.repo.DeleteMessageStatus(context.Background(), input.Id)
+
+	s.repo.IncreaseDeliveryResults(context.Background(), input.Id, 2, 1)
+	err := s.repo.IncreaseSuppressedCount(context.Background(), input.Id, 3)
+	status, _ := s.repo.GetMessageStatus(context.Background(), input.Id)
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(3), status.SuppressedCount)
+	s.Assert().Equal(int64(6), status.TotalReported())
+}
+
+func (s *MessageStatusRepositoryTestSuite) Test_IncreaseDuplicateCount() {
+	input := models.NewMessageInput("testcampaign", "title", "body")
+	s.repo.SaveMessageStatus(context.Background(), models.NewMessageStatus(input, models.MessageBuilding))
+	defer s.repo.DeleteMessageStatus(context.Background(), input.Id)
+
+	s.repo.IncreaseSuppressedCount(context.Background(), input.Id, 3)
+	err := s.repo.IncreaseDuplicateCount(context.Background(), input.Id, 2)
+	status, _ := s.repo.GetMessageStatus(context.Background(), input.Id)
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(3), status.SuppressedCount)
+	s.Assert().Equal(int64(2), status.DuplicateCount)
+	s.Assert().Equal(int64(5), status.TotalReported())
+}
+
+func (s *MessageStatusRepositoryTestSuite) Test_StartMessageBuilding() {
+	input := models.NewMessageInput("testcampaign", "title", "body")
+	s.repo.SaveMessageStatus(context.Background(), models.NewMessageStatus(input, models.MessageQueued))
+	defer s.repo.DeleteMessageStatus(context.Background(), input.Id)
+
+	err := s.repo.StartMessageBuilding(context.Background(), input.Id, "W1", 100, 10)
+	status, _ := s.repo.GetMessageStatus(context.Background(), input.Id)
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(models.MessageBuilding, status.State)
+	s.Assert().Equal("W1", status.WorkloadId)
+	s.Assert().Equal(int64(100), status.TotalDevices)
+	s.Assert().Equal(int64(10), status.TotalAssignments)
+}
+
+func (s *MessageStatusRepositoryTestSuite) Test_StartMessageBuilding_Refused_Once_Canceled() {
+	input := models.NewMessageInput("testcampaign", "title", "body")
+	s.repo.SaveMessageStatus(context.Background(), models.NewMessageStatus(input, models.MessageQueued))
+	defer s.repo.DeleteMessageStatus(context.Background(), input.Id)
+
+	before, cancelErr := s.repo.CancelMessage(context.Background(), input.Id)
+	buildErr := s.repo.StartMessageBuilding(context.Background(), input.Id, "W1", 100, 10)
+	status, _ := s.repo.GetMessageStatus(context.Background(), input.Id)
+
+	s.Assert().NoError(cancelErr)
+	s.Assert().Equal(models.MessageQueued, before.State)
+	s.Assert().Equal(status_repo.ErrMessageStatusFinished, buildErr)
+	s.Assert().Equal(models.MessageCanceled, status.State)
+	s.Assert().Empty(status.WorkloadId)
+}
+
+func (s *MessageStatusRepositoryTestSuite) Test_CancelMessage_Returns_Workload() {
+	input := models.NewMessageInput("testcampaign", "title", "body")
+	s.repo.SaveMessageStatus(context.Background(), models.NewMessageStatus(input, models.MessageQueued))
+	defer s.repo.DeleteMessageStatus(context.Background(), input.Id)
+
+	s.repo.StartMessageBuilding(context.Background(), input.Id, "W1", 100, 10)
+	before, cancelErr := s.repo.CancelMessage(context.Background(), input.Id)
+	_, recancelErr := s.repo.CancelMessage(context.Background(), input.Id)
+	_, notExistsErr := s.repo.CancelMessage(context.Background(), "not_exist_id")
+
+	s.Assert().NoError(cancelErr)
+	s.Assert().Equal(models.MessageBuilding, before.State)
+	s.Assert().Equal("W1", before.WorkloadId)
+	s.Assert().Equal(status_repo.ErrMessageStatusFinished, recancelErr)
+	s.Assert().Equal(status_repo.ErrMessageStatusNotExists, notExistsErr)
+}
+
+func (s *MessageStatusRepositoryTestSuite) Test_Updates_Ignored_If_Not_Exists() {
+	updateErr := s.repo.IncreaseDeliveryResults(context.Background(), "not_exist_id", 1, 1)
+	_, getErr := s.repo.GetMessageStatus(context.Background(), "not_exist_id")
+
+	s.Assert().NoError(updateErr)
+	s.Assert().Equal(status_repo.ErrMessageStatusNotExists, getErr)
+}
@@ -0,0 +1,281 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"duolingo/models"
+	outbox_repo "duolingo/repositories/outbox_repository/external"
+
+	connection "duolingo/libraries/connection_manager/drivers/mongodb"
+	events "duolingo/libraries/events/facade"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+### Notions:
+ 1. A relay claims a message by pushing its "next_attempt_at" forward, so
+    that a message is relayed by one relay at a time. If the relay crashes,
+    the message is claimed again once the claim timeout has passed.
+ 2. The sent messages are kept for the "retention" duration, they are removed
+    by the TTL index on "sent_at".
+*/
+type OutboxRepo struct {
+	connection.MongoClient
+
+	databaseName   string
+	collectionName string
+	retention      time.Duration
+}
+
+func NewOutboxRepo(
+	client *connection.MongoClient,
+	databaseName string,
+	collectionName string,
+	retention time.Duration,
+) *OutboxRepo {
+	return &OutboxRepo{
+		MongoClient:    *client,
+		databaseName:   databaseName,
+		collectionName: collectionName,
+		retention:      retention,
+	}
+}
+
+func (repo *OutboxRepo) CreateIndexes(ctx context.Context) error {
+	var err error
+
+	evt := events.Start(ctx, "outbox_repo.create_indexes", map[string]any{
+		"db_operation":   "create_indexes",
+		"operation_name": "create_indexes",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.ExecuteClosure(evt.Context(), repo.GetWriteTimeout(), func(
+		timeoutCtx context.Context,
+		conn *mongo.Client,
+	) error {
+		collection := conn.Database(repo.databaseName).Collection(repo.collectionName)
+		_, createErr := collection.Indexes().CreateMany(timeoutCtx, []mongo.IndexModel{
+			{
+				Keys: bson.D{{Key: "state", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+			},
+			{
+				Keys:    bson.D{{Key: "sent_at", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(int32(repo.retention.Seconds())),
+			},
+		})
+		return createErr
+	})
+
+	return err
+}
+
+func (repo *OutboxRepo) AddOutboxMessage(ctx context.Context, message *models.OutboxMessage) error {
+	var err error
+
+	evt := events.Start(ctx, "outbox_repo.add_outbox_message", map[string]any{
+		"db_operation":   "insert",
+		"operation_name": "add_outbox_message",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.ExecuteClosure(evt.Context(), repo.GetWriteTimeout(), func(
+		timeoutCtx context.Context,
+		conn *mongo.Client,
+	) error {
+		collection := conn.Database(repo.databaseName).Collection(repo.collectionName)
+		_, insertErr := collection.InsertOne(timeoutCtx, message)
+		return insertErr
+	})
+
+	return err
+}
+
+func (repo *OutboxRepo) GetOutboxMessage(ctx context.Context, id string) (*models.OutboxMessage, error) {
+	var err error
+	var message *models.OutboxMessage
+
+	evt := events.Start(ctx, "outbox_repo.get_outbox_message", map[string]any{
+		"db_operation":   "find",
+		"operation_name": "get_outbox_message",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.ExecuteClosure(evt.Context(), repo.GetReadTimeout(), func(
+		timeoutCtx context.Context,
+		conn *mongo.Client,
+	) error {
+		collection := conn.Database(repo.databaseName).Collection(repo.collectionName)
+		result := new(models.OutboxMessage)
+		findErr := collection.FindOne(timeoutCtx, bson.M{"_id": id}).Decode(result)
+		if findErr == nil {
+			message = result
+		}
+		return findErr
+	})
+	if err == mongo.ErrNoDocuments {
+		err = outbox_repo.ErrOutboxMessageNotExists
+	}
+
+	return message, err
+}
+
+func (repo *OutboxRepo) DeleteOutboxMessage(ctx context.Context, id string) error {
+	var err error
+
+	evt := events.Start(ctx, "outbox_repo.delete_outbox_message", map[string]any{
+		"db_operation":   "delete",
+		"operation_name": "delete_outbox_message",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.ExecuteClosure(evt.Context(), repo.GetWriteTimeout(), func(
+		timeoutCtx context.Context,
+		conn *mongo.Client,
+	) error {
+		collection := conn.Database(repo.databaseName).Collection(repo.collectionName)
+		_, deleteErr := collection.DeleteOne(timeoutCtx, bson.M{"_id": id})
+		return deleteErr
+	})
+
+	return err
+}
+
+func (repo *OutboxRepo) ClaimPendingMessages(
+	ctx context.Context,
+	limit int,
+	claimTimeout time.Duration,
+) ([]*models.OutboxMessage, error) {
+	var err error
+	messages := []*models.OutboxMessage{}
+
+	evt := events.Start(ctx, "outbox_repo.claim_pending_messages", map[string]any{
+		"db_operation":   "find_and_modify",
+		"operation_name": "claim_pending_messages",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.ExecuteClosure(evt.Context(), repo.GetWriteTimeout(), func(
+		timeoutCtx context.Context,
+		conn *mongo.Client,
+	) error {
+		// the closure may be retried, the messages claimed by a failed attempt
+		// are left to be claimed again once their claim has expired
+		messages = []*models.OutboxMessage{}
+		collection := conn.Database(repo.databaseName).Collection(repo.collectionName)
+		// each message is claimed atomically, as the other relays
+		// may be claiming the same messages concurrently
+		for len(messages) < limit {
+			now := time.Now()
+			claimed := new(models.OutboxMessage)
+			claimErr := collection.FindOneAndUpdate(
+				timeoutCtx,
+				bson.M{
+					"state":           models.OutboxPending,
+					"next_attempt_at": bson.M{"$lte": now},
+				},
+				bson.M{"$set": bson.M{"next_attempt_at": now.Add(claimTimeout)}},
+				options.FindOneAndUpdate().
+					SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+					SetReturnDocument(options.After),
+			).Decode(claimed)
+			if claimErr == mongo.ErrNoDocuments {
+				return nil
+			}
+			if claimErr != nil {
+				return claimErr
+			}
+			messages = append(messages, claimed)
+		}
+		return nil
+	})
+
+	return messages, err
+}
+
+func (repo *OutboxRepo) MarkMessageSent(ctx context.Context, id string) error {
+	var err error
+
+	evt := events.Start(ctx, "outbox_repo.mark_message_sent", map[string]any{
+		"db_operation":   "update",
+		"operation_name": "mark_message_sent",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.updateOutboxMessage(evt.Context(), id, bson.M{
+		"$set": bson.M{
+			"state":   models.OutboxSent,
+			"sent_at": time.Now(),
+		},
+		"$inc": bson.M{"attempts": 1},
+	})
+
+	return err
+}
+
+func (repo *OutboxRepo) MarkMessageFailed(
+	ctx context.Context,
+	id string,
+	reason string,
+	retryAt time.Time,
+) error {
+	var err error
+
+	evt := events.Start(ctx, "outbox_repo.mark_message_failed", map[string]any{
+		"db_operation":   "update",
+		"operation_name": "mark_message_failed",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.updateOutboxMessage(evt.Context(), id, bson.M{
+		"$set": bson.M{
+			"error":           reason,
+			"next_attempt_at": retryAt,
+		},
+		"$inc": bson.M{"attempts": 1},
+	})
+
+	return err
+}
+
+func (repo *OutboxRepo) MarkMessageFailedPermanently(
+	ctx context.Context,
+	id string,
+	reason string,
+) error {
+	var err error
+
+	evt := events.Start(ctx, "outbox_repo.mark_message_failed_permanently", map[string]any{
+		"db_operation":   "update",
+		"operation_name": "mark_message_failed_permanently",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.updateOutboxMessage(evt.Context(), id, bson.M{
+		"$set": bson.M{
+			"state": models.OutboxFailed,
+			"error": reason,
+		},
+		"$inc": bson.M{"attempts": 1},
+	})
+
+	return err
+}
+
+func (repo *OutboxRepo) updateOutboxMessage(ctx context.Context, id string, update bson.M) error {
+	return repo.ExecuteClosure(ctx, repo.GetWriteTimeout(), func(
+		timeoutCtx context.Context,
+		conn *mongo.Client,
+	) error {
+		collection := conn.Database(repo.databaseName).Collection(repo.collectionName)
+		result, updateErr := collection.UpdateOne(timeoutCtx, bson.M{"_id": id}, update)
+		if updateErr == nil && result.MatchedCount == 0 {
+			return outbox_repo.ErrOutboxMessageNotExists
+		}
+		return updateErr
+	})
+}
@@ -0,0 +1,27 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"duolingo/models"
+)
+
+var (
+	ErrOutboxMessageNotExists = errors.New("outbox message not exists")
+)
+
+type OutboxRepository interface {
+	AddOutboxMessage(ctx context.Context, message *models.OutboxMessage) error
+	GetOutboxMessage(ctx context.Context, id string) (*models.OutboxMessage, error)
+	DeleteOutboxMessage(ctx context.Context, id string) error
+
+	// ClaimPendingMessages returns the pending messages due for an attempt, a claimed
+	// message is hidden from the other claims for the "claimTimeout" duration.
+	ClaimPendingMessages(ctx context.Context, limit int, claimTimeout time.Duration) ([]*models.OutboxMessage, error)
+	MarkMessageSent(ctx context.Context, id string) error
+	MarkMessageFailed(ctx context.Context, id string, reason string, retryAt time.Time) error
+	// MarkMessageFailedPermanently stops the attempts of a message which can never be relayed
+	MarkMessageFailedPermanently(ctx context.Context, id string, reason string) error
+}
@@ -0,0 +1,115 @@
+package test_suites
+
+import (
+	"context"
+	"time"
+
+	"duolingo/models"
+	outbox_repo "duolingo/repositories/outbox_repository/external"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type OutboxRepositoryTestSuite struct {
+	suite.Suite
+	repo outbox_repo.OutboxRepository
+}
+
+func NewOutboxRepositoryTestSuite(repo outbox_repo.OutboxRepository) *OutboxRepositoryTestSuite {
+	return &OutboxRepositoryTestSuite{
+		repo: repo,
+	}
+}
+
+func (s *OutboxRepositoryTestSuite) Test_AddOutboxMessage_GetOutboxMessage() {
+	message := models.NewOutboxMessage("test_topic", "payload")
+	addErr := s.repo.AddOutboxMessage(context.Background(), message)
+	defer s.repo.DeleteOutboxMessage(context.Background(), message.Id)
+
+	result, getErr := s.repo.GetOutboxMessage(context.Background(), message.Id)
+	notExists, notExistsErr := s.repo.GetOutboxMessage(context.Background(), "not_exist_id")
+
+	s.Assert().NoError(addErr)
+	s.Assert().NoError(getErr)
+	s.Assert().Equal(message.Topic, result.Topic)
+	s.Assert().Equal(message.Payload, result.Payload)
+	s.Assert().Equal(models.OutboxPending, result.State)
+	s.Assert().Nil(notExists)
+	s.Assert().Equal(outbox_repo.ErrOutboxMessageNotExists, notExistsErr)
+}
+
+func (s *OutboxRepositoryTestSuite) Test_ClaimPendingMessages() {
+	topic := "test_topic_" + uuid.NewString()
+	message := models.NewOutboxMessage(topic, "payload")
+	s.repo.AddOutboxMessage(context.Background(), message)
+	defer s.repo.DeleteOutboxMessage(context.Background(), message.Id)
+
+	claimed1, claimErr1 := s.repo.ClaimPendingMessages(context.Background(), 100, time.Minute)
+	claimed2, claimErr2 := s.repo.ClaimPendingMessages(context.Background(), 100, time.Minute)
+
+	s.Assert().NoError(claimErr1)
+	s.Assert().NoError(claimErr2)
+	s.Assert().True(s.containsMessage(claimed1, message.Id))
+	s.Assert().False(s.containsMessage(claimed2, message.Id), "claimed message should be hidden")
+}
+
+func (s *OutboxRepositoryTestSuite) Test_MarkMessageSent() {
+	message := models.NewOutboxMessage("test_topic", "payload")
+	s.repo.AddOutboxMessage(context.Background(), message)
+	defer s.repo.DeleteOutboxMessage(context.Background(), message.Id)
+
+	markErr := s.repo.MarkMessageSent(context.Background(), message.Id)
+	notExistsErr := s.repo.MarkMessageSent(context.Background(), "not_exist_id")
+	result, _ := s.repo.GetOutboxMessage(context.Background(), message.Id)
+	claimed, _ := s.repo.ClaimPendingMessages(context.Background(), 100, time.Minute)
+
+	s.Assert().NoError(markErr)
+	s.Assert().Equal(outbox_repo.ErrOutboxMessageNotExists, notExistsErr)
+	s.Assert().Equal(models.OutboxSent, result.State)
+	s.Assert().Equal(1, result.Attempts)
+	s.Assert().NotNil(result.SentAt)
+	s.Assert().False(s.containsMessage(claimed, message.Id), "sent message should not be claimed")
+}
+
+func (s *OutboxRepositoryTestSuite) Test_MarkMessageFailed() {
+	message := models.NewOutboxMessage("test_topic", "payload")
+	s.repo.AddOutboxMessage(context.Background(), message)
+	defer s.repo.DeleteOutboxMessage(context.Background(), message.Id)
+
+	s.repo.ClaimPendingMessages(context.Background(), 100, time.Minute)
+	markErr := s.repo.MarkMessageFailed(context.Background(), message.Id, "broker down", time.Now())
+	result, _ := s.repo.GetOutboxMessage(context.Background(), message.Id)
+	claimed, _ := s.repo.ClaimPendingMessages(context.Background(), 100, time.Minute)
+
+	s.Assert().NoError(markErr)
+	s.Assert().Equal(models.OutboxPending, result.State)
+	s.Assert().Equal(1, result.Attempts)
+	s.Assert().Equal("broker down", result.Error)
+	s.Assert().True(s.containsMessage(claimed, message.Id), "failed message should be retried")
+}
+
+func (s *OutboxRepositoryTestSuite) Test_MarkMessageFailedPermanently() {
+	message := models.NewOutboxMessage("test_topic", "payload")
+	s.repo.AddOutboxMessage(context.Background(), message)
+	defer s.repo.DeleteOutboxMessage(context.Background(), message.Id)
+
+	s.repo.ClaimPendingMessages(context.Background(), 100, 0)
+	markErr := s.repo.MarkMessageFailedPermanently(context.Background(), message.Id, "unknown topic")
+	result, _ := s.repo.GetOutboxMessage(context.Background(), message.Id)
+	claimed, _ := s.repo.ClaimPendingMessages(context.Background(), 100, time.Minute)
+
+	s.Assert().NoError(markErr)
+	s.Assert().Equal(models.OutboxFailed, result.State)
+	s.Assert().Equal("unknown topic", result.Error)
+	s.Assert().False(s.containsMessage(claimed, message.Id), "permanently failed message should not be retried")
+}
+
+func (s *OutboxRepositoryTestSuite) containsMessage(messages []*models.OutboxMessage, id string) bool {
+	for _, message := range messages {
+		if message.Id == id {
+			return true
+		}
+	}
+	return false
+}
@@ -10,8 +10,10 @@ import (
 type AggregateUsersCommand struct {
 	*filters.UserFilters
 
-	pipelineSteps []b.D
-	pipeline      mongo.Pipeline
+	sumUserDevices    bool
+	sumDistinctTokens bool
+	pipelineSteps     []b.D
+	pipeline          mongo.Pipeline
 }
 
 func NewAggregateUsersCommand() *AggregateUsersCommand {
@@ -22,26 +24,31 @@ func NewAggregateUsersCommand() *AggregateUsersCommand {
 	}
 }
 
+// The steps are built with the filters, since the devices are counted
+// only if they match the device filters (e.g. the platforms).
 func (command *AggregateUsersCommand) AddAggregationSumUserDevices() {
-	command.pipelineSteps = append(command.pipelineSteps,
-		b.D{{Key: "$project", Value: b.M{
-			"count": b.M{"$size": "$user_devices"},
-		}}},
-		b.D{{Key: "$group", Value: b.M{
-			"_id":                nil,
-			"count_user_devices": b.M{"$sum": "$count"},
-		}}},
-	)
+	command.sumUserDevices = true
+}
+
+func (command *AggregateUsersCommand) AddAggregationSumDistinctDeviceTokens() {
+	command.sumDistinctTokens = true
 }
 
 func (command *AggregateUsersCommand) Build() error {
 	command.pipeline = mongo.Pipeline{}
+	command.pipelineSteps = []b.D{}
 
 	filters := command.GetFilters()
 	if len(filters) > 0 {
 		command.pipeline = append(command.pipeline, b.D{{Key: "$match", Value: filters}})
 	}
 
+	if command.sumDistinctTokens {
+		command.buildSumDistinctDeviceTokensSteps()
+	} else if command.sumUserDevices {
+		command.buildSumUserDevicesSteps()
+	}
+
 	for i := range command.pipelineSteps {
 		command.pipeline = append(command.pipeline, command.pipelineSteps[i])
 	}
@@ -49,6 +56,44 @@ func (command *AggregateUsersCommand) Build() error {
 	return nil
 }
 
+func (command *AggregateUsersCommand) buildSumUserDevicesSteps() {
+	var devices any = "$user_devices"
+	if platforms := command.GetPlatforms(); len(platforms) > 0 {
+		devices = b.M{"$filter": b.M{
+			"input": "$user_devices",
+			"as":    "device",
+			"cond":  b.M{"$in": b.A{"$$device.platform", platforms}},
+		}}
+	}
+	command.pipelineSteps = append(command.pipelineSteps,
+		b.D{{Key: "$project", Value: b.M{
+			"count": b.M{"$size": devices},
+		}}},
+		b.D{{Key: "$group", Value: b.M{
+			"_id":                nil,
+			"count_user_devices": b.M{"$sum": "$count"},
+		}}},
+	)
+}
+
+// The devices are grouped by their token, the devices of the token owned by
+// several users count once, and its other owners count as its duplicates.
+func (command *AggregateUsersCommand) buildSumDistinctDeviceTokensSteps() {
+	command.pipelineSteps = append(command.pipelineSteps,
+		b.D{{Key: "$unwind", Value: b.M{"path": "$user_devices"}}},
+		b.D{{Key: "$match", Value: command.GetDeviceFilters()}},
+		b.D{{Key: "$group", Value: b.M{
+			"_id":    "$user_devices.token",
+			"owners": b.M{"$sum": 1},
+		}}},
+		b.D{{Key: "$group", Value: b.M{
+			"_id":                    nil,
+			"count_user_devices":     b.M{"$sum": 1},
+			"count_duplicate_tokens": b.M{"$sum": b.M{"$subtract": b.A{"$owners", 1}}},
+		}}},
+	)
+}
+
 func (command *AggregateUsersCommand) GetPipeline() mongo.Pipeline {
 	return command.pipeline
 }
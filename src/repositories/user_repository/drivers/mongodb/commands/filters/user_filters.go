@@ -1,12 +1,15 @@
 package filters
 
 import (
-	b "go.mongodb.org/mongo-driver/bson"
+	"duolingo/models"
 	"time"
+
+	b "go.mongodb.org/mongo-driver/bson"
 )
 
 type UserFilters struct {
-	filters b.M
+	filters   b.M
+	platforms []string
 }
 
 func NewUserFilters() *UserFilters {
@@ -30,6 +33,40 @@ func (f *UserFilters) SetFilterOnlyEmailVerified() {
 	}
 }
 
+func (f *UserFilters) SetFilterMemberships(memberships []models.Membership) {
+	if len(memberships) > 0 {
+		f.filters["membership_enum"] = b.M{"$in": memberships}
+	}
+}
+
+func (f *UserFilters) SetFilterNativeLanguages(languages []models.NativeLanguage) {
+	if len(languages) > 0 {
+		f.filters["native_lan_enum"] = b.M{"$in": languages}
+	}
+}
+
+// SetFilterPlatforms matches the users having a device of the platforms,
+// the devices of the other platforms are filtered by the device filters.
+func (f *UserFilters) SetFilterPlatforms(platforms []string) {
+	if len(platforms) > 0 {
+		f.platforms = platforms
+		f.filters["user_devices.platform"] = b.M{"$in": platforms}
+	}
+}
+
+func (f *UserFilters) GetPlatforms() []string {
+	return f.platforms
+}
+
+// GetDeviceFilters returns the filters on the unwound "user_devices"
+func (f *UserFilters) GetDeviceFilters() b.M {
+	deviceFilters := b.M{}
+	if len(f.platforms) > 0 {
+		deviceFilters["user_devices.platform"] = b.M{"$in": f.platforms}
+	}
+	return deviceFilters
+}
+
 func (f *UserFilters) GetFilters() b.M {
 	return f.filters
 }
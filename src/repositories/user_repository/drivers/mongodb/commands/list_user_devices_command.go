@@ -1,6 +1,9 @@
 package commands
 
 import (
+	"slices"
+	"time"
+
 	"duolingo/repositories/user_repository/drivers/mongodb/commands/filters"
 
 	cmd "duolingo/repositories/user_repository/external/commands"
@@ -12,10 +15,15 @@ import (
 type ListUserDevicesCommand struct {
 	*filters.UserFilters
 
-	pipeline mongo.Pipeline
-	sorts    b.M
-	offset   int64
-	limit    int64
+	stages       mongo.Pipeline
+	pipeline     mongo.Pipeline
+	keysPipeline mongo.Pipeline
+	sorts        b.M
+	offset       int64
+	limit        int64
+	keyRange     b.M
+	snapshotId   string
+	distinct     bool
 }
 
 func NewListUserDevicesCommand() *ListUserDevicesCommand {
@@ -31,6 +39,31 @@ func (command *ListUserDevicesCommand) SetPagination(offset int64, limit int64)
 	command.limit = limit
 }
 
+func (command *ListUserDevicesCommand) SetKeyRange(after string, until string) {
+	keyRange := b.M{}
+	if after != "" {
+		keyRange["$gt"] = after
+	}
+	if until != "" {
+		keyRange["$lte"] = until
+	}
+	if len(keyRange) > 0 {
+		command.keyRange = keyRange
+	}
+}
+
+func (command *ListUserDevicesCommand) SetSnapshot(snapshotId string) {
+	command.snapshotId = snapshotId
+}
+
+func (command *ListUserDevicesCommand) SetDistinctTokens() {
+	command.distinct = true
+}
+
+func (command *ListUserDevicesCommand) IsSnapshot() bool {
+	return command.snapshotId != ""
+}
+
 func (command *ListUserDevicesCommand) SetSortById(ord cmd.SortOrder) {
 	if ord == cmd.OrderASC {
 		command.sorts["user_id"] = 1
@@ -39,21 +72,115 @@ func (command *ListUserDevicesCommand) SetSortById(ord cmd.SortOrder) {
 	}
 }
 
+// Build sorts the users before unwinding their devices, so that the sort
+// and the key range are served by the "user_id" index. The snapshot devices
+// are stored unwound, and already filtered.
 func (command *ListUserDevicesCommand) Build() error {
-	command.pipeline = mongo.Pipeline{
-		{{Key: "$match", Value: command.GetFilters()}},
-		{{Key: "$unwind", Value: b.M{"path": "$user_devices"}}},
-		{{Key: "$sort", Value: command.sorts}},
-		{{Key: "$project", Value: b.M{
-			"platform": "$user_devices.platform",
-			"token":    "$user_devices.token",
-		}}},
-		{{Key: "$skip", Value: command.offset}},
-		{{Key: "$limit", Value: command.limit}},
+	var projection b.M
+	if command.IsSnapshot() {
+		match := b.M{"snapshot_id": command.snapshotId}
+		if command.keyRange != nil {
+			match["user_id"] = command.keyRange
+		}
+		command.stages = mongo.Pipeline{{{Key: "$match", Value: match}}}
+		if len(command.sorts) > 0 {
+			command.stages = append(command.stages, b.D{{Key: "$sort", Value: command.sorts}})
+		}
+		projection = b.M{"platform": 1, "token": 1, "web_push": 1, "recipient": 1}
+	} else {
+		match := command.GetFilters()
+		if command.keyRange != nil {
+			match = b.M{"$and": []b.M{match, {"user_id": command.keyRange}}}
+		}
+		command.stages = mongo.Pipeline{{{Key: "$match", Value: match}}}
+		if len(command.sorts) > 0 {
+			command.stages = append(command.stages, b.D{{Key: "$sort", Value: command.sorts}})
+		}
+		command.stages = append(command.stages,
+			b.D{{Key: "$unwind", Value: b.M{"path": "$user_devices"}}},
+			b.D{{Key: "$match", Value: command.GetDeviceFilters()}},
+		)
+		projection = deviceProjection()
+	}
+
+	command.keysPipeline = slices.Clone(command.stages)
+	if command.distinct && !command.IsSnapshot() {
+		command.keysPipeline = append(command.keysPipeline,
+			b.D{{Key: "$group", Value: b.M{
+				"_id":     "$user_devices.token",
+				"user_id": b.M{"$min": "$user_id"},
+			}}},
+			b.D{{Key: "$sort", Value: b.M{"user_id": 1}}},
+		)
+	}
+	command.keysPipeline = append(command.keysPipeline,
+		b.D{{Key: "$project", Value: b.M{"_id": 0, "user_id": 1}}},
+	)
+
+	command.pipeline = append(slices.Clone(command.stages),
+		b.D{{Key: "$project", Value: projection}},
+	)
+	if command.offset > 0 {
+		command.pipeline = append(command.pipeline, b.D{{Key: "$skip", Value: command.offset}})
+	}
+	if command.limit > 0 {
+		command.pipeline = append(command.pipeline, b.D{{Key: "$limit", Value: command.limit}})
 	}
 	return nil
 }
 
+// GetSnapshotPipeline materializes the listed devices into the snapshot
+// collection, along with their user id so that the snapshot can be paged.
+// The token owned by several users is kept once, for the lowest user id,
+// and the number of its duplicates is kept along.
+func (command *ListUserDevicesCommand) GetSnapshotPipeline(
+	collectionName string,
+	snapshotId string,
+	createdAt time.Time,
+) mongo.Pipeline {
+	projection := deviceProjection()
+	projection["_id"] = 0
+	projection["snapshot_id"] = b.M{"$literal": snapshotId}
+	projection["user_id"] = "$user_id"
+	projection["created_at"] = b.M{"$literal": createdAt}
+
+	return append(slices.Clone(command.stages),
+		b.D{{Key: "$project", Value: projection}},
+		b.D{{Key: "$group", Value: b.M{
+			"_id":    "$token",
+			"device": b.M{"$top": b.M{"sortBy": b.M{"user_id": 1}, "output": "$$ROOT"}},
+			"owners": b.M{"$sum": 1},
+		}}},
+		b.D{{Key: "$replaceRoot", Value: b.M{"newRoot": b.M{"$mergeObjects": b.A{
+			"$device",
+			b.M{"duplicates": b.M{"$subtract": b.A{"$owners", 1}}},
+		}}}}},
+		b.D{{Key: "$merge", Value: b.M{"into": collectionName}}},
+	)
+}
+
 func (command *ListUserDevicesCommand) GetPipeline() mongo.Pipeline {
 	return command.pipeline
 }
+
+// GetKeysPipeline lists the user id of each device only, in the same order
+func (command *ListUserDevicesCommand) GetKeysPipeline() mongo.Pipeline {
+	return command.keysPipeline
+}
+
+// deviceProjection shapes an unwound user device as a models.UserDevice
+func deviceProjection() b.M {
+	return b.M{
+		"platform": "$user_devices.platform",
+		"token":    "$user_devices.token",
+		"web_push": "$user_devices.web_push",
+		"recipient": b.M{
+			"user_id":         "$user_id",
+			"firstname":       "$firstname",
+			"lastname":        "$lastname",
+			"username":        "$username",
+			"native_lan_enum": "$native_lan_enum",
+			"timezone":        "$timezone",
+		},
+	}
+}
@@ -1,9 +1,14 @@
 package results
 
 type UsersAggregationResult struct {
-	CountUserDevices int64 `bson:"count_user_devices"`
+	CountUserDevices     int64 `bson:"count_user_devices"`
+	CountDuplicateTokens int64 `bson:"count_duplicate_tokens"`
 }
 
 func (result *UsersAggregationResult) GetCountUserDevices() int64 {
 	return result.CountUserDevices
 }
+
+func (result *UsersAggregationResult) GetCountDuplicateTokens() int64 {
+	return result.CountDuplicateTokens
+}
@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"context"
+	"time"
 
 	"duolingo/models"
 	driver_cmd "duolingo/repositories/user_repository/drivers/mongodb/commands"
@@ -13,7 +14,9 @@ import (
 	events "duolingo/libraries/events/facade"
 
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type UserRepo struct {
@@ -21,6 +24,14 @@ type UserRepo struct {
 
 	databaseName   string
 	collectionName string
+
+	// The audience snapshots are removed once their job is done, the
+	// retention removes the snapshots of the jobs that never finished.
+	// Snapshotting a large audience takes longer than the other writes,
+	// hence its own timeout.
+	snapshotCollectionName string
+	snapshotRetention      time.Duration
+	snapshotTimeout        time.Duration
 }
 
 func NewUserRepo(
@@ -29,12 +40,66 @@ func NewUserRepo(
 	collectionName string,
 ) *UserRepo {
 	return &UserRepo{
-		MongoClient:    *client,
-		databaseName:   databaseName,
-		collectionName: collectionName,
+		MongoClient:            *client,
+		databaseName:           databaseName,
+		collectionName:         collectionName,
+		snapshotCollectionName: "audience_snapshots",
+		snapshotRetention:      72 * time.Hour,
+		snapshotTimeout:        10 * time.Minute,
 	}
 }
 
+// SetSnapshotTimeout ignores the non-positive timeouts, the default is kept
+func (repo *UserRepo) SetSnapshotTimeout(timeout time.Duration) *UserRepo {
+	if timeout > 0 {
+		repo.snapshotTimeout = timeout
+	}
+	return repo
+}
+
+// CreateIndexes creates the indexes seeking the campaign users, and the
+// snapshot devices, by their user id.
+func (repo *UserRepo) CreateIndexes(ctx context.Context) error {
+	var err error
+
+	evt := events.Start(ctx, "user_repo.create_indexes", map[string]any{
+		"db_operation":   "create_indexes",
+		"operation_name": "create_indexes",
+	})
+	defer events.End(evt, true, err, nil)
+
+	err = repo.ExecuteClosure(evt.Context(), repo.GetWriteTimeout(), func(
+		timeoutCtx context.Context,
+		conn *mongo.Client,
+	) error {
+		collection := conn.Database(repo.databaseName).Collection(repo.collectionName)
+		_, createErr := collection.Indexes().CreateMany(timeoutCtx, []mongo.IndexModel{
+			{
+				Keys: bson.D{{Key: "user_id", Value: 1}},
+			},
+			{
+				Keys: bson.D{{Key: "campaigns", Value: 1}, {Key: "user_id", Value: 1}},
+			},
+		})
+		if createErr != nil {
+			return createErr
+		}
+		snapshots := conn.Database(repo.databaseName).Collection(repo.snapshotCollectionName)
+		_, createErr = snapshots.Indexes().CreateMany(timeoutCtx, []mongo.IndexModel{
+			{
+				Keys: bson.D{{Key: "snapshot_id", Value: 1}, {Key: "user_id", Value: 1}},
+			},
+			{
+				Keys:    bson.D{{Key: "created_at", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(int32(repo.snapshotRetention.Seconds())),
+			},
+		})
+		return createErr
+	})
+
+	return err
+}
+
 func (repo *UserRepo) InsertManyUsers(ctx context.Context, users []*models.User) ([]*models.User, error) {
 	var err error
 
@@ -111,6 +176,42 @@ func (repo *UserRepo) DeleteUsers(ctx context.Context, command cmd.DeleteUsersCo
 	return err
 }
 
+// DeleteDevicesByTokens removes the devices from every user owning the tokens,
+// it returns the number of the modified users.
+func (repo *UserRepo) DeleteDevicesByTokens(ctx context.Context, tokens []string) (int64, error) {
+	var err error
+	var modified int64
+
+	evt := events.Start(ctx, "user_repo.delete_devices_by_tokens", map[string]any{
+		"db_operation":   "update",
+		"operation_name": "delete_devices_by_tokens",
+	})
+	defer events.End(evt, true, err, nil)
+
+	if len(tokens) == 0 {
+		return 0, nil
+	}
+
+	timeout := repo.GetWriteTimeout()
+	err = repo.ExecuteClosure(evt.Context(), timeout, func(
+		timeoutCtx context.Context,
+		conn *mongo.Client,
+	) error {
+		collection := conn.Database(repo.databaseName).Collection(repo.collectionName)
+		result, updateErr := collection.UpdateMany(
+			timeoutCtx,
+			bson.M{"user_devices.token": bson.M{"$in": tokens}},
+			bson.M{"$pull": bson.M{"user_devices": bson.M{"token": bson.M{"$in": tokens}}}},
+		)
+		if updateErr == nil {
+			modified = result.ModifiedCount
+		}
+		return updateErr
+	})
+
+	return modified, err
+}
+
 func (repo *UserRepo) GetListUsersByIds(ctx context.Context, ids []string) ([]*models.User, error) {
 	var err error
 	var users []*models.User
@@ -197,7 +298,7 @@ func (repo *UserRepo) GetListUserDevices(ctx context.Context, command cmd.ListUs
 		timeoutCtx context.Context,
 		conn *mongo.Client,
 	) error {
-		collection := conn.Database(repo.databaseName).Collection(repo.collectionName)
+		collection := conn.Database(repo.databaseName).Collection(repo.devicesCollectionName(mongoCmd))
 		cursor, cursorErr := collection.Aggregate(timeoutCtx, mongoCmd.GetPipeline())
 		if cursorErr != nil {
 			return cursorErr
@@ -209,6 +310,167 @@ func (repo *UserRepo) GetListUserDevices(ctx context.Context, command cmd.ListUs
 	return userDevices, err
 }
 
+func (repo *UserRepo) GetDeviceKeyBoundaries(
+	ctx context.Context,
+	command cmd.ListUserDevicesCommand,
+	batchSize int64,
+) (
+	[]string,
+	error,
+) {
+	var err error
+	var boundaries = []string{}
+
+	evt := events.Start(ctx, "user_repo.get_device_key_boundaries", map[string]any{
+		"db_operation":   "aggregate",
+		"operation_name": "get_device_key_boundaries",
+	})
+	defer events.End(evt, true, err, nil)
+
+	mongoCmd, ok := command.(*driver_cmd.ListUserDevicesCommand)
+	if !ok {
+		panic(ErrInvalidCommandType)
+	}
+	mongoCmd.SetSortById(cmd.OrderASC)
+	if err := mongoCmd.Build(); err != nil {
+		return nil, err
+	}
+
+	timeout := repo.GetReadTimeout()
+	err = repo.ExecuteClosure(evt.Context(), timeout, func(
+		timeoutCtx context.Context,
+		conn *mongo.Client,
+	) error {
+		collection := conn.Database(repo.databaseName).Collection(repo.devicesCollectionName(mongoCmd))
+		cursor, cursorErr := collection.Aggregate(
+			timeoutCtx,
+			mongoCmd.GetKeysPipeline(),
+			options.Aggregate().SetAllowDiskUse(true),
+		)
+		if cursorErr != nil {
+			return cursorErr
+		}
+		defer cursor.Close(timeoutCtx)
+
+		// the batch is closed by the user following the last device of the batch,
+		// so that the remaining devices of that user still belong to the batch
+		var count int64
+		var previous string
+		for cursor.Next(timeoutCtx) {
+			key := cursor.Current.Lookup("user_id").StringValue()
+			if count >= batchSize && key != previous {
+				boundaries = append(boundaries, previous)
+				count = 0
+			}
+			count++
+			previous = key
+		}
+		return cursor.Err()
+	})
+
+	return boundaries, err
+}
+
+func (repo *UserRepo) CreateDevicesSnapshot(
+	ctx context.Context,
+	command cmd.ListUserDevicesCommand,
+	snapshotId string,
+) (
+	int64,
+	int64,
+	error,
+) {
+	var err error
+	var summary struct {
+		Devices    int64 `bson:"devices"`
+		Duplicates int64 `bson:"duplicates"`
+	}
+
+	evt := events.Start(ctx, "user_repo.create_devices_snapshot", map[string]any{
+		"db_operation":   "aggregate",
+		"operation_name": "create_devices_snapshot",
+	})
+	defer events.End(evt, true, err, nil)
+
+	mongoCmd, ok := command.(*driver_cmd.ListUserDevicesCommand)
+	if !ok {
+		panic(ErrInvalidCommandType)
+	}
+	if err = mongoCmd.Build(); err != nil {
+		return 0, 0, err
+	}
+
+	// a snapshot left by a former attempt is replaced, rather than merged
+	if err = repo.DeleteDevicesSnapshot(evt.Context(), snapshotId); err != nil {
+		return 0, 0, err
+	}
+
+	err = repo.ExecuteClosure(evt.Context(), repo.snapshotTimeout, func(
+		timeoutCtx context.Context,
+		conn *mongo.Client,
+	) error {
+		collection := conn.Database(repo.databaseName).Collection(repo.collectionName)
+		pipeline := mongoCmd.GetSnapshotPipeline(repo.snapshotCollectionName, snapshotId, time.Now().UTC())
+		cursor, cursorErr := collection.Aggregate(timeoutCtx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+		if cursorErr != nil {
+			return cursorErr
+		}
+		cursor.Close(timeoutCtx)
+
+		snapshots := conn.Database(repo.databaseName).Collection(repo.snapshotCollectionName)
+		cursor, cursorErr = snapshots.Aggregate(timeoutCtx, mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"snapshot_id": snapshotId}}},
+			{{Key: "$group", Value: bson.M{
+				"_id":        nil,
+				"devices":    bson.M{"$sum": 1},
+				"duplicates": bson.M{"$sum": "$duplicates"},
+			}}},
+		})
+		if cursorErr != nil {
+			return cursorErr
+		}
+		defer cursor.Close(timeoutCtx)
+		if cursor.Next(timeoutCtx) {
+			return cursor.Decode(&summary)
+		}
+		return cursor.Err()
+	})
+	evt.SetData("devices_total", summary.Devices)
+	evt.SetData("duplicates_total", summary.Duplicates)
+
+	return summary.Devices, summary.Duplicates, err
+}
+
+func (repo *UserRepo) DeleteDevicesSnapshot(ctx context.Context, snapshotId string) error {
+	var err error
+
+	evt := events.Start(ctx, "user_repo.delete_devices_snapshot", map[string]any{
+		"db_operation":   "delete",
+		"operation_name": "delete_devices_snapshot",
+	})
+	defer events.End(evt, true, err, nil)
+
+	timeout := repo.GetWriteTimeout()
+	err = repo.ExecuteClosure(evt.Context(), timeout, func(
+		timeoutCtx context.Context,
+		conn *mongo.Client,
+	) error {
+		collection := conn.Database(repo.databaseName).Collection(repo.snapshotCollectionName)
+		_, deleteErr := collection.DeleteMany(timeoutCtx, bson.M{"snapshot_id": snapshotId})
+		return deleteErr
+	})
+
+	return err
+}
+
+// devicesCollectionName returns the collection listed by the command
+func (repo *UserRepo) devicesCollectionName(command *driver_cmd.ListUserDevicesCommand) string {
+	if command.IsSnapshot() {
+		return repo.snapshotCollectionName
+	}
+	return repo.collectionName
+}
+
 func (repo *UserRepo) AggregateUsers(ctx context.Context, command cmd.AggregateUsersCommand) (
 	results.UsersAggregationResult,
 	error,
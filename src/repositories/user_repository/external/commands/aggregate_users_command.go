@@ -4,6 +4,11 @@ type AggregateUsersCommand interface {
 	SetFilterIds(ids []string)
 	SetFilterCampaign(campaign string)
 	SetFilterOnlyEmailVerified()
+	AudienceFilters
 	AddAggregationSumUserDevices()
+
+	// AddAggregationSumDistinctDeviceTokens counts the token owned by several
+	// users once, as the audience snapshot keeps it, and counts its duplicates.
+	AddAggregationSumDistinctDeviceTokens()
 	Build() error
 }
@@ -0,0 +1,9 @@
+package commands
+
+import "duolingo/models"
+
+type AudienceFilters interface {
+	SetFilterMemberships(memberships []models.Membership)
+	SetFilterNativeLanguages(languages []models.NativeLanguage)
+	SetFilterPlatforms(platforms []string)
+}
@@ -4,9 +4,23 @@ type ListUserDevicesCommand interface {
 	SetFilterIds(ids []string)
 	SetFilterCampaign(campaign string)
 	SetFilterOnlyEmailVerified()
+	AudienceFilters
 
 	SetPagination(offset int64, limit int64)
 	SetSortById(order SortOrder)
 
+	// SetKeyRange seeks the devices by their user id on an index, rather than
+	// skipping the previous ones. "after" is exclusive, "until" inclusive, and
+	// the empty bound is unbounded.
+	SetKeyRange(after string, until string)
+
+	// SetSnapshot lists the devices of the audience snapshot rather than the
+	// devices of the users, the filters were applied when it was created.
+	SetSnapshot(snapshotId string)
+
+	// SetDistinctTokens keeps the token owned by several users once, for the
+	// lowest user id, as the snapshot does. It applies to the key boundaries.
+	SetDistinctTokens()
+
 	Build() error
 }
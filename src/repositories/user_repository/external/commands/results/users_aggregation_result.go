@@ -2,4 +2,5 @@ package results
 
 type UsersAggregationResult interface {
 	GetCountUserDevices() int64
+	GetCountDuplicateTokens() int64
 }
@@ -1,6 +1,7 @@
 package test_suites
 
 import (
+	"context"
 	"sort"
 	"time"
 
@@ -115,6 +116,28 @@ func (s *UserRepositoryTestSuite) Test_DeleteUsers_ByIds() {
 	s.Assert().Equal(len(data.TestUsersEmailUnverifiedIds), len(listResult2))
 }
 
+func (s *UserRepositoryTestSuite) Test_DeleteDevicesByTokens() {
+	user := &models.User{
+		Id:        uuid.NewString(),
+		Campaigns: []string{"usr_campaign"},
+		Devices: []*models.UserDevice{
+			{Platform: "android", Token: "usr_invalid_token"},
+			{Platform: "ios", Token: "usr_valid_token"},
+		},
+	}
+	s.repo.InsertManyUsers(context.Background(), []*models.User{user})
+	defer s.repo.DeleteUsersByIds(context.Background(), []string{user.Id})
+
+	modified, err := s.repo.DeleteDevicesByTokens(context.Background(), []string{"usr_invalid_token"})
+	result, _ := s.repo.GetListUsersByIds(context.Background(), []string{user.Id})
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(1), modified)
+	if s.Assert().Len(result, 1) && s.Assert().Len(result[0].Devices, 1) {
+		s.Assert().Equal("usr_valid_token", result[0].Devices[0].Token)
+	}
+}
+
 func (s *UserRepositoryTestSuite) Test_GetListUsers_ByIds() {
 	listResult1, err1 := s.repo.GetListUsersByIds(data.TestUserIds)
 	if s.Assert().NoError(err1) && s.Assert().NotEmpty(listResult1) {
@@ -240,7 +263,8 @@ func (s *UserRepositoryTestSuite) Test_GetListUserDevices() {
 
 		for i := range size {
 			if j := page*size + i; j < len(data.TestDevices) {
-				s.Assert().Equal(*data.TestDevices[j], *devices[i])
+				s.Assert().Equal(data.TestDevices[j].Platform, devices[i].Platform)
+				s.Assert().Equal(data.TestDevices[j].Token, devices[i].Token)
 			}
 		}
 	}
@@ -12,10 +12,23 @@ type UserRepository interface {
 
 	DeleteUsersByIds(ctx context.Context, ids []string) error
 	DeleteUsers(ctx context.Context, cmd commands.DeleteUsersCommand) error
+	DeleteDevicesByTokens(ctx context.Context, tokens []string) (int64, error)
 
 	GetListUsersByIds(ctx context.Context, ids []string) ([]*models.User, error)
 	GetListUsers(ctx context.Context, cmd commands.ListUsersCommand) ([]*models.User, error)
 	GetListUserDevices(ctx context.Context, cmd commands.ListUserDevicesCommand) ([]*models.UserDevice, error)
 
+	// GetDeviceKeyBoundaries scans the user ids of the listed devices, and returns
+	// the user id closing each batch of at least "batchSize" devices. The devices
+	// of a user are never split across batches.
+	GetDeviceKeyBoundaries(ctx context.Context, cmd commands.ListUserDevicesCommand, batchSize int64) ([]string, error)
+
+	// CreateDevicesSnapshot materializes the listed devices into the audience
+	// snapshot, replacing the previous snapshot of the same id. The tokens owned
+	// by several users are kept once. It returns the number of the devices within
+	// the snapshot, and the number of the duplicate tokens left out.
+	CreateDevicesSnapshot(ctx context.Context, cmd commands.ListUserDevicesCommand, snapshotId string) (int64, int64, error)
+	DeleteDevicesSnapshot(ctx context.Context, snapshotId string) error
+
 	AggregateUsers(ctx context.Context, cmd commands.AggregateUsersCommand) (results.UsersAggregationResult, error)
 }
@@ -0,0 +1,154 @@
+package message_status_service
+
+import (
+	"context"
+	"errors"
+
+	events "duolingo/libraries/events/facade"
+	dist "duolingo/libraries/work_distributor"
+	"duolingo/models"
+	status_repo "duolingo/repositories/message_status_repository/external"
+)
+
+var (
+	ErrMessageNotCancelable = errors.New("message has already finished, it can not be canceled")
+	ErrMessageNotBuildable  = errors.New("message has already finished, it can not be built")
+)
+
+type MessageStatusService struct {
+	status_repo.MessageStatusRepository
+
+	distributor *dist.WorkDistributor
+}
+
+func NewMessageStatusService(
+	repo status_repo.MessageStatusRepository,
+	distributor *dist.WorkDistributor,
+) *MessageStatusService {
+	return &MessageStatusService{
+		MessageStatusRepository: repo,
+		distributor:             distributor,
+	}
+}
+
+func (service *MessageStatusService) MarkScheduled(ctx context.Context, input *models.MessageInput) error {
+	return service.SaveMessageStatus(ctx, models.NewMessageStatus(input, models.MessageScheduled))
+}
+
+func (service *MessageStatusService) MarkQueued(ctx context.Context, input *models.MessageInput) error {
+	return service.SaveMessageStatus(ctx, models.NewMessageStatus(input, models.MessageQueued))
+}
+
+// MarkBuilding records the workload of the message, it returns ErrMessageNotBuildable
+// if the message has finished (e.g. canceled) meanwhile, the workload is then to be
+// discarded.
+func (service *MessageStatusService) MarkBuilding(
+	ctx context.Context,
+	messageId string,
+	workload *dist.Workload,
+) error {
+	err := service.StartMessageBuilding(
+		ctx,
+		messageId,
+		workload.Id,
+		workload.TotalWorkUnits,
+		workload.GetExpectTotalAssignments(),
+	)
+	if err == status_repo.ErrMessageStatusFinished {
+		return ErrMessageNotBuildable
+	}
+	return err
+}
+
+func (service *MessageStatusService) MarkCompleted(ctx context.Context, messageId string) error {
+	return service.UpdateMessageState(ctx, messageId, models.MessageCompleted, "")
+}
+
+func (service *MessageStatusService) MarkFailed(ctx context.Context, messageId string, reason error) error {
+	return service.UpdateMessageState(ctx, messageId, models.MessageFailed, reason.Error())
+}
+
+// Cancel marks the message as canceled, and deletes its workload so that
+// the builders stop building the batches for the message.
+func (service *MessageStatusService) Cancel(
+	ctx context.Context,
+	messageId string,
+) (*models.MessageStatus, error) {
+	var status *models.MessageStatus
+	var err error
+
+	evt := events.Start(ctx, "message_status_service.cancel", map[string]any{
+		"operation_name": "cancel",
+	})
+	defer events.End(evt, true, err, nil)
+
+	// the workload is read along with the cancellation, the workload recorded
+	// later on is discarded by the builder, since the message is finished
+	if status, err = service.CancelMessage(evt.Context(), messageId); err != nil {
+		if err == status_repo.ErrMessageStatusFinished {
+			err = ErrMessageNotCancelable
+		}
+		return nil, err
+	}
+	if status.WorkloadId != "" {
+		err = service.distributor.DeleteWorkloadAndAssignments(evt.Context(), status.WorkloadId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	status.State = models.MessageCanceled
+
+	return status, nil
+}
+
+func (service *MessageStatusService) IsCanceled(ctx context.Context, messageId string) bool {
+	status, err := service.GetMessageStatus(ctx, messageId)
+	return err == nil && status.State == models.MessageCanceled
+}
+
+func (service *MessageStatusService) ReportDeliveryResults(
+	ctx context.Context,
+	messageId string,
+	successCount int64,
+	failureCount int64,
+) error {
+	return service.IncreaseDeliveryResults(ctx, messageId, successCount, failureCount)
+}
+
+// GetDeliveryStatus returns the message status with the progress of its workload.
+// The "sending" and "completed" states are resolved from the progress, as they are
+// reached by the concurrent builders and senders, rather than by a single worker.
+func (service *MessageStatusService) GetDeliveryStatus(
+	ctx context.Context,
+	messageId string,
+) (*models.MessageStatus, error) {
+	var status *models.MessageStatus
+	var err error
+
+	evt := events.Start(ctx, "message_status_service.get_delivery_status", map[string]any{
+		"operation_name": "get_delivery_status",
+	})
+	defer events.End(evt, true, err, nil)
+
+	if status, err = service.GetMessageStatus(evt.Context(), messageId); err != nil {
+		return nil, err
+	}
+	if status.WorkloadId == "" || status.State == models.MessageCanceled {
+		return status, nil
+	}
+
+	workload, err := service.distributor.GetWorkload(evt.Context(), status.WorkloadId)
+	if err != nil {
+		return nil, err
+	}
+	status.CommittedAssignments = workload.TotalCommittedAssignments
+	if !status.IsFinished() && workload.HasWorkloadFulfilled() {
+		status.State = models.MessageSending
+		if status.TotalReported() >= status.TotalDevices {
+			status.State = models.MessageCompleted
+			err = service.MarkCompleted(evt.Context(), messageId)
+		}
+	}
+
+	return status, err
+}
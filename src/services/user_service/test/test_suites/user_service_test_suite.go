@@ -1,11 +1,16 @@
 package test_suites
 
 import (
+	"context"
+	"time"
+
 	container "duolingo/libraries/dependencies_container"
+	"duolingo/models"
 	user_repo "duolingo/repositories/user_repository/external"
 	"duolingo/services/user_service"
 	"duolingo/test/fixtures/data"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -23,17 +28,17 @@ func NewUserServiceTestSuite() *UserServiceTestSuite {
 }
 
 func (s *UserServiceTestSuite) SetupTest() {
-	s.repo.InsertManyUsers(data.TestUsers)
-	s.repo.InsertManyUsers(data.TestUsersEmailUnverified)
+	s.repo.InsertManyUsers(context.Background(), data.TestUsers)
+	s.repo.InsertManyUsers(context.Background(), data.TestUsersEmailUnverified)
 }
 
 func (s *UserServiceTestSuite) TearDownTest() {
-	s.repo.DeleteUsersByIds(data.TestUserIds)
-	s.repo.DeleteUsersByIds(data.TestUsersEmailUnverifiedIds)
+	s.repo.DeleteUsersByIds(context.Background(), data.TestUserIds)
+	s.repo.DeleteUsersByIds(context.Background(), data.TestUsersEmailUnverifiedIds)
 }
 
 func (s *UserServiceTestSuite) Test_CountDevicesForCampaign() {
-	count, err := s.service.CountDevicesForCampaign(data.TestCampaignPrimary)
+	count, err := s.service.CountDevicesForCampaign(context.Background(), data.TestCampaignPrimary, nil)
 	s.Assert().NoError(err)
 	s.Assert().Equal(int64(len(data.TestDevices)), count)
 }
@@ -43,7 +48,9 @@ func (s *UserServiceTestSuite) Test_GetDevicesForCampaign() {
 	total := (len(data.TestDevices) + 1) / size // ceiling(len/size)
 	for page := range total {
 		devices, err := s.service.GetDevicesForCampaign(
+			context.Background(),
 			data.TestCampaignPrimary,
+			nil,
 			int64(page*size),
 			int64(size),
 		)
@@ -54,8 +61,187 @@ func (s *UserServiceTestSuite) Test_GetDevicesForCampaign() {
 
 		for i := range size {
 			if j := page*size + i; j < len(data.TestDevices) {
-				s.Assert().Equal(*data.TestDevices[j], *devices[i])
+				s.Assert().Equal(data.TestDevices[j].Platform, devices[i].Platform)
+				s.Assert().Equal(data.TestDevices[j].Token, devices[i].Token)
 			}
 		}
 	}
 }
+
+func (s *UserServiceTestSuite) Test_Audience_Filters_Count_Matches_List() {
+	cases := []struct {
+		name     string
+		audience *models.AudienceFilter
+		expected []string
+	}{
+		{
+			name:     "membership",
+			audience: &models.AudienceFilter{Memberships: []models.Membership{models.MembershipPremium}},
+			expected: []string{"user_1_device_1", "user_1_device_2", "user_2_device_1", "user_2_device_2"},
+		},
+		{
+			name:     "native language",
+			audience: &models.AudienceFilter{NativeLanguages: []models.NativeLanguage{models.LanguageVN}},
+			expected: []string{
+				"user_3_device_1", "user_3_device_2",
+				"user_4_device_1", "user_4_device_2",
+				"user_5_device_1", "user_5_device_2",
+			},
+		},
+		{
+			name:     "platform",
+			audience: &models.AudienceFilter{Platforms: []string{"ios"}},
+			expected: []string{
+				"user_1_device_2", "user_2_device_2", "user_3_device_2",
+				"user_4_device_2", "user_5_device_2",
+			},
+		},
+		{
+			name: "combined",
+			audience: &models.AudienceFilter{
+				Memberships: []models.Membership{models.MembershipPremium},
+				Platforms:   []string{"android"},
+			},
+			expected: []string{"user_1_device_1", "user_2_device_1"},
+		},
+	}
+	for _, c := range cases {
+		count, countErr := s.service.CountDevicesForCampaign(
+			context.Background(), data.TestCampaignPrimary, c.audience,
+		)
+		devices, listErr := s.service.GetDevicesForCampaign(
+			context.Background(), data.TestCampaignPrimary, c.audience, 0, 100,
+		)
+		tokens := tokensOf(devices)
+
+		s.Assert().NoError(countErr, c.name)
+		s.Assert().NoError(listErr, c.name)
+		s.Assert().ElementsMatch(c.expected, tokens, c.name)
+		// the count is the number of the devices listed
+		s.Assert().Equal(int64(len(tokens)), count, c.name)
+	}
+}
+
+func (s *UserServiceTestSuite) Test_SnapshotDevicesForCampaign_Dedupes_Tokens() {
+	// the device shared with user_1, the snapshot keeps it for the lowest user id
+	sharing := s.campaignUser("user_8", "user_1_device_1")
+	s.repo.InsertManyUsers(context.Background(), []*models.User{sharing})
+	defer s.repo.DeleteUsersByIds(context.Background(), []string{sharing.Id})
+	snapshotId := uuid.NewString()
+	defer s.service.DeleteDevicesSnapshot(context.Background(), snapshotId)
+
+	count, duplicates, err := s.service.SnapshotDevicesForCampaign(
+		context.Background(), snapshotId, data.TestCampaignPrimary, nil,
+	)
+	devices := s.snapshotDevices(snapshotId, 4)
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(len(data.TestDevices)), count)
+	s.Assert().Equal(int64(1), duplicates)
+	s.Assert().ElementsMatch(tokensOf(data.TestDevices), tokensOf(devices))
+	for _, device := range devices {
+		if device.Token == "user_1_device_1" {
+			s.Assert().Equal("user_1", device.Recipient.UserId)
+		}
+	}
+}
+
+func (s *UserServiceTestSuite) Test_Distinct_Counts_Match_Snapshot() {
+	sharing := s.campaignUser("user_8", "user_1_device_1")
+	s.repo.InsertManyUsers(context.Background(), []*models.User{sharing})
+	defer s.repo.DeleteUsersByIds(context.Background(), []string{sharing.Id})
+	snapshotId := uuid.NewString()
+	defer s.service.DeleteDevicesSnapshot(context.Background(), snapshotId)
+
+	count, duplicates, err := s.service.CountDistinctDevicesForCampaign(
+		context.Background(), data.TestCampaignPrimary, nil,
+	)
+	boundaries, boundariesErr := s.service.GetDeviceBoundariesForCampaign(
+		context.Background(), data.TestCampaignPrimary, nil, 4,
+	)
+	snapshotCount, snapshotDuplicates, _ := s.service.SnapshotDevicesForCampaign(
+		context.Background(), snapshotId, data.TestCampaignPrimary, nil,
+	)
+	snapshotBoundaries, _ := s.service.GetDeviceBoundariesForSnapshot(context.Background(), snapshotId, 4)
+
+	// the preview counts the live audience as the snapshot would freeze it
+	s.Assert().NoError(err)
+	s.Assert().NoError(boundariesErr)
+	s.Assert().Equal(snapshotCount, count)
+	s.Assert().Equal(snapshotDuplicates, duplicates)
+	s.Assert().Equal(snapshotBoundaries, boundaries)
+}
+
+func (s *UserServiceTestSuite) Test_SnapshotDevicesForCampaign_Replaces_On_Retry() {
+	snapshotId := uuid.NewString()
+	defer s.service.DeleteDevicesSnapshot(context.Background(), snapshotId)
+
+	s.service.SnapshotDevicesForCampaign(context.Background(), snapshotId, data.TestCampaignPrimary, nil)
+	count, _, err := s.service.SnapshotDevicesForCampaign(
+		context.Background(), snapshotId, data.TestCampaignPrimary, nil,
+	)
+	devices := s.snapshotDevices(snapshotId, 4)
+
+	// the devices of the former attempt are not merged with the retry
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(len(data.TestDevices)), count)
+	s.Assert().Len(devices, len(data.TestDevices))
+}
+
+func (s *UserServiceTestSuite) Test_Snapshot_Batches_Stable_While_Audience_Changes() {
+	snapshotId := uuid.NewString()
+	defer s.service.DeleteDevicesSnapshot(context.Background(), snapshotId)
+	s.service.SnapshotDevicesForCampaign(context.Background(), snapshotId, data.TestCampaignPrimary, nil)
+	boundaries, _ := s.service.GetDeviceBoundariesForSnapshot(context.Background(), snapshotId, 4)
+	before := s.snapshotDevices(snapshotId, 4)
+
+	// a user joins and another one leaves while the job is running
+	joining := s.campaignUser("user_0", "user_0_device_1")
+	s.repo.InsertManyUsers(context.Background(), []*models.User{joining})
+	defer s.repo.DeleteUsersByIds(context.Background(), []string{joining.Id})
+	s.repo.DeleteUsersByIds(context.Background(), []string{"user_3"})
+
+	boundariesAfter, err := s.service.GetDeviceBoundariesForSnapshot(context.Background(), snapshotId, 4)
+	after := s.snapshotDevices(snapshotId, 4)
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(boundaries, boundariesAfter)
+	s.Assert().Equal(tokensOf(before), tokensOf(after))
+}
+
+// snapshotDevices lists the snapshot devices range by range, as the builders do
+func (s *UserServiceTestSuite) snapshotDevices(snapshotId string, batchSize int64) []*models.UserDevice {
+	boundaries, err := s.service.GetDeviceBoundariesForSnapshot(context.Background(), snapshotId, batchSize)
+	s.Require().NoError(err)
+	devices := []*models.UserDevice{}
+	for i := range len(boundaries) + 1 {
+		after, until := "", ""
+		if i > 0 {
+			after = boundaries[i-1]
+		}
+		if i < len(boundaries) {
+			until = boundaries[i]
+		}
+		batch, err := s.service.GetDevicesForSnapshotInRange(context.Background(), snapshotId, after, until)
+		s.Require().NoError(err)
+		devices = append(devices, batch...)
+	}
+	return devices
+}
+
+func (s *UserServiceTestSuite) campaignUser(userId string, token string) *models.User {
+	return &models.User{
+		Id:              userId,
+		Campaigns:       []string{data.TestCampaignPrimary},
+		Devices:         []*models.UserDevice{{Token: token, Platform: "android"}},
+		EmailVerifiedAt: time.Now().UTC().Add(-1 * time.Hour),
+	}
+}
+
+func tokensOf(devices []*models.UserDevice) []string {
+	tokens := []string{}
+	for _, device := range devices {
+		tokens = append(tokens, device.Token)
+	}
+	return tokens
+}
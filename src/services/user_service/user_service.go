@@ -24,7 +24,11 @@ func NewUserService(
 	}
 }
 
-func (service *UserService) CountDevicesForCampaign(ctx context.Context, campaign string) (int64, error) {
+func (service *UserService) CountDevicesForCampaign(
+	ctx context.Context,
+	campaign string,
+	audience *models.AudienceFilter,
+) (int64, error) {
 	var aggregateResult results.UsersAggregationResult
 	var err error
 
@@ -36,6 +40,7 @@ func (service *UserService) CountDevicesForCampaign(ctx context.Context, campaig
 	cmd := service.MakeAggregateUsersCommand()
 	cmd.SetFilterCampaign(campaign)
 	cmd.SetFilterOnlyEmailVerified()
+	setFilterAudience(cmd, audience)
 	cmd.AddAggregationSumUserDevices()
 
 	aggregateResult, err = service.AggregateUsers(evt.Context(), cmd)
@@ -46,9 +51,40 @@ func (service *UserService) CountDevicesForCampaign(ctx context.Context, campaig
 	return aggregateResult.GetCountUserDevices(), nil
 }
 
+// CountDistinctDevicesForCampaign counts the devices of the campaign audience
+// as its snapshot would, the token owned by several users is counted once. It
+// returns the number of the devices, and the number of the duplicate tokens.
+func (service *UserService) CountDistinctDevicesForCampaign(
+	ctx context.Context,
+	campaign string,
+	audience *models.AudienceFilter,
+) (int64, int64, error) {
+	var aggregateResult results.UsersAggregationResult
+	var err error
+
+	evt := events.Start(ctx, "user_service.count_distinct_devices_for_campaign", map[string]any{
+		"operation_name": "count_distinct_devices_for_campaign",
+	})
+	defer events.End(evt, true, err, nil)
+
+	cmd := service.MakeAggregateUsersCommand()
+	cmd.SetFilterCampaign(campaign)
+	cmd.SetFilterOnlyEmailVerified()
+	setFilterAudience(cmd, audience)
+	cmd.AddAggregationSumDistinctDeviceTokens()
+
+	aggregateResult, err = service.AggregateUsers(evt.Context(), cmd)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return aggregateResult.GetCountUserDevices(), aggregateResult.GetCountDuplicateTokens(), nil
+}
+
 func (service *UserService) GetDevicesForCampaign(
 	ctx context.Context,
 	campaign string,
+	audience *models.AudienceFilter,
 	offset int64,
 	limit int64,
 ) ([]*models.UserDevice, error) {
@@ -63,6 +99,7 @@ func (service *UserService) GetDevicesForCampaign(
 	query := service.MakeListUserDevicesCommand()
 	query.SetFilterCampaign(campaign)
 	query.SetFilterOnlyEmailVerified()
+	setFilterAudience(query, audience)
 	query.SetPagination(offset, limit)
 	query.SetSortById(commands.OrderASC)
 
@@ -70,3 +107,153 @@ func (service *UserService) GetDevicesForCampaign(
 
 	return devices, err
 }
+
+// GetDevicesForCampaignInRange lists the devices of the users whose id is within
+// the range, seeking the users by their id rather than skipping the previous ones.
+func (service *UserService) GetDevicesForCampaignInRange(
+	ctx context.Context,
+	campaign string,
+	audience *models.AudienceFilter,
+	after string,
+	until string,
+) ([]*models.UserDevice, error) {
+	var devices []*models.UserDevice
+	var err error
+
+	evt := events.Start(ctx, "user_service.get_devices_for_campaign_in_range", map[string]any{
+		"operation_name": "get_devices_for_campaign_in_range",
+	})
+	defer events.End(evt, true, err, nil)
+
+	query := service.MakeListUserDevicesCommand()
+	query.SetFilterCampaign(campaign)
+	query.SetFilterOnlyEmailVerified()
+	setFilterAudience(query, audience)
+	query.SetKeyRange(after, until)
+	query.SetSortById(commands.OrderASC)
+
+	devices, err = service.GetListUserDevices(evt.Context(), query)
+
+	return devices, err
+}
+
+// SnapshotDevicesForCampaign freezes the devices of the campaign audience, so
+// that the devices joining or leaving meanwhile do not shift the batches. The
+// token owned by several users is kept once, so that the device receives the
+// message once. It returns the number of the devices within the snapshot, and
+// the number of the duplicate tokens left out.
+func (service *UserService) SnapshotDevicesForCampaign(
+	ctx context.Context,
+	snapshotId string,
+	campaign string,
+	audience *models.AudienceFilter,
+) (int64, int64, error) {
+	var count, duplicates int64
+	var err error
+
+	evt := events.Start(ctx, "user_service.snapshot_devices_for_campaign", map[string]any{
+		"operation_name": "snapshot_devices_for_campaign",
+	})
+	defer func() {
+		evt.SetData("devices_total", count)
+		evt.SetData("duplicate_tokens_total", duplicates)
+		events.End(evt, true, err, nil)
+	}()
+
+	query := service.MakeListUserDevicesCommand()
+	query.SetFilterCampaign(campaign)
+	query.SetFilterOnlyEmailVerified()
+	setFilterAudience(query, audience)
+
+	count, duplicates, err = service.CreateDevicesSnapshot(evt.Context(), query, snapshotId)
+
+	return count, duplicates, err
+}
+
+// GetDeviceBoundariesForCampaign returns the user ids splitting the devices of
+// the campaign audience into batches of at least "batchSize" devices, as the
+// boundaries of its snapshot would, the duplicate tokens are left out.
+func (service *UserService) GetDeviceBoundariesForCampaign(
+	ctx context.Context,
+	campaign string,
+	audience *models.AudienceFilter,
+	batchSize int64,
+) ([]string, error) {
+	var boundaries []string
+	var err error
+
+	evt := events.Start(ctx, "user_service.get_device_boundaries_for_campaign", map[string]any{
+		"operation_name": "get_device_boundaries_for_campaign",
+	})
+	defer events.End(evt, true, err, nil)
+
+	query := service.MakeListUserDevicesCommand()
+	query.SetFilterCampaign(campaign)
+	query.SetFilterOnlyEmailVerified()
+	setFilterAudience(query, audience)
+	query.SetDistinctTokens()
+
+	boundaries, err = service.GetDeviceKeyBoundaries(evt.Context(), query, batchSize)
+
+	return boundaries, err
+}
+
+// GetDeviceBoundariesForSnapshot returns the user ids splitting the devices of
+// the snapshot into batches of at least "batchSize" devices.
+func (service *UserService) GetDeviceBoundariesForSnapshot(
+	ctx context.Context,
+	snapshotId string,
+	batchSize int64,
+) ([]string, error) {
+	var boundaries []string
+	var err error
+
+	evt := events.Start(ctx, "user_service.get_device_boundaries_for_snapshot", map[string]any{
+		"operation_name": "get_device_boundaries_for_snapshot",
+	})
+	defer events.End(evt, true, err, nil)
+
+	query := service.MakeListUserDevicesCommand()
+	query.SetSnapshot(snapshotId)
+
+	boundaries, err = service.GetDeviceKeyBoundaries(evt.Context(), query, batchSize)
+
+	return boundaries, err
+}
+
+// GetDevicesForSnapshotInRange lists the snapshot devices of the users whose
+// id is within the range.
+func (service *UserService) GetDevicesForSnapshotInRange(
+	ctx context.Context,
+	snapshotId string,
+	after string,
+	until string,
+) ([]*models.UserDevice, error) {
+	var devices []*models.UserDevice
+	var err error
+
+	evt := events.Start(ctx, "user_service.get_devices_for_snapshot_in_range", map[string]any{
+		"operation_name": "get_devices_for_snapshot_in_range",
+	})
+	defer events.End(evt, true, err, nil)
+
+	query := service.MakeListUserDevicesCommand()
+	query.SetSnapshot(snapshotId)
+	query.SetKeyRange(after, until)
+	query.SetSortById(commands.OrderASC)
+
+	devices, err = service.GetListUserDevices(evt.Context(), query)
+
+	return devices, err
+}
+
+// The same audience filters must be set on both of the count and list commands,
+// so that the workload size matches the devices listed in batches.
+func setFilterAudience(cmd commands.AudienceFilters, audience *models.AudienceFilter) {
+	if audience.IsEmpty() {
+		return
+	}
+	cmd.SetFilterMemberships(audience.Memberships)
+	cmd.SetFilterNativeLanguages(audience.NativeLanguages)
+	cmd.SetFilterPlatforms(audience.Platforms)
+}
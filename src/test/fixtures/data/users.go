@@ -56,8 +56,10 @@ var (
 
 	TestUsers = []*models.User{
 		{
-			Id:        "user_1",
-			Campaigns: []string{TestCampaignPrimary, TestCampaignSecondary},
+			Id:             "user_1",
+			Membership:     models.MembershipPremium,
+			NativeLanguage: models.LanguageEN,
+			Campaigns:      []string{TestCampaignPrimary, TestCampaignSecondary},
 			Devices: []*models.UserDevice{
 				{Token: "user_1_device_1", Platform: "android"},
 				{Token: "user_1_device_2", Platform: "ios"},
@@ -65,8 +67,10 @@ var (
 			EmailVerifiedAt: time.Now().UTC().Add(-1 * time.Hour),
 		},
 		{
-			Id:        "user_2",
-			Campaigns: []string{TestCampaignPrimary, TestCampaignSecondary},
+			Id:             "user_2",
+			Membership:     models.MembershipPremium,
+			NativeLanguage: models.LanguageEN,
+			Campaigns:      []string{TestCampaignPrimary, TestCampaignSecondary},
 			Devices: []*models.UserDevice{
 				{Token: "user_2_device_1", Platform: "android"},
 				{Token: "user_2_device_2", Platform: "ios"},
@@ -74,8 +78,10 @@ var (
 			EmailVerifiedAt: time.Now().UTC().Add(-1 * time.Hour),
 		},
 		{
-			Id:        "user_3",
-			Campaigns: []string{TestCampaignPrimary},
+			Id:             "user_3",
+			Membership:     models.MembershipFreeTier,
+			NativeLanguage: models.LanguageVN,
+			Campaigns:      []string{TestCampaignPrimary},
 			Devices: []*models.UserDevice{
 				{Token: "user_3_device_1", Platform: "android"},
 				{Token: "user_3_device_2", Platform: "ios"},
@@ -83,8 +89,10 @@ var (
 			EmailVerifiedAt: time.Now().UTC().Add(-1 * time.Hour),
 		},
 		{
-			Id:        "user_4",
-			Campaigns: []string{TestCampaignPrimary},
+			Id:             "user_4",
+			Membership:     models.MembershipFreeTier,
+			NativeLanguage: models.LanguageVN,
+			Campaigns:      []string{TestCampaignPrimary},
 			Devices: []*models.UserDevice{
 				{Token: "user_4_device_1", Platform: "android"},
 				{Token: "user_4_device_2", Platform: "ios"},
@@ -92,8 +100,10 @@ var (
 			EmailVerifiedAt: time.Now().UTC().Add(-1 * time.Hour),
 		},
 		{
-			Id:        "user_5",
-			Campaigns: []string{TestCampaignPrimary},
+			Id:             "user_5",
+			Membership:     models.MembershipFreeTier,
+			NativeLanguage: models.LanguageVN,
+			Campaigns:      []string{TestCampaignPrimary},
 			Devices: []*models.UserDevice{
 				{Token: "user_5_device_1", Platform: "android"},
 				{Token: "user_5_device_2", Platform: "ios"},
@@ -12,13 +12,18 @@ import (
 
 func TestNotiBuilder(t *testing.T) {
 	fixtures.SetTestConfigDir()
-	dependencies.Bootstrap(context.Background(), "test", []string{
-		"common",
+	dependencies.Bootstrap(context.Background(), "test", "test", []string{
+		"essentials",
 		"connections",
 		"message_queues",
+		"pub_sub",
+		"task_queues",
 		"user_repo",
 		"user_service",
 		"work_distributor",
+		"message_status_repo",
+		"message_status_service",
+		"task_scheduler",
 	})
 	suite.Run(t, test_suites.NewNotiBuilderTestSuite())
 }
@@ -12,11 +12,16 @@ import (
 
 func TestSender(t *testing.T) {
 	fixtures.SetTestConfigDir()
-	dependencies.Bootstrap(context.Background(), "test", []string{
-		"common",
+	dependencies.Bootstrap(context.Background(), "test", "test", []string{
+		"essentials",
 		"connections",
 		"message_queues",
+		"pub_sub",
+		"task_queues",
+		"message_status_repo",
 		"push_service",
+		"rate_limiter",
+		"frequency_cap",
 	})
 	suite.Run(t, test_suites.NewSenderTestSuite())
 }
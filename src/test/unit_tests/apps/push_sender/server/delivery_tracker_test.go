@@ -0,0 +1,13 @@
+package server
+
+import (
+	"testing"
+
+	"duolingo/apps/push_sender/server/test/test_suites"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestDeliveryTracker(t *testing.T) {
+	suite.Run(t, test_suites.NewDeliveryTrackerTestSuite())
+}
@@ -0,0 +1,13 @@
+package backoff
+
+import (
+	"testing"
+
+	"duolingo/libraries/backoff/test/test_suites"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	suite.Run(t, test_suites.NewExponentialBackoffTestSuite())
+}
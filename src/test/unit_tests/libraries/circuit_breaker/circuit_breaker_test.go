@@ -0,0 +1,13 @@
+package circuit_breaker
+
+import (
+	"testing"
+
+	"duolingo/libraries/circuit_breaker/test/test_suites"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	suite.Run(t, test_suites.NewCircuitBreakerTestSuite())
+}
@@ -0,0 +1,31 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"duolingo/dependencies"
+	facade "duolingo/libraries/connection_manager/facade"
+	container "duolingo/libraries/dependencies_container"
+	redis "duolingo/libraries/frequency_cap/drivers/redis"
+	"duolingo/libraries/frequency_cap/test/test_suites"
+	"duolingo/test/fixtures"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRedisFrequencyCap(t *testing.T) {
+	fixtures.SetTestConfigDir()
+	dependencies.Bootstrap(context.Background(), "test", "test", []string{
+		"essentials",
+		"connections",
+	})
+
+	provider := container.MustResolve[*facade.ConnectionProvider]()
+	client := provider.GetRedisClient()
+	freqCap := redis.NewRedisFrequencyCap(client, "test_"+uuid.NewString(), 3, time.Minute)
+
+	suite.Run(t, test_suites.NewFrequencyCapTestSuite(freqCap, 3))
+}
@@ -16,9 +16,8 @@ import (
 
 func TestPubSub(t *testing.T) {
 	fixtures.SetTestConfigDir()
-	dependencies.RegisterDependencies(context.Background())
-	dependencies.BootstrapDependencies("test", []string{
-		"common",
+	dependencies.Bootstrap(context.Background(), "test", "test", []string{
+		"essentials",
 		"connections",
 	})
 
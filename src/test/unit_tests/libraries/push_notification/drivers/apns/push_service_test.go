@@ -0,0 +1,21 @@
+package apns
+
+import (
+	"context"
+	"testing"
+
+	"duolingo/dependencies"
+	"duolingo/libraries/push_notification/drivers/apns/test/test_suites"
+	"duolingo/test/fixtures"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestPushService(t *testing.T) {
+	fixtures.SetTestConfigDir()
+	dependencies.Bootstrap(context.Background(), "test", "test", []string{
+		"essentials",
+	})
+
+	suite.Run(t, test_suites.NewPushServiceTestSuite())
+}
@@ -15,9 +15,8 @@ import (
 
 func TestPushNotiFactory(t *testing.T) {
 	fixtures.SetTestConfigDir()
-	dependencies.RegisterDependencies(context.Background())
-	dependencies.BootstrapDependencies("test", []string{
-		"common",
+	dependencies.Bootstrap(context.Background(), "test", "test", []string{
+		"essentials",
 	})
 
 	config := container.MustResolve[config_reader.ConfigReader]()
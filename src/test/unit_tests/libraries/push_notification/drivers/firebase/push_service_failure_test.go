@@ -0,0 +1,21 @@
+package firebase
+
+import (
+	"context"
+	"testing"
+
+	"duolingo/dependencies"
+	"duolingo/libraries/push_notification/drivers/firebase/test/test_suites"
+	"duolingo/test/fixtures"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestPushServiceFailure(t *testing.T) {
+	fixtures.SetTestConfigDir()
+	dependencies.Bootstrap(context.Background(), "test", "test", []string{
+		"essentials",
+	})
+
+	suite.Run(t, test_suites.NewPushServiceFailureTestSuite())
+}
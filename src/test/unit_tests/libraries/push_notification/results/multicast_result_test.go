@@ -0,0 +1,13 @@
+package results
+
+import (
+	"testing"
+
+	"duolingo/libraries/push_notification/test/test_suites"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMulticastResult(t *testing.T) {
+	suite.Run(t, test_suites.NewMulticastResultTestSuite())
+}
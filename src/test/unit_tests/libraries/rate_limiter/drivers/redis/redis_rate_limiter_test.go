@@ -0,0 +1,30 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"duolingo/dependencies"
+	facade "duolingo/libraries/connection_manager/facade"
+	container "duolingo/libraries/dependencies_container"
+	redis "duolingo/libraries/rate_limiter/drivers/redis"
+	"duolingo/libraries/rate_limiter/test/test_suites"
+	"duolingo/test/fixtures"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRedisRateLimiter(t *testing.T) {
+	fixtures.SetTestConfigDir()
+	dependencies.Bootstrap(context.Background(), "test", "test", []string{
+		"essentials",
+		"connections",
+	})
+
+	provider := container.MustResolve[*facade.ConnectionProvider]()
+	client := provider.GetRedisClient()
+	limiter := redis.NewRedisRateLimiter(client, "test_"+uuid.NewString(), 100).SetBurst(10)
+
+	suite.Run(t, test_suites.NewRateLimiterTestSuite(limiter, 100, 10))
+}
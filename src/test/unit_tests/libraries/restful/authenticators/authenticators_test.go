@@ -0,0 +1,13 @@
+package authenticators
+
+import (
+	"testing"
+
+	"duolingo/libraries/restful/authenticators/test/test_suites"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestAuthenticators(t *testing.T) {
+	suite.Run(t, test_suites.NewAuthenticatorsTestSuite())
+}
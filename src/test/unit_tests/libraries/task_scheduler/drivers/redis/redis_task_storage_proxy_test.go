@@ -0,0 +1,30 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"duolingo/dependencies"
+	facade "duolingo/libraries/connection_manager/facade"
+	container "duolingo/libraries/dependencies_container"
+	redis "duolingo/libraries/task_scheduler/drivers/redis"
+	"duolingo/libraries/task_scheduler/test/test_suites"
+	"duolingo/test/fixtures"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRedisTaskStorageProxy(t *testing.T) {
+	fixtures.SetTestConfigDir()
+	dependencies.Bootstrap(context.Background(), "test", "test", []string{
+		"essentials",
+		"connections",
+	})
+
+	provider := container.MustResolve[*facade.ConnectionProvider]()
+	client := provider.GetRedisClient()
+	proxy := redis.NewRedisTaskStorageProxy(client, "test_"+uuid.NewString())
+
+	suite.Run(t, test_suites.NewTaskStorageProxyTestSuite(proxy))
+}
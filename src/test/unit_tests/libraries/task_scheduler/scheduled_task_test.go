@@ -0,0 +1,13 @@
+package task_scheduler
+
+import (
+	"testing"
+
+	"duolingo/libraries/task_scheduler/test/test_suites"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestScheduledTask(t *testing.T) {
+	suite.Run(t, test_suites.NewScheduledTaskTestSuite())
+}
@@ -16,9 +16,8 @@ import (
 
 func TestRedisWorkDistributor(t *testing.T) {
 	fixtures.SetTestConfigDir()
-	dependencies.RegisterDependencies(context.Background())
-	dependencies.BootstrapDependencies("test", []string{
-		"common",
+	dependencies.Bootstrap(context.Background(), "test", "test", []string{
+		"essentials",
 		"connections",
 	})
 
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"testing"
+
+	"duolingo/models/test/test_suites"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestPushNotiMessage(t *testing.T) {
+	suite.Run(t, test_suites.NewPushNotiMessageTestSuite())
+}
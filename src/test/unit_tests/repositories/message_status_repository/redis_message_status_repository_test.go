@@ -0,0 +1,27 @@
+package message_status_repository
+
+import (
+	"context"
+	"testing"
+
+	"duolingo/dependencies"
+	container "duolingo/libraries/dependencies_container"
+	status_repo "duolingo/repositories/message_status_repository/external"
+	"duolingo/repositories/message_status_repository/external/test/test_suites"
+	"duolingo/test/fixtures"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRedisMessageStatusRepository(t *testing.T) {
+	fixtures.SetTestConfigDir()
+	dependencies.Bootstrap(context.Background(), "test", "test", []string{
+		"essentials",
+		"connections",
+		"message_status_repo",
+	})
+
+	repo := container.MustResolve[status_repo.MessageStatusRepository]()
+
+	suite.Run(t, test_suites.NewMessageStatusRepositoryTestSuite(repo))
+}
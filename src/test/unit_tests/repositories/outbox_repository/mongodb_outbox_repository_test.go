@@ -0,0 +1,27 @@
+package outbox_repository
+
+import (
+	"context"
+	"testing"
+
+	"duolingo/dependencies"
+	container "duolingo/libraries/dependencies_container"
+	outbox_repo "duolingo/repositories/outbox_repository/external"
+	"duolingo/repositories/outbox_repository/external/test/test_suites"
+	"duolingo/test/fixtures"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMongoDBOutboxRepository(t *testing.T) {
+	fixtures.SetTestConfigDir()
+	dependencies.Bootstrap(context.Background(), "test", "test", []string{
+		"essentials",
+		"connections",
+		"outbox_repo",
+	})
+
+	repo := container.MustResolve[outbox_repo.OutboxRepository]()
+
+	suite.Run(t, test_suites.NewOutboxRepositoryTestSuite(repo))
+}